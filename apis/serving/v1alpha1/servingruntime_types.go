@@ -24,3 +24,87 @@ func init() {
 	SchemeBuilder.Register(&kserveapi.ServingRuntime{}, &kserveapi.ServingRuntimeList{})
 	SchemeBuilder.Register(&kserveapi.ClusterServingRuntime{}, &kserveapi.ClusterServingRuntimeList{})
 }
+
+// RuntimeState enum
+// +kubebuilder:validation:Enum=Ready;Progressing;ScaledToZero;Disabled;Invalid
+// +k8s:openapi-gen=true
+type RuntimeState string
+
+// RuntimeState Enum values
+const (
+	// All requested runtime Pods are ready
+	RuntimeReady RuntimeState = "Ready"
+	// The runtime Deployment exists but not all requested Pods are ready yet
+	RuntimeProgressing RuntimeState = "Progressing"
+	// The runtime Deployment exists but has been scaled to zero replicas
+	RuntimeScaledToZero RuntimeState = "ScaledToZero"
+	// No runtime Deployment exists because the runtime is not enabled
+	RuntimeDisabled RuntimeState = "Disabled"
+	// No runtime Deployment exists because the runtime spec failed validation
+	RuntimeInvalid RuntimeState = "Invalid"
+)
+
+// RuntimeStateReason enum
+// +kubebuilder:validation:Enum=NoPredictors;DisabledInSpec;NotMultiModel;NamespaceNotEnabled;InvalidSpec
+// +k8s:openapi-gen=true
+type RuntimeStateReason string
+
+// RuntimeStateReason Enum values
+const (
+	// The runtime was scaled to zero because no Predictors are assigned to it
+	RuntimeHasNoPredictors RuntimeStateReason = "NoPredictors"
+	// The runtime spec has disabled set to true
+	RuntimeDisabledInSpec RuntimeStateReason = "DisabledInSpec"
+	// The runtime spec does not have multiModel set to true
+	RuntimeNotMultiModel RuntimeStateReason = "NotMultiModel"
+	// The namespace of the runtime is not enabled for model-mesh
+	RuntimeNamespaceNotEnabled RuntimeStateReason = "NamespaceNotEnabled"
+	// The runtime spec failed validation
+	InvalidRuntimeSpec RuntimeStateReason = "InvalidSpec"
+)
+
+// RuntimeDeploymentStatus describes the model-mesh Deployment of a runtime in a single namespace
+// +k8s:openapi-gen=true
+type RuntimeDeploymentStatus struct {
+	// High level state string: Ready, Progressing, ScaledToZero, Disabled, Invalid
+	//+optional
+	State RuntimeState `json:"state,omitempty"`
+	// Reason the runtime is not deployed or is scaled to zero
+	//+optional
+	Reason RuntimeStateReason `json:"reason,omitempty"`
+	// Detailed message, e.g. the validation error for an invalid runtime
+	//+optional
+	Message string `json:"message,omitempty"`
+	// Number of Pods requested for the runtime Deployment
+	// +kubebuilder:default=0
+	Replicas int32 `json:"replicas"`
+	// Number of ready Pods of the runtime Deployment
+	// +kubebuilder:default=0
+	ReadyReplicas int32 `json:"readyReplicas"`
+}
+
+// ServingRuntimeStatus is the status reported by the controller on ServingRuntimes.
+// The ServingRuntime type itself is owned by KServe so this is written via a patch
+// of the status subresource rather than through the typed client.
+// +k8s:openapi-gen=true
+type ServingRuntimeStatus struct {
+	RuntimeDeploymentStatus `json:",inline"`
+}
+
+// NamespacedRuntimeStatus is the status of a ClusterServingRuntime in one namespace
+// +k8s:openapi-gen=true
+type NamespacedRuntimeStatus struct {
+	Namespace               string `json:"namespace"`
+	RuntimeDeploymentStatus `json:",inline"`
+}
+
+// ClusterServingRuntimeStatus is the status reported by the controller on ClusterServingRuntimes
+// +k8s:openapi-gen=true
+type ClusterServingRuntimeStatus struct {
+	// Namespaces in which a runtime Deployment exists for this ClusterServingRuntime
+	//+optional
+	DeployedNamespaces []string `json:"deployedNamespaces,omitempty"`
+	// Per-namespace status of the runtime
+	//+optional
+	Namespaces []NamespacedRuntimeStatus `json:"namespaces,omitempty"`
+}
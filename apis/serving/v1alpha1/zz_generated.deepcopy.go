@@ -23,6 +23,31 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterServingRuntimeStatus) DeepCopyInto(out *ClusterServingRuntimeStatus) {
+	*out = *in
+	if in.DeployedNamespaces != nil {
+		in, out := &in.DeployedNamespaces, &out.DeployedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]NamespacedRuntimeStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterServingRuntimeStatus.
+func (in *ClusterServingRuntimeStatus) DeepCopy() *ClusterServingRuntimeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterServingRuntimeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FailureInfo) DeepCopyInto(out *FailureInfo) {
 	*out = *in
@@ -88,6 +113,22 @@ func (in *ModelType) DeepCopy() *ModelType {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedRuntimeStatus) DeepCopyInto(out *NamespacedRuntimeStatus) {
+	*out = *in
+	out.RuntimeDeploymentStatus = in.RuntimeDeploymentStatus
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedRuntimeStatus.
+func (in *NamespacedRuntimeStatus) DeepCopy() *NamespacedRuntimeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedRuntimeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Predictor) DeepCopyInto(out *Predictor) {
 	*out = *in
@@ -223,6 +264,21 @@ func (in *PredictorStatus) DeepCopy() *PredictorStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RuntimeDeploymentStatus) DeepCopyInto(out *RuntimeDeploymentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RuntimeDeploymentStatus.
+func (in *RuntimeDeploymentStatus) DeepCopy() *RuntimeDeploymentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RuntimeDeploymentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RuntimeRef) DeepCopyInto(out *RuntimeRef) {
 	*out = *in
@@ -258,6 +314,22 @@ func (in *S3StorageSource) DeepCopy() *S3StorageSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServingRuntimeStatus) DeepCopyInto(out *ServingRuntimeStatus) {
+	*out = *in
+	out.RuntimeDeploymentStatus = in.RuntimeDeploymentStatus
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServingRuntimeStatus.
+func (in *ServingRuntimeStatus) DeepCopy() *ServingRuntimeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServingRuntimeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServingRuntimeWebhook) DeepCopyInto(out *ServingRuntimeWebhook) {
 	*out = *in
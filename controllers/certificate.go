@@ -0,0 +1,92 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+// the cert-manager API isn't a dependency, Certificates are handled as unstructured objects
+var certificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;patch;delete
+
+// reconcileCertificate creates or updates the cert-manager Certificate which issues the
+// TLS secret of the runtime Pods behind the given inference Service. Certificates are
+// left in place when the cert-manager integration is disabled.
+func (r *ServiceReconciler) reconcileCertificate(ctx context.Context, cfg *config.Config, s *corev1.Service) error {
+	if !cfg.TLS.CertManager.Enabled() {
+		return nil
+	}
+	cert := &unstructured.Unstructured{}
+	cert.SetGroupVersionKind(certificateGVK)
+	cert.SetName(cfg.TLS.SecretName)
+	cert.SetNamespace(s.Namespace)
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, cert, func() error {
+		cert.SetLabels(s.Labels)
+		if err := unstructured.SetNestedField(cert.Object, runtimeCertificateSpec(cfg, s), "spec"); err != nil {
+			return err
+		}
+		return controllerutil.SetControllerReference(s, cert, r.Scheme)
+	})
+	if meta.IsNoMatchError(err) {
+		return fmt.Errorf("could not create or update Certificate %s, is cert-manager installed? %w", cert.GetName(), err)
+	} else if err != nil {
+		return fmt.Errorf("could not create or update Certificate %s: %w", cert.GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		r.Log.Info("Reconciled Certificate", "name", cert.GetName(), "namespace", cert.GetNamespace(), "result", result)
+	}
+	return nil
+}
+
+func runtimeCertificateSpec(cfg *config.Config, s *corev1.Service) map[string]interface{} {
+	cm := cfg.TLS.CertManager
+	dnsNames := []interface{}{
+		s.Name,
+		fmt.Sprintf("%s.%s", s.Name, s.Namespace),
+		fmt.Sprintf("%s.%s.svc", s.Name, s.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", s.Name, s.Namespace),
+	}
+	spec := map[string]interface{}{
+		"secretName": cfg.TLS.SecretName,
+		"commonName": s.Name,
+		"dnsNames":   dnsNames,
+		// model-mesh only accepts PKCS8 encoded keys
+		"privateKey": map[string]interface{}{"encoding": "PKCS8"},
+		"issuerRef": map[string]interface{}{
+			"name":  cm.IssuerName,
+			"kind":  cm.IssuerKind,
+			"group": certificateGVK.Group,
+		},
+	}
+	if cm.Duration > 0 {
+		spec["duration"] = cm.Duration.String()
+	}
+	if cm.RenewBefore > 0 {
+		spec["renewBefore"] = cm.RenewBefore.String()
+	}
+	return spec
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+func TestRuntimeCertificateSpec(t *testing.T) {
+	s := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "modelmesh-serving", Namespace: "team-a"}}
+	cfg := &config.Config{TLS: config.TLSConfig{
+		SecretName: "modelmesh-certificate",
+		CertManager: config.CertManagerConfig{
+			IssuerName:  "ca-issuer",
+			IssuerKind:  "ClusterIssuer",
+			RenewBefore: 360 * time.Hour,
+		},
+	}}
+
+	spec := runtimeCertificateSpec(cfg, s)
+
+	assert.Equal(t, "modelmesh-certificate", spec["secretName"])
+	assert.Equal(t, []interface{}{
+		"modelmesh-serving",
+		"modelmesh-serving.team-a",
+		"modelmesh-serving.team-a.svc",
+		"modelmesh-serving.team-a.svc.cluster.local",
+	}, spec["dnsNames"])
+	assert.Equal(t, map[string]interface{}{"encoding": "PKCS8"}, spec["privateKey"])
+	assert.Equal(t, map[string]interface{}{"name": "ca-issuer", "kind": "ClusterIssuer", "group": "cert-manager.io"},
+		spec["issuerRef"])
+	assert.Equal(t, "360h0m0s", spec["renewBefore"])
+	// cert-manager's default duration applies
+	assert.NotContains(t, spec, "duration")
+}
@@ -0,0 +1,259 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+const (
+	grpcRouteSuffix = "-grpc"
+	httpRouteSuffix = "-http"
+)
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;grpcroutes,verbs=get;list;watch;create;update;patch;delete
+
+// reconcileExternalRoutes creates, updates or deletes the Gateway API routes or Ingresses exposing
+// the gRPC and REST ports of the given inference Service, which is the owner of the generated objects.
+// It returns the external endpoints of the routes or Ingresses which exist, empty for those which don't.
+func (r *ServiceReconciler) reconcileExternalRoutes(ctx context.Context, cfg *config.Config, s *corev1.Service) (
+	grpcEndpoint, httpEndpoint string, err error) {
+	erc := cfg.ExternalRoute
+	grpcHost, httpHost := erc.Hosts(s.Name, s.Namespace)
+	grpcPort, httpPort := servicePort(s, "grpc"), servicePort(s, "http")
+
+	grpcAnnotations := make(map[string]string, len(erc.Annotations)+len(erc.GrpcAnnotations))
+	for k, v := range erc.Annotations {
+		grpcAnnotations[k] = v
+	}
+	for k, v := range erc.GrpcAnnotations {
+		grpcAnnotations[k] = v
+	}
+
+	gatewayRoutes := erc.Type == config.ExternalRouteGateway
+	ingresses := erc.Type == config.ExternalRouteIngress
+
+	// the routes whose CRDs aren't installed are skipped rather than failing the reconcile,
+	// GRPCRoute is only in the experimental channel of the Gateway API before v1.1
+	if gatewayRoutes && httpPort != 0 && !r.HTTPRouteCRDExists {
+		r.recordMissingRouteCRD(s, "HTTPRoute")
+	}
+	if gatewayRoutes && grpcPort != 0 && !r.GRPCRouteCRDExists {
+		r.recordMissingRouteCRD(s, "GRPCRoute")
+	}
+
+	var grpcRouted, httpRouted bool
+	if r.GRPCRouteCRDExists {
+		grpcRoute := &gatewayv1alpha2.GRPCRoute{ObjectMeta: routeMeta(s, grpcRouteSuffix)}
+		if gatewayRoutes && grpcPort != 0 {
+			grpcRouted = true
+			if err := r.applyRoute(ctx, s, grpcRoute, grpcAnnotations, func() {
+				grpcRoute.Spec = gatewayv1alpha2.GRPCRouteSpec{
+					CommonRouteSpec: gatewayCommonRouteSpec(erc),
+					Hostnames:       []gatewayv1alpha2.Hostname{gatewayv1alpha2.Hostname(grpcHost)},
+					Rules: []gatewayv1alpha2.GRPCRouteRule{{
+						BackendRefs: []gatewayv1alpha2.GRPCBackendRef{{BackendRef: gatewayBackendRef(s, grpcPort)}},
+					}},
+				}
+			}); err != nil {
+				return "", "", err
+			}
+		} else if err := r.deleteRoute(ctx, grpcRoute); err != nil {
+			return "", "", err
+		}
+	}
+
+	if r.HTTPRouteCRDExists {
+		httpRoute := &gatewayv1.HTTPRoute{ObjectMeta: routeMeta(s, httpRouteSuffix)}
+		if gatewayRoutes && httpPort != 0 {
+			httpRouted = true
+			if err := r.applyRoute(ctx, s, httpRoute, erc.Annotations, func() {
+				httpRoute.Spec = gatewayv1.HTTPRouteSpec{
+					CommonRouteSpec: gatewayCommonRouteSpec(erc),
+					Hostnames:       []gatewayv1.Hostname{gatewayv1.Hostname(httpHost)},
+					Rules: []gatewayv1.HTTPRouteRule{{
+						Matches:     []gatewayv1.HTTPRouteMatch{defaultHTTPRouteMatch()},
+						BackendRefs: []gatewayv1.HTTPBackendRef{{BackendRef: gatewayBackendRef(s, httpPort)}},
+					}},
+				}
+			}); err != nil {
+				return "", "", err
+			}
+		} else if err := r.deleteRoute(ctx, httpRoute); err != nil {
+			return "", "", err
+		}
+	}
+
+	grpcIngress := &networkingv1.Ingress{ObjectMeta: routeMeta(s, grpcRouteSuffix)}
+	if ingresses && grpcPort != 0 {
+		grpcRouted = true
+		if err := r.applyRoute(ctx, s, grpcIngress, grpcAnnotations, func() {
+			grpcIngress.Spec = ingressSpec(erc, grpcIngress.Spec.IngressClassName, s.Name, grpcHost, grpcPort)
+		}); err != nil {
+			return "", "", err
+		}
+	} else if err := r.deleteRoute(ctx, grpcIngress); err != nil {
+		return "", "", err
+	}
+
+	httpIngress := &networkingv1.Ingress{ObjectMeta: routeMeta(s, httpRouteSuffix)}
+	if ingresses && httpPort != 0 {
+		httpRouted = true
+		if err := r.applyRoute(ctx, s, httpIngress, erc.Annotations, func() {
+			httpIngress.Spec = ingressSpec(erc, httpIngress.Spec.IngressClassName, s.Name, httpHost, httpPort)
+		}); err != nil {
+			return "", "", err
+		}
+	} else if err := r.deleteRoute(ctx, httpIngress); err != nil {
+		return "", "", err
+	}
+
+	// the endpoints of the routes which were skipped aren't advertised
+	grpcEndpoint, httpEndpoint = erc.Endpoints(s.Name, s.Namespace, httpRouted)
+	if !grpcRouted {
+		grpcEndpoint = ""
+	}
+	return grpcEndpoint, httpEndpoint, nil
+}
+
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// recordMissingRouteCRD logs and records an event on the Service for a route which isn't created
+func (r *ServiceReconciler) recordMissingRouteCRD(s *corev1.Service, kind string) {
+	r.Log.Info("Skipping external route, the Gateway API CRD is not installed",
+		"kind", kind, "service", s.Name, "namespace", s.Namespace)
+	if r.Recorder != nil {
+		r.Recorder.Eventf(s, corev1.EventTypeWarning, "ExternalRouteSkipped",
+			"The %s CRD of the Gateway API is not installed, the %s route is not created", kind, kind)
+	}
+}
+
+func (r *ServiceReconciler) applyRoute(ctx context.Context, s *corev1.Service, o client.Object,
+	annotations map[string]string, setSpec func()) error {
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, o, func() error {
+		o.SetLabels(s.Labels)
+		o.SetAnnotations(annotations)
+		setSpec()
+		return controllerutil.SetControllerReference(s, o, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("could not create or update %T %s: %w", o, o.GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		r.Log.Info("Reconciled external route", "type", fmt.Sprintf("%T", o),
+			"name", o.GetName(), "namespace", o.GetNamespace(), "result", result)
+	}
+	return nil
+}
+
+func (r *ServiceReconciler) deleteRoute(ctx context.Context, o client.Object) error {
+	if err := r.Client.Delete(ctx, o); err != nil && !k8serr.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return fmt.Errorf("could not delete %T %s: %w", o, o.GetName(), err)
+	}
+	return nil
+}
+
+func routeMeta(s *corev1.Service, suffix string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: s.Name + suffix, Namespace: s.Namespace}
+}
+
+// returns the port number of the named Service port, or 0 if it doesn't exist
+func servicePort(s *corev1.Service, name string) int32 {
+	for _, p := range s.Spec.Ports {
+		if p.Name == name {
+			return p.Port
+		}
+	}
+	return 0
+}
+
+// Fields defaulted by the Gateway API CRDs are set explicitly so that
+// unchanged routes are not updated on every reconciliation
+
+func gatewayCommonRouteSpec(erc config.ExternalRouteConfig) gatewayv1.CommonRouteSpec {
+	group, kind := gatewayv1.Group(gatewayv1.GroupName), gatewayv1.Kind("Gateway")
+	parentRef := gatewayv1.ParentReference{Group: &group, Kind: &kind, Name: gatewayv1.ObjectName(erc.GatewayName)}
+	if erc.GatewayNamespace != "" {
+		ns := gatewayv1.Namespace(erc.GatewayNamespace)
+		parentRef.Namespace = &ns
+	}
+	return gatewayv1.CommonRouteSpec{ParentRefs: []gatewayv1.ParentReference{parentRef}}
+}
+
+func gatewayBackendRef(s *corev1.Service, port int32) gatewayv1.BackendRef {
+	group, kind := gatewayv1.Group(""), gatewayv1.Kind("Service")
+	portNumber, weight := gatewayv1.PortNumber(port), int32(1)
+	return gatewayv1.BackendRef{
+		BackendObjectReference: gatewayv1.BackendObjectReference{
+			Group: &group,
+			Kind:  &kind,
+			Name:  gatewayv1.ObjectName(s.Name),
+			Port:  &portNumber,
+		},
+		Weight: &weight,
+	}
+}
+
+func defaultHTTPRouteMatch() gatewayv1.HTTPRouteMatch {
+	matchType, value := gatewayv1.PathMatchPathPrefix, "/"
+	return gatewayv1.HTTPRouteMatch{Path: &gatewayv1.HTTPPathMatch{Type: &matchType, Value: &value}}
+}
+
+// ingressSpec builds the spec of an Ingress, an existing class name is retained if none is configured
+// since it may have been set by the default IngressClass admission
+func ingressSpec(erc config.ExternalRouteConfig, className *string, serviceName, host string, port int32) networkingv1.IngressSpec {
+	pathType := networkingv1.PathTypePrefix
+	spec := networkingv1.IngressSpec{
+		Rules: []networkingv1.IngressRule{{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{
+							Service: &networkingv1.IngressServiceBackend{
+								Name: serviceName,
+								Port: networkingv1.ServiceBackendPort{Number: port},
+							},
+						},
+					}},
+				},
+			},
+		}},
+	}
+	if erc.IngressClassName != "" {
+		className = &erc.IngressClassName
+	}
+	spec.IngressClassName = className
+	if erc.TLS {
+		spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{host}, SecretName: erc.IngressTLSSecretName}}
+	}
+	return spec
+}
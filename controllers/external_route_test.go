@@ -0,0 +1,189 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+func newExternalRouteReconciler(t *testing.T, httpRouteCRD, grpcRouteCRD bool) (*ServiceReconciler, *corev1.Service) {
+	s := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(s))
+	require.NoError(t, networkingv1.AddToScheme(s))
+	require.NoError(t, gatewayv1.AddToScheme(s))
+	require.NoError(t, gatewayv1alpha2.AddToScheme(s))
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "modelmesh-serving", Namespace: "team-a",
+			Labels: map[string]string{"modelmesh-service": "modelmesh-serving"}},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "grpc", Port: 8033},
+			{Name: "http", Port: 8008},
+		}},
+	}
+	r := &ServiceReconciler{
+		Client:             fake.NewClientBuilder().WithScheme(s).WithObjects(svc).Build(),
+		Log:                logr.Discard(),
+		Scheme:             s,
+		HTTPRouteCRDExists: httpRouteCRD,
+		GRPCRouteCRDExists: grpcRouteCRD,
+		Recorder:           record.NewFakeRecorder(10),
+	}
+	return r, svc
+}
+
+func TestReconcileExternalRoutes(t *testing.T) {
+	r, svc := newExternalRouteReconciler(t, true, true)
+	ctx := context.Background()
+	cfg := &config.Config{ExternalRoute: config.ExternalRouteConfig{
+		Type:            config.ExternalRouteGateway,
+		Domain:          "example.com",
+		GatewayName:     "public",
+		Annotations:     map[string]string{"team": "a"},
+		GrpcAnnotations: map[string]string{"grpc": "true"},
+	}}
+	grpcEndpoint, httpEndpoint, err := r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	assert.Equal(t, "grpc://modelmesh-serving-grpc.team-a.example.com:80", grpcEndpoint)
+	assert.Equal(t, "http://modelmesh-serving.team-a.example.com", httpEndpoint)
+
+	grpcRoute := &gatewayv1alpha2.GRPCRoute{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-grpc", Namespace: "team-a"}, grpcRoute))
+	assert.Equal(t, []gatewayv1alpha2.Hostname{"modelmesh-serving-grpc.team-a.example.com"}, grpcRoute.Spec.Hostnames)
+	assert.Equal(t, map[string]string{"team": "a", "grpc": "true"}, grpcRoute.Annotations)
+	assert.Equal(t, svc.Labels, grpcRoute.Labels)
+	assert.Equal(t, "modelmesh-serving", grpcRoute.OwnerReferences[0].Name)
+	backend := grpcRoute.Spec.Rules[0].BackendRefs[0]
+	assert.Equal(t, gatewayv1.PortNumber(8033), *backend.Port)
+
+	httpRoute := &gatewayv1.HTTPRoute{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-http", Namespace: "team-a"}, httpRoute))
+	assert.Equal(t, []gatewayv1.Hostname{"modelmesh-serving.team-a.example.com"}, httpRoute.Spec.Hostnames)
+	assert.Equal(t, map[string]string{"team": "a"}, httpRoute.Annotations)
+	assert.Equal(t, gatewayv1.ObjectName("public"), httpRoute.Spec.ParentRefs[0].Name)
+	assert.Nil(t, httpRoute.Spec.ParentRefs[0].Namespace)
+	assert.Equal(t, "/", *httpRoute.Spec.Rules[0].Matches[0].Path.Value)
+	assert.Equal(t, gatewayv1.PortNumber(8008), *httpRoute.Spec.Rules[0].BackendRefs[0].Port)
+
+	// switching to Ingresses replaces the routes
+	cfg.ExternalRoute.Type = config.ExternalRouteIngress
+	_, _, err = r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	err = r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-grpc", Namespace: "team-a"}, grpcRoute)
+	assert.True(t, k8serr.IsNotFound(err))
+	err = r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-http", Namespace: "team-a"}, httpRoute)
+	assert.True(t, k8serr.IsNotFound(err))
+	ingress := &networkingv1.Ingress{}
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-grpc", Namespace: "team-a"}, ingress))
+	assert.Equal(t, "modelmesh-serving-grpc.team-a.example.com", ingress.Spec.Rules[0].Host)
+
+	// without the REST port, only the gRPC Ingress is kept
+	svc.Spec.Ports = svc.Spec.Ports[:1]
+	grpcEndpoint, httpEndpoint, err = r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	assert.Equal(t, "grpc://modelmesh-serving-grpc.team-a.example.com:80", grpcEndpoint)
+	assert.Empty(t, httpEndpoint)
+	err = r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-http", Namespace: "team-a"}, ingress)
+	assert.True(t, k8serr.IsNotFound(err))
+
+	// and everything is deleted once disabled
+	cfg.ExternalRoute.Type = ""
+	grpcEndpoint, httpEndpoint, err = r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	assert.Empty(t, grpcEndpoint)
+	assert.Empty(t, httpEndpoint)
+	err = r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-grpc", Namespace: "team-a"}, ingress)
+	assert.True(t, k8serr.IsNotFound(err))
+}
+
+func TestReconcileExternalRoutesWithoutCRDs(t *testing.T) {
+	// the experimental GRPCRoute CRD isn't installed
+	r, svc := newExternalRouteReconciler(t, true, false)
+	ctx := context.Background()
+	cfg := &config.Config{ExternalRoute: config.ExternalRouteConfig{
+		Type: config.ExternalRouteGateway, Domain: "example.com", GatewayName: "public",
+	}}
+	// the skipped route's endpoint isn't advertised
+	grpcEndpoint, httpEndpoint, err := r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	assert.Empty(t, grpcEndpoint)
+	assert.Equal(t, "http://modelmesh-serving.team-a.example.com", httpEndpoint)
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-http", Namespace: "team-a"}, &gatewayv1.HTTPRoute{}))
+	events := r.Recorder.(*record.FakeRecorder).Events
+	assert.Equal(t, "Warning ExternalRouteSkipped The GRPCRoute CRD of the Gateway API is not installed, the GRPCRoute route is not created", <-events)
+
+	// none of them are, the reconcile isn't failed
+	r, svc = newExternalRouteReconciler(t, false, false)
+	grpcEndpoint, httpEndpoint, err = r.reconcileExternalRoutes(ctx, cfg, svc)
+	require.NoError(t, err)
+	assert.Empty(t, grpcEndpoint)
+	assert.Empty(t, httpEndpoint)
+	assert.Len(t, r.Recorder.(*record.FakeRecorder).Events, 2)
+}
+
+func TestIngressSpec(t *testing.T) {
+	erc := config.ExternalRouteConfig{TLS: true, IngressTLSSecretName: "wildcard-tls"}
+	defaultClass := "nginx"
+	spec := ingressSpec(erc, &defaultClass, "modelmesh-serving", "modelmesh-serving.team-a.example.com", 8008)
+	assert.Equal(t, "modelmesh-serving.team-a.example.com", spec.Rules[0].Host)
+	path := spec.Rules[0].HTTP.Paths[0]
+	assert.Equal(t, networkingv1.PathTypePrefix, *path.PathType)
+	assert.Equal(t, "modelmesh-serving", path.Backend.Service.Name)
+	assert.Equal(t, int32(8008), path.Backend.Service.Port.Number)
+	assert.Equal(t, []networkingv1.IngressTLS{{
+		Hosts: []string{"modelmesh-serving.team-a.example.com"}, SecretName: "wildcard-tls",
+	}}, spec.TLS)
+	// the class set by the default IngressClass admission is retained
+	assert.Equal(t, "nginx", *spec.IngressClassName)
+
+	erc = config.ExternalRouteConfig{IngressClassName: "internal"}
+	spec = ingressSpec(erc, &defaultClass, "modelmesh-serving", "modelmesh-serving.team-a.example.com", 8008)
+	assert.Equal(t, "internal", *spec.IngressClassName)
+	assert.Empty(t, spec.TLS)
+}
+
+func TestGatewayRouteBuilders(t *testing.T) {
+	parentRef := gatewayCommonRouteSpec(config.ExternalRouteConfig{GatewayName: "public", GatewayNamespace: "gateways"}).ParentRefs[0]
+	assert.Equal(t, gatewayv1.Group("gateway.networking.k8s.io"), *parentRef.Group)
+	assert.Equal(t, gatewayv1.Kind("Gateway"), *parentRef.Kind)
+	assert.Equal(t, gatewayv1.Namespace("gateways"), *parentRef.Namespace)
+
+	backend := gatewayBackendRef(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "modelmesh-serving"}}, 8033)
+	assert.Equal(t, gatewayv1.Group(""), *backend.Group)
+	assert.Equal(t, gatewayv1.Kind("Service"), *backend.Kind)
+	assert.Equal(t, gatewayv1.ObjectName("modelmesh-serving"), backend.Name)
+	assert.Equal(t, gatewayv1.PortNumber(8033), *backend.Port)
+	assert.Equal(t, int32(1), *backend.Weight)
+
+	match := defaultHTTPRouteMatch()
+	assert.Equal(t, gatewayv1.PathMatchPathPrefix, *match.Path.Type)
+	assert.Equal(t, "/", *match.Path.Value)
+}
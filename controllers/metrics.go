@@ -0,0 +1,92 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+)
+
+var runtimeReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "modelmesh_controller_runtime_replicas",
+	Help: "Number of replicas which the controller determined for each ServingRuntime, 0 when scaled to zero",
+}, []string{"namespace", "runtime"})
+
+func init() {
+	metrics.Registry.MustRegister(runtimeReplicas)
+}
+
+// predictorCollectTimeout bounds listing the cached Predictors on each scrape
+const predictorCollectTimeout = 10 * time.Second
+
+// PredictorCollector reports the number of Predictors of each source by state. They're
+// counted from the cached Predictors on each scrape, so that deleted Predictors and
+// namespaces don't leave stale series behind. The lists are served by the informer
+// caches of the manager rather than the API server.
+type PredictorCollector struct {
+	registries map[string]predictor_source.PredictorRegistry
+	desc       *prometheus.Desc
+}
+
+func NewPredictorCollector(registries map[string]predictor_source.PredictorRegistry) *PredictorCollector {
+	return &PredictorCollector{
+		registries: registries,
+		desc: prometheus.NewDesc("modelmesh_controller_predictors",
+			"Number of Predictors by active model state and transition status",
+			[]string{"source", "namespace", "active_model_state", "transition_status"}, nil),
+	}
+}
+
+func (c *PredictorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *PredictorCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), predictorCollectTimeout)
+	defer cancel()
+	counts := map[[4]string]int{}
+	for _, registry := range c.registries {
+		source := registry.GetSourceName()
+		count := func(p *api.Predictor) bool {
+			counts[[4]string{source, p.Namespace, string(p.Status.ActiveModelState), string(p.Status.TransitionStatus)}]++
+			return false
+		}
+		var err error
+		if lister, ok := registry.(predictor_source.PredictorLister); ok {
+			// unlike Find, List doesn't stop at invalid items
+			var predictors []*api.Predictor
+			if predictors, err = lister.List(ctx, ""); err == nil {
+				for _, p := range predictors {
+					count(p)
+				}
+			}
+		} else {
+			_, err = registry.Find(ctx, "", count)
+		}
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(c.desc, err)
+			return
+		}
+	}
+	for labels, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), labels[:]...)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kserve/kserve/pkg/apis/serving/v1beta1"
+	kserveConstants "github.com/kserve/kserve/pkg/constants"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+)
+
+func TestPredictorCollector(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, api.AddToScheme(s))
+	predictor := func(name, namespace string, state api.ModelState, transition api.TransitionStatus) *api.Predictor {
+		return &api.Predictor{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     api.PredictorStatus{ActiveModelState: state, TransitionStatus: transition},
+		}
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		predictor("a", "team-a", api.Loaded, api.UpToDate),
+		predictor("b", "team-a", api.Loaded, api.UpToDate),
+		predictor("c", "team-a", api.FailedToLoad, api.BlockedByFailedLoad),
+		predictor("d", "team-b", api.Loaded, api.UpToDate),
+	).Build()
+
+	collector := NewPredictorCollector(map[string]predictor_source.PredictorRegistry{
+		PredictorCRSourceId: predictor_source.PredictorCRRegistry{Client: cl},
+	})
+
+	expected := `
+# HELP modelmesh_controller_predictors Number of Predictors by active model state and transition status
+# TYPE modelmesh_controller_predictors gauge
+modelmesh_controller_predictors{active_model_state="FailedToLoad",namespace="team-a",source="Predictor",transition_status="BlockedByFailedLoad"} 1
+modelmesh_controller_predictors{active_model_state="Loaded",namespace="team-a",source="Predictor",transition_status="UpToDate"} 2
+modelmesh_controller_predictors{active_model_state="Loaded",namespace="team-b",source="Predictor",transition_status="UpToDate"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestPredictorCollector_InferenceServices(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, v1beta1.AddToScheme(s))
+	storageURI := "s3://models/model"
+	isvc := func(name string, annotations map[string]string, status *v1beta1.ModelRevisionStates,
+		transition v1beta1.TransitionStatus) *v1beta1.InferenceService {
+		return &v1beta1.InferenceService{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", Annotations: annotations},
+			Spec: v1beta1.InferenceServiceSpec{Predictor: v1beta1.PredictorSpec{
+				Model: &v1beta1.ModelSpec{
+					ModelFormat:            v1beta1.ModelFormat{Name: "sklearn"},
+					PredictorExtensionSpec: v1beta1.PredictorExtensionSpec{StorageURI: &storageURI},
+				},
+			}},
+			Status: v1beta1.InferenceServiceStatus{ModelStatus: v1beta1.ModelStatus{
+				TransitionStatus: transition, ModelRevisionStates: status,
+			}},
+		}
+	}
+	modelMesh := map[string]string{kserveConstants.DeploymentMode: string(kserveConstants.ModelMeshDeployment)}
+	loaded := &v1beta1.ModelRevisionStates{ActiveModelState: v1beta1.Loaded, TargetModelState: v1beta1.Loaded}
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		// invalid, since it has both the model spec and the runtime annotation
+		isvc("a-invalid", map[string]string{
+			kserveConstants.DeploymentMode:     string(kserveConstants.ModelMeshDeployment),
+			"serving.kserve.io/servingRuntime": "mlserver",
+		}, loaded, v1beta1.UpToDate),
+		isvc("b", modelMesh, loaded, v1beta1.UpToDate),
+		isvc("c", modelMesh, loaded, v1beta1.UpToDate),
+		isvc("d", modelMesh, nil, ""),
+		// not deployed by model-mesh
+		isvc("e", nil, loaded, v1beta1.UpToDate),
+	).Build()
+
+	collector := NewPredictorCollector(map[string]predictor_source.PredictorRegistry{
+		InferenceServiceCRSourceId: predictor_source.InferenceServiceRegistry{Client: cl},
+	})
+
+	expected := `
+# HELP modelmesh_controller_predictors Number of Predictors by active model state and transition status
+# TYPE modelmesh_controller_predictors gauge
+modelmesh_controller_predictors{active_model_state="Loaded",namespace="team-a",source="InferenceService",transition_status="UpToDate"} 2
+modelmesh_controller_predictors{active_model_state="Pending",namespace="team-a",source="InferenceService",transition_status=""} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
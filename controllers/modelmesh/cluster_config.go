@@ -60,7 +60,9 @@ var dataPlaneApiJsonConfigBytes = []byte(`{
 // a logical model mesh cluster
 type ClusterConfig struct {
 	SRSpecs map[string]*kserveapi.ServingRuntimeSpec
-	Scheme  *runtime.Scheme
+	// whether the REST proxy is injected into the pods of each runtime
+	RESTProxyEnabled map[string]bool
+	Scheme           *runtime.Scheme
 }
 
 func (cc ClusterConfig) Reconcile(ctx context.Context, namespace string, cl client.Client, cfg *config.Config) error {
@@ -88,7 +90,7 @@ func (cc ClusterConfig) Reconcile(ctx context.Context, namespace string, cl clie
 			"app.kubernetes.io/name":       commonLabelValue,
 		},
 	}
-	cc.addConstraints(cc.SRSpecs, m, cfg.RESTProxy.Enabled)
+	cc.addConstraints(cc.SRSpecs, m, cc.RESTProxyEnabled)
 
 	if notfound {
 		return cl.Create(ctx, m)
@@ -98,7 +100,7 @@ func (cc ClusterConfig) Reconcile(ctx context.Context, namespace string, cl clie
 }
 
 // Add constraint data to the provided config map
-func (cc ClusterConfig) addConstraints(srSpecs map[string]*kserveapi.ServingRuntimeSpec, m *corev1.ConfigMap, restProxyEnabled bool) {
+func (cc ClusterConfig) addConstraints(srSpecs map[string]*kserveapi.ServingRuntimeSpec, m *corev1.ConfigMap, restProxyEnabled map[string]bool) {
 	b := calculateConstraintData(srSpecs, restProxyEnabled)
 	if m.BinaryData == nil {
 		m.BinaryData = make(map[string][]byte)
@@ -107,7 +109,7 @@ func (cc ClusterConfig) addConstraints(srSpecs map[string]*kserveapi.ServingRunt
 	m.BinaryData[MMDataPlaneConfigKey] = dataPlaneApiJsonConfigBytes
 }
 
-func calculateConstraintData(srSpecs map[string]*kserveapi.ServingRuntimeSpec, restProxyEnabled bool) []byte {
+func calculateConstraintData(srSpecs map[string]*kserveapi.ServingRuntimeSpec, restProxyEnabled map[string]bool) []byte {
 
 	/*b := []byte(`{
 	  "rt:tf-serving-runtime": {
@@ -127,7 +129,7 @@ func calculateConstraintData(srSpecs map[string]*kserveapi.ServingRuntimeSpec, r
 	m := make(map[string]interface{})
 	for name, spec := range srSpecs {
 		if !spec.IsDisabled() && spec.IsMultiModelRuntime() {
-			mtLabels, pvLabels, rtLabel := GetServingRuntimeLabelSets(spec, restProxyEnabled, name)
+			mtLabels, pvLabels, rtLabel := GetServingRuntimeLabelSets(spec, restProxyEnabled[name], name)
 			m[rtLabel] = map[string]interface{}{"required": []string{rtLabel}}
 			// treat each combo of model-type label and proto version label as a separate model type
 			for l := range mtLabels {
@@ -63,7 +63,40 @@ func TestCalculateConstraintData(t *testing.T) {
 	srSpecs := make(map[string]*kserveapi.ServingRuntimeSpec)
 	srSpecs[l.Items[0].GetName()] = &l.Items[0].Spec
 
-	res := calculateConstraintData(srSpecs, false)
+	res := calculateConstraintData(srSpecs, nil)
+
+	if string(res) != expected {
+		t.Errorf("%v did not match expected %v", string(res), expected)
+	}
+}
+
+func TestCalculateConstraintDataRESTProxy(t *testing.T) {
+	// only the runtime with the REST proxy serves the v2 protocol over HTTP
+	expected := `{"_default":{"required":["_no_runtime"]},` +
+		`"mt:onnx":{"required":["mt:onnx"]},` +
+		`"mt:onnx|pv:grpc-v2":{"required":["mt:onnx","pv:grpc-v2"]},` +
+		`"mt:onnx|pv:v2":{"required":["mt:onnx","pv:v2"]},` +
+		`"mt:pytorch":{"required":["mt:pytorch"]},` +
+		`"mt:pytorch|pv:grpc-v2":{"required":["mt:pytorch","pv:grpc-v2"]},` +
+		`"rt:native-http-runtime":{"required":["rt:native-http-runtime"]},` +
+		`"rt:proxied-runtime":{"required":["rt:proxied-runtime"]}}`
+
+	a := true
+	mm := true
+	spec := func(modelType string) *kserveapi.ServingRuntimeSpec {
+		return &kserveapi.ServingRuntimeSpec{
+			SupportedModelFormats: []kserveapi.SupportedModelFormat{{Name: modelType, AutoSelect: &a}},
+			ProtocolVersions:      []constants.InferenceServiceProtocol{constants.ProtocolGRPCV2},
+			MultiModel:            &mm,
+		}
+	}
+	srSpecs := map[string]*kserveapi.ServingRuntimeSpec{
+		"proxied-runtime":     spec("onnx"),
+		"native-http-runtime": spec("pytorch"),
+	}
+	restProxyEnabled := map[string]bool{"proxied-runtime": true, "native-http-runtime": false}
+
+	res := calculateConstraintData(srSpecs, restProxyEnabled)
 
 	if string(res) != expected {
 		t.Errorf("%v did not match expected %v", string(res), expected)
@@ -17,14 +17,23 @@ package modelmesh
 const (
 	ModelMeshContainerName = "mm"
 	RESTProxyContainerName = "rest-proxy"
+	// serves the OpenAI API next to the REST proxy
+	OpenAIProxyContainerName = "openai-proxy"
+	// serves the REST port in front of the REST proxy when its listener options are set
+	RESTProxyListenerContainerName = "rest-proxy-listener"
 
 	GrpcPortEnvVar         = "INTERNAL_GRPC_PORT"
 	ServeGrpcPortEnvVar    = "INTERNAL_SERVING_GRPC_PORT"
 	GrpcUdsPathEnvVar      = "INTERNAL_GRPC_SOCKET_PATH"
 	ServeGrpcUdsPathEnvVar = "INTERNAL_SERVING_GRPC_SOCKET_PATH"
 
-	EtcdSecretKey = "etcd_connection"
-	EtcdVolume    = "etcd-config"
+	EtcdSecretKey      = "etcd_connection"
+	EtcdVolume         = "etcd-config"
+	ZookeeperSecretKey = "zk_connection"
+
+	// KV store types, determined by the key present in the KV store secret
+	KVStoreEtcd      = "etcd"
+	KVStoreZookeeper = "zookeeper"
 
 	ModelsDirVolume = "models-dir"
 	SocketVolume    = "domain-socket"
@@ -24,15 +24,20 @@ const (
 	kvStoreEnvVar = "KV_STORE"
 )
 
-// mimics base/patches/etcd.yaml
-func (m *Deployment) configureMMDeploymentForEtcdSecret(deployment *appsv1.Deployment) error {
+// mimics base/patches/etcd.yaml. The KV store secret is mounted for ZooKeeper too,
+// but model-mesh is then given the connection string directly.
+func (m *Deployment) configureMMDeploymentForKVStore(deployment *appsv1.Deployment) error {
 	EtcdSecretName := m.EtcdSecretName
+	kvStore := "etcd:" + etcdMountPath + "/" + EtcdSecretKey
+	if m.KVStoreType == KVStoreZookeeper {
+		kvStore = "zookeeper:" + m.ZookeeperConnection
+	}
 
 	for containerI, container := range deployment.Spec.Template.Spec.Containers {
 		if container.Name == ModelMeshContainerName {
 			for i, env := range container.Env {
 				if env.Name == kvStoreEnvVar {
-					env.Value = "etcd:" + etcdMountPath + "/" + EtcdSecretKey
+					env.Value = kvStore
 				}
 				container.Env[i] = env
 			}
@@ -16,12 +16,15 @@ package modelmesh
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strconv"
 
 	"github.com/go-logr/logr"
 	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
 	"github.com/kserve/modelmesh-serving/controllers/config"
+	config2 "github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 	mf "github.com/manifestival/manifestival"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -57,6 +60,21 @@ type Deployment struct {
 	RESTProxyResources  *corev1.ResourceRequirements
 	RESTProxyPort       uint16
 	PVCs                []string
+	ModelCache          config2.ModelCacheConfig
+	// HTTP listener options of the REST port, enforced by a sidecar in front of the REST proxy
+	// which then listens on the backend port
+	RESTProxyListener          config2.HTTPListenerConfig
+	RESTProxyListenerImage     string
+	RESTProxyListenerCommand   []string
+	RESTProxyListenerResources *corev1.ResourceRequirements
+	RESTProxyBackendPort       uint16
+	// OpenAI API sidecar of the REST proxy
+	OpenAIProxyEnabled   bool
+	OpenAIProxyImage     string
+	OpenAIProxyCommand   []string
+	OpenAIProxyPort      uint16
+	OpenAIProxyResources *corev1.ResourceRequirements
+	OpenAIProxyListener  config2.HTTPListenerConfig
 	// internal fields used when templating
 	ModelMeshLimitCPU          string
 	ModelMeshRequestsCPU       string
@@ -71,7 +89,10 @@ type Deployment struct {
 	Port                uint16
 	TLSSecretName       string
 	TLSClientAuth       string
+	TLSCertManaged      bool
 	EtcdSecretName      string
+	KVStoreType         string
+	ZookeeperConnection string
 	ServiceAccountName  string
 	GrpcMaxMessageSize  int
 	AnnotationConfigMap *corev1.ConfigMap
@@ -80,6 +101,38 @@ type Deployment struct {
 	ImagePullSecrets    []corev1.LocalObjectReference
 	EnableAccessLogging bool
 	Client              client.Client
+	// text or json, the format of all of model-mesh's logs
+	ModelMeshLogFormat string
+	// options of the access logs when enabled
+	AccessLogRequestHeaders []string
+	// fraction of the requests logged by the OpenAI API sidecar, model-mesh logs all of them
+	OpenAIAccessLogSampleRatio float64
+}
+
+// RuntimeAccessLoggingEnabled applies the runtime's annotation overriding whether model-mesh
+// logs each inference request to the configured default. The default is returned along with
+// the error if the annotation can't be parsed.
+func RuntimeAccessLoggingEnabled(annotations map[string]string, enabled bool) (bool, error) {
+	return runtimeBoolAnnotation(annotations, constants.AccessLoggingAnnotationKey, enabled)
+}
+
+// RuntimeOpenAIAccessLogSampleRatio applies the runtime's annotation overriding the fraction of
+// the requests logged by the OpenAI API sidecar. The default is returned along with the error
+// if the annotation can't be parsed or isn't between 0 and 1.
+func RuntimeOpenAIAccessLogSampleRatio(annotations map[string]string, ratio float64) (float64, error) {
+	key := constants.OpenAIAccessLogSampleRatioAnnotationKey
+	annotation, ok := annotations[key]
+	if !ok {
+		return ratio, nil
+	}
+	override, err := strconv.ParseFloat(annotation, 64)
+	if err != nil {
+		return ratio, fmt.Errorf("could not parse annotation %s: %w", key, err)
+	}
+	if override < 0 || override > 1 {
+		return ratio, fmt.Errorf("could not parse annotation %s: must be between 0 and 1, got %v", key, override)
+	}
+	return override, nil
 }
 
 func (m *Deployment) Apply(ctx context.Context) error {
@@ -118,14 +171,16 @@ func (m *Deployment) Apply(ctx context.Context) error {
 			}
 
 			if tErr := m.transform(deployment,
-				m.addVolumesToDeployment,
+				func(deployment *appsv1.Deployment) error {
+					return m.addVolumesToDeployment(ctx, deployment)
+				},
 				m.addMMDomainSocketMount,
 				m.addPassThroughPodFieldsToDeployment,
 				m.addRuntimeToDeployment,
 				m.syncGracePeriod,
 				m.addMMEnvVars,
 				m.addModelTypeConstraints,
-				m.configureMMDeploymentForEtcdSecret,
+				m.configureMMDeploymentForKVStore,
 				m.addRESTProxyToDeployment,
 				m.configureMMDeploymentForTLSSecret,
 				m.configureRuntimePodSpecAnnotations,
@@ -261,11 +316,32 @@ func (m *Deployment) addMMEnvVars(deployment *appsv1.Deployment) error {
 		}
 	}
 
+	if m.ModelMeshLogFormat == config2.LogFormatJSON {
+		// the start script of model-mesh then uses its JSON logging configuration for all its logs
+		if err := setEnvironmentVar(ModelMeshContainerName, "MM_JSON_LOGGING", "true", deployment); err != nil {
+			return err
+		}
+	}
+
 	if m.EnableAccessLogging {
 		// See https://github.com/kserve/modelmesh/blob/v0.11.1/src/main/java/com/ibm/watson/modelmesh/ModelMeshEnvVars.java#L55
 		if err := setEnvironmentVar(ModelMeshContainerName, "MM_LOG_EACH_INVOKE", "true", deployment); err != nil {
 			return err
 		}
+		if len(m.AccessLogRequestHeaders) > 0 {
+			// JSON object of the request headers to log, mapped to the names of their log fields
+			fields := make(map[string]string, len(m.AccessLogRequestHeaders))
+			for _, header := range m.AccessLogRequestHeaders {
+				fields[header] = header
+			}
+			b, err := json.Marshal(fields)
+			if err != nil {
+				return err
+			}
+			if err = setEnvironmentVar(ModelMeshContainerName, "MM_LOG_REQUEST_HEADERS", string(b), deployment); err != nil {
+				return err
+			}
+		}
 	}
 
 	if m.GrpcMaxMessageSize > 0 {
@@ -22,6 +22,12 @@ import (
 	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 )
 
 func TestAddMMDomainSocketMount(t *testing.T) {
@@ -85,6 +91,86 @@ func TestEnableAccessLogging(t *testing.T) {
 	}
 }
 
+func TestAccessLogOptions(t *testing.T) {
+	rt := &kserveapi.ServingRuntime{}
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+		},
+	}}
+	m := &Deployment{Owner: rt, EnableAccessLogging: true, SRSpec: &rt.Spec,
+		ModelMeshLogFormat: config.LogFormatJSON, AccessLogRequestHeaders: []string{"x-request-id"}}
+	assert.NoError(t, m.addMMEnvVars(d))
+
+	_, c := findContainer(ModelMeshContainerName, d)
+	env := map[string]string{}
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "true", env["MM_JSON_LOGGING"])
+	assert.Equal(t, `{"x-request-id":"x-request-id"}`, env["MM_LOG_REQUEST_HEADERS"])
+
+	// the log format applies to all of model-mesh's logs, not only the access logs
+	m.EnableAccessLogging = false
+	d.Spec.Template.Spec.Containers[0].Env = nil
+	assert.NoError(t, m.addMMEnvVars(d))
+	_, c = findContainer(ModelMeshContainerName, d)
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "MM_JSON_LOGGING", Value: "true"})
+	assert.NotContains(t, c.Env, corev1.EnvVar{Name: "MM_LOG_EACH_INVOKE", Value: "true"})
+
+	// runtimes can opt out of the access logs
+	enabled, err := RuntimeAccessLoggingEnabled(map[string]string{constants.AccessLoggingAnnotationKey: "false"}, true)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestOpenAIProxyAccessLog(t *testing.T) {
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+		},
+	}}
+	m := &Deployment{
+		RESTProxyEnabled: true, RESTProxyPort: 8008, RESTProxyResources: &corev1.ResourceRequirements{},
+		OpenAIProxyEnabled: true, OpenAIProxyPort: 8009, OpenAIProxyResources: &corev1.ResourceRequirements{},
+		EnableAccessLogging: true, ModelMeshLogFormat: config.LogFormatJSON,
+		AccessLogRequestHeaders: []string{"x-request-id", "x-tenant"}, OpenAIAccessLogSampleRatio: 0.25,
+	}
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	_, c := findContainer(OpenAIProxyContainerName, d)
+	env := map[string]string{}
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "json", env["OPENAI_PROXY_ACCESS_LOG_FORMAT"])
+	assert.Equal(t, "0.25", env["OPENAI_PROXY_ACCESS_LOG_SAMPLE_RATIO"])
+	assert.Equal(t, "x-request-id,x-tenant", env["OPENAI_PROXY_ACCESS_LOG_REQUEST_HEADERS"])
+
+	// the sidecar doesn't log when model-mesh doesn't
+	m.EnableAccessLogging = false
+	d.Spec.Template.Spec.Containers = d.Spec.Template.Spec.Containers[:1]
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	_, c = findContainer(OpenAIProxyContainerName, d)
+	for _, e := range c.Env {
+		assert.NotEqual(t, "OPENAI_PROXY_ACCESS_LOG_FORMAT", e.Name)
+	}
+
+	// runtimes can override the sample ratio
+	ratio, err := RuntimeOpenAIAccessLogSampleRatio(nil, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, ratio)
+	ratio, err = RuntimeOpenAIAccessLogSampleRatio(
+		map[string]string{constants.OpenAIAccessLogSampleRatioAnnotationKey: "0.01"}, 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.01, ratio)
+	for _, invalid := range []string{"all", "-0.5", "2"} {
+		ratio, err = RuntimeOpenAIAccessLogSampleRatio(
+			map[string]string{constants.OpenAIAccessLogSampleRatioAnnotationKey: invalid}, 0.5)
+		assert.ErrorContains(t, err, "could not parse annotation "+constants.OpenAIAccessLogSampleRatioAnnotationKey)
+		assert.Equal(t, 0.5, ratio)
+	}
+}
+
 func TestSetConfigMap(t *testing.T) {
 	rt := &kserveapi.ServingRuntime{}
 	m := Deployment{Owner: rt, SRSpec: &rt.Spec}
@@ -123,3 +209,244 @@ func TestModelMeshAdditionalEnvVars(t *testing.T) {
 		t.Fatal("Expected to find an env variable ENV_VAR but not found")
 	}
 }
+
+func TestConfigureMMDeploymentForKVStore(t *testing.T) {
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "mm",
+							Env:  []corev1.EnvVar{{Name: kvStoreEnvVar}},
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	d := newDeployment()
+	m := &Deployment{EtcdSecretName: "model-serving-etcd", KVStoreType: KVStoreEtcd}
+	assert.NoError(t, m.configureMMDeploymentForKVStore(d))
+	_, c := findContainer("mm", d)
+	assert.Equal(t, "etcd:/opt/kserve/mmesh/etcd/etcd_connection", c.Env[0].Value)
+	assert.Equal(t, "model-serving-etcd", d.Spec.Template.Spec.Volumes[0].Secret.SecretName)
+
+	d = newDeployment()
+	m = &Deployment{EtcdSecretName: "model-serving-etcd", KVStoreType: KVStoreZookeeper,
+		ZookeeperConnection: "zk-0:2181,zk-1:2181/mmesh/mm_ns/user-ns"}
+	assert.NoError(t, m.configureMMDeploymentForKVStore(d))
+	_, c = findContainer("mm", d)
+	assert.Equal(t, "zookeeper:zk-0:2181,zk-1:2181/mmesh/mm_ns/user-ns", c.Env[0].Value)
+}
+
+func TestConfigureMMDeploymentForTLSSecretHash(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "modelmesh-certificate", Namespace: "user-ns"},
+		Data:       map[string][]byte{TLSSecretCertKey: []byte("cert"), TLSSecretKeyKey: []byte("key")},
+	}
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+			},
+		}}
+	}
+	hash := func(managed bool) string {
+		m := &Deployment{Namespace: "user-ns", TLSSecretName: secret.Name, TLSCertManaged: managed,
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret.DeepCopy()).Build()}
+		d := newDeployment()
+		assert.NoError(t, m.configureMMDeploymentForTLSSecret(d))
+		assert.Equal(t, secret.Name, d.Spec.Template.Spec.Volumes[0].Secret.SecretName)
+		return d.Spec.Template.Annotations[constants.TLSSecretHashAnnotationKey]
+	}
+
+	// user provided secrets don't roll the pods
+	assert.Empty(t, hash(false))
+
+	first := hash(true)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, hash(true))
+
+	// a renewed certificate changes the hash
+	secret.Data[TLSSecretCertKey] = []byte("renewed cert")
+	assert.NotEqual(t, first, hash(true))
+}
+
+func TestAddOpenAIProxyListenerOptions(t *testing.T) {
+	newDeployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+			},
+		}}
+	}
+	containerEnv := func(d *appsv1.Deployment, name string) map[string]string {
+		_, c := findContainer(name, d)
+		if c == nil {
+			t.Fatalf("Could not find the %s container", name)
+		}
+		env := map[string]string{}
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		return env
+	}
+	m := &Deployment{
+		RESTProxyEnabled: true, RESTProxyPort: 8008, RESTProxyResources: &corev1.ResourceRequirements{},
+		OpenAIProxyEnabled: true, OpenAIProxyPort: 8009, OpenAIProxyResources: &corev1.ResourceRequirements{},
+	}
+
+	// the listener options are only set when configured
+	d := newDeployment()
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	env := containerEnv(d, OpenAIProxyContainerName)
+	assert.NotContains(t, env, "OPENAI_PROXY_CORS_ALLOWED_ORIGINS")
+	assert.NotContains(t, env, "OPENAI_PROXY_AUTH_TYPE")
+	assert.Empty(t, d.Spec.Template.Spec.Volumes)
+
+	m.OpenAIProxyListener = config.HTTPListenerConfig{
+		CORSAllowedOrigins:  []string{"https://app.example.com", "http://localhost:3000"},
+		MaxRequestBodyBytes: 1048576,
+		Auth:                config.HTTPListenerAuthConfig{Type: config.HTTPListenerAuthBearer, TokenSecretName: "openai-proxy-tokens"},
+		TLS:                 config.HTTPListenerTLSConfig{SecretName: "openai-proxy-tls"},
+	}
+	d = newDeployment()
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	env = containerEnv(d, OpenAIProxyContainerName)
+	assert.Equal(t, "https://app.example.com,http://localhost:3000", env["OPENAI_PROXY_CORS_ALLOWED_ORIGINS"])
+	assert.Equal(t, "1048576", env["OPENAI_PROXY_MAX_REQUEST_BODY_BYTES"])
+	assert.Equal(t, "bearer", env["OPENAI_PROXY_AUTH_TYPE"])
+	assert.Equal(t, "/opt/kserve/openai-proxy/auth/tokens", env["OPENAI_PROXY_AUTH_TOKENS_PATH"])
+	assert.Equal(t, "/opt/kserve/openai-proxy/tls/tls.crt", env["OPENAI_PROXY_LISTEN_TLS_CERT_PATH"])
+	assert.Equal(t, "/opt/kserve/openai-proxy/tls/tls.key", env["OPENAI_PROXY_LISTEN_TLS_KEY_PATH"])
+	volumes := d.Spec.Template.Spec.Volumes
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "openai-proxy-tls", volumes[0].Secret.SecretName)
+	assert.Equal(t, "openai-proxy-tokens", volumes[1].Secret.SecretName)
+
+	// they don't apply to the REST proxy
+	env = containerEnv(d, RESTProxyContainerName)
+	assert.NotContains(t, env, "OPENAI_PROXY_CORS_ALLOWED_ORIGINS")
+	assert.NotContains(t, env, "OPENAI_PROXY_AUTH_TYPE")
+	_, c := findContainer(RESTProxyContainerName, d)
+	assert.Empty(t, c.VolumeMounts)
+
+	// mTLS clients are verified against the CAs of the listener's secret
+	m.OpenAIProxyListener.Auth = config.HTTPListenerAuthConfig{Type: config.HTTPListenerAuthMTLS}
+	d = newDeployment()
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	env = containerEnv(d, OpenAIProxyContainerName)
+	assert.Equal(t, "mtls", env["OPENAI_PROXY_AUTH_TYPE"])
+	assert.Equal(t, "/opt/kserve/openai-proxy/tls/ca.crt", env["OPENAI_PROXY_LISTEN_TLS_CLIENT_CA_PATH"])
+	assert.Len(t, d.Spec.Template.Spec.Volumes, 1)
+}
+
+func TestAddRESTProxyListener(t *testing.T) {
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+		},
+	}}
+	m := &Deployment{
+		RESTProxyEnabled: true, RESTProxyPort: 8008, RESTProxyResources: &corev1.ResourceRequirements{},
+		RESTProxyListenerImage:     "kserve/modelmesh-controller:latest",
+		RESTProxyListenerCommand:   []string{"/manager", "rest-proxy-listener"},
+		RESTProxyListenerResources: &corev1.ResourceRequirements{},
+		RESTProxyBackendPort:       8018,
+	}
+
+	// the REST proxy serves the REST port itself without listener options
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+	_, c := findContainer(RESTProxyListenerContainerName, d)
+	assert.Nil(t, c)
+	_, c = findContainer(RESTProxyContainerName, d)
+	assert.Equal(t, []corev1.ContainerPort{{Name: "http", ContainerPort: 8008}}, c.Ports)
+
+	m.RESTProxyListener = config.HTTPListenerConfig{
+		MaxRequestBodyBytes: 1048576,
+		Auth:                config.HTTPListenerAuthConfig{Type: config.HTTPListenerAuthBearer, TokenSecretName: "rest-proxy-tokens"},
+	}
+	m.TLSSecretName = "mm-tls"
+	d.Spec.Template.Spec.Containers = d.Spec.Template.Spec.Containers[:1]
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+
+	// the listener takes over the REST port and forwards to the REST proxy on the backend port
+	_, c = findContainer(RESTProxyContainerName, d)
+	assert.Equal(t, []corev1.ContainerPort{{Name: "rest-proxy", ContainerPort: 8018}}, c.Ports)
+	assert.Contains(t, c.Env, corev1.EnvVar{Name: "REST_PROXY_LISTEN_PORT", Value: "8018"})
+	_, c = findContainer(RESTProxyListenerContainerName, d)
+	assert.Equal(t, []string{"/manager", "rest-proxy-listener"}, c.Command)
+	assert.Equal(t, []corev1.ContainerPort{{Name: "http", ContainerPort: 8008}}, c.Ports)
+	env := map[string]string{}
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "8008", env["REST_PROXY_LISTENER_LISTEN_PORT"])
+	assert.Equal(t, "8018", env["REST_PROXY_LISTENER_BACKEND_PORT"])
+	assert.Equal(t, "true", env["REST_PROXY_USE_TLS"])
+	assert.NotContains(t, env, "REST_PROXY_LISTEN_PORT")
+	assert.Equal(t, "1048576", env["REST_PROXY_LISTENER_MAX_REQUEST_BODY_BYTES"])
+	assert.Equal(t, "bearer", env["REST_PROXY_LISTENER_AUTH_TYPE"])
+	assert.Equal(t, "/opt/kserve/rest-proxy-listener/auth/tokens", env["REST_PROXY_LISTENER_AUTH_TOKENS_PATH"])
+	// without a certificate of its own it serves with that of model-mesh
+	assert.Equal(t, "/opt/kserve/rest-proxy-listener/tls/tls.crt", env["REST_PROXY_LISTENER_LISTEN_TLS_CERT_PATH"])
+	volumes := d.Spec.Template.Spec.Volumes
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "mm-tls", volumes[0].Secret.SecretName)
+	assert.Equal(t, "rest-proxy-tokens", volumes[1].Secret.SecretName)
+}
+
+func TestAddOpenAIProxyToDeployment(t *testing.T) {
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: ModelMeshContainerName}}},
+		},
+	}}
+	m := &Deployment{
+		RESTProxyEnabled: true, RESTProxyPort: 8008, RESTProxyResources: &corev1.ResourceRequirements{},
+		GrpcMaxMessageSize:   16777216,
+		OpenAIProxyEnabled:   true,
+		OpenAIProxyImage:     "kserve/modelmesh-controller:latest",
+		OpenAIProxyCommand:   []string{"/manager", "openai-proxy"},
+		OpenAIProxyPort:      8009,
+		OpenAIProxyResources: &corev1.ResourceRequirements{},
+	}
+	assert.NoError(t, m.addRESTProxyToDeployment(d))
+
+	_, c := findContainer(OpenAIProxyContainerName, d)
+	if c == nil {
+		t.Fatal("Could not find the OpenAI proxy container")
+	}
+	assert.Equal(t, []string{"/manager", "openai-proxy"}, c.Command)
+	assert.Equal(t, "openai", c.Ports[0].Name)
+	assert.Equal(t, int32(8009), c.Ports[0].ContainerPort)
+	env := map[string]string{}
+	for _, e := range c.Env {
+		env[e.Name] = e.Value
+	}
+	// it shares the configuration of the REST proxy, except the listen port
+	assert.Equal(t, "8009", env["OPENAI_PROXY_LISTEN_PORT"])
+	assert.NotContains(t, env, restProxyPortEnvVar)
+	assert.Equal(t, "16777216", env[restProxyGrpcMaxMsgSizeEnvVar])
+}
+
+func TestRuntimeRESTProxyEnabled(t *testing.T) {
+	// the configured default applies without the annotation
+	enabled, err := RuntimeRESTProxyEnabled(nil, true)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	// runtimes can opt out of and into the REST proxy
+	enabled, err = RuntimeRESTProxyEnabled(map[string]string{constants.RESTProxyAnnotationKey: "false"}, true)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+	enabled, err = RuntimeRESTProxyEnabled(map[string]string{constants.RESTProxyAnnotationKey: "true"}, false)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	enabled, err = RuntimeRESTProxyEnabled(map[string]string{constants.RESTProxyAnnotationKey: "sometimes"}, true)
+	assert.ErrorContains(t, err, constants.RESTProxyAnnotationKey)
+	assert.True(t, enabled)
+}
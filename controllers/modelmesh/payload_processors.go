@@ -0,0 +1,100 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modelmesh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+// A PayloadProcessorDestination receives the inference request and response payloads of the
+// Predictors which select it. The destinations are defined per namespace.
+type PayloadProcessorDestination struct {
+	// URL of the destination, http:// for a remote processor or logger:// to log the payloads
+	URL string `json:"url"`
+	// Method optionally restricts the payloads to those of one inference method
+	Method string `json:"method,omitempty"`
+}
+
+func (d PayloadProcessorDestination) validate() error {
+	if strings.ContainsAny(d.URL+d.Method, " #?") {
+		return errors.New("url and method must not contain spaces, queries or fragments")
+	}
+	u, err := url.Parse(d.URL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http":
+		if u.Host == "" {
+			return errors.New("url must have a host")
+		}
+	case "logger":
+	default:
+		return fmt.Errorf("unsupported url scheme '%s', must be http or logger", u.Scheme)
+	}
+	return nil
+}
+
+// Processor returns the model-mesh payload processor definition of the destination which
+// only receives the payloads of the given model
+func (d PayloadProcessorDestination) Processor(modelId string) string {
+	processor := d.URL + "?" + modelId
+	if d.Method != "" {
+		processor += "#" + d.Method
+	}
+	return processor
+}
+
+// ParsePayloadProcessorDestinations parses and validates the payload processor destinations
+// defined by a namespace's annotations. Invalid destinations are left out and reported by the error.
+func ParsePayloadProcessorDestinations(annotations map[string]string) (map[string]PayloadProcessorDestination, error) {
+	value := annotations[constants.PayloadProcessorDestinationsAnnotationKey]
+	if value == "" {
+		return nil, nil
+	}
+	var destinations map[string]PayloadProcessorDestination
+	if err := json.Unmarshal([]byte(value), &destinations); err != nil {
+		return nil, fmt.Errorf("could not parse annotation %s: %w", constants.PayloadProcessorDestinationsAnnotationKey, err)
+	}
+	var errs []error
+	for _, name := range sets.List(sets.KeySet(destinations)) {
+		if err := destinations[name].validate(); err != nil {
+			delete(destinations, name)
+			errs = append(errs, fmt.Errorf("invalid payload processor destination '%s' in annotation %s: %w",
+				name, constants.PayloadProcessorDestinationsAnnotationKey, err))
+		}
+	}
+	return destinations, errors.Join(errs...)
+}
+
+// SelectedPayloadProcessorDestinations returns the names of the destinations selected by
+// a Predictor's annotations
+func SelectedPayloadProcessorDestinations(annotations map[string]string) []string {
+	var names []string
+	for _, name := range strings.Split(annotations[constants.PayloadProcessorsAnnotationKey], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
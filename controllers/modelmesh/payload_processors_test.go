@@ -0,0 +1,66 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modelmesh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+func TestParsePayloadProcessorDestinations(t *testing.T) {
+	destinations, err := ParsePayloadProcessorDestinations(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, destinations)
+
+	destinations, err = ParsePayloadProcessorDestinations(map[string]string{
+		constants.PayloadProcessorDestinationsAnnotationKey: `{
+			"audit": {"url": "http://audit.compliance:8080/consumer/kserve/v2", "method": "inference.GRPCInferenceService/ModelInfer"},
+			"log": {"url": "logger://"}
+		}`,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://audit.compliance:8080/consumer/kserve/v2?fraud-detector#inference.GRPCInferenceService/ModelInfer",
+		destinations["audit"].Processor("fraud-detector"))
+	assert.Equal(t, "logger://?fraud-detector", destinations["log"].Processor("fraud-detector"))
+
+	for annotation, expectedErr := range map[string]string{
+		`["http://audit:8080"]`:                       "could not parse annotation",
+		`{"audit": {"url": "https://audit:8080"}}`:    "unsupported url scheme 'https'",
+		`{"audit": {"url": "http:///consumer"}}`:      "url must have a host",
+		`{"audit": {"url": "http://audit:8080?m=1"}}`: "must not contain spaces, queries or fragments",
+	} {
+		_, err = ParsePayloadProcessorDestinations(map[string]string{
+			constants.PayloadProcessorDestinationsAnnotationKey: annotation,
+		})
+		assert.ErrorContains(t, err, expectedErr, annotation)
+	}
+
+	// the valid destinations are kept
+	destinations, err = ParsePayloadProcessorDestinations(map[string]string{
+		constants.PayloadProcessorDestinationsAnnotationKey: `{"audit": {"url": "ftp://audit"}, "log": {"url": "logger://"}}`,
+	})
+	assert.ErrorContains(t, err, "invalid payload processor destination 'audit'")
+	assert.Equal(t, map[string]PayloadProcessorDestination{"log": {URL: "logger://"}}, destinations)
+}
+
+func TestSelectedPayloadProcessorDestinations(t *testing.T) {
+	assert.Empty(t, SelectedPayloadProcessorDestinations(nil))
+	assert.Equal(t, []string{"audit", "log"}, SelectedPayloadProcessorDestinations(map[string]string{
+		constants.PayloadProcessorsAnnotationKey: "audit, log,",
+	}))
+}
@@ -16,9 +16,14 @@ package modelmesh
 
 import (
 	"strconv"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+	"github.com/kserve/modelmesh-serving/pkg/openai"
 )
 
 const (
@@ -27,8 +32,17 @@ const (
 	restProxyGrpcPortEnvVar       = "REST_PROXY_GRPC_PORT"
 	restProxyTlsEnvVar            = "REST_PROXY_USE_TLS"
 	restProxySkipVerifyEnvVar     = "REST_PROXY_SKIP_VERIFY"
+
+	httpListenerTokensKey = "tokens"
 )
 
+// RuntimeRESTProxyEnabled applies the runtime's annotation overriding whether the REST proxy
+// is injected into its pods to the configured default. The default is returned along with
+// the error if the annotation can't be parsed.
+func RuntimeRESTProxyEnabled(annotations map[string]string, enabled bool) (bool, error) {
+	return runtimeBoolAnnotation(annotations, constants.RESTProxyAnnotationKey, enabled)
+}
+
 func (m *Deployment) addRESTProxyToDeployment(deployment *appsv1.Deployment) error {
 
 	if m.RESTProxyEnabled {
@@ -61,8 +75,152 @@ func (m *Deployment) addRESTProxyToDeployment(deployment *appsv1.Deployment) err
 			},
 			Resources: *m.RESTProxyResources,
 		}
-
-		deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, cspec)
+		podSpec := &deployment.Spec.Template.Spec
+		var sidecars []corev1.Container
+		if m.OpenAIProxyEnabled {
+			sidecars = append(sidecars, m.openAIProxyContainer(&cspec, podSpec))
+		}
+		if m.RESTProxyListener.Configured() {
+			sidecars = append(sidecars, m.restProxyListenerContainer(&cspec, podSpec))
+			// the REST proxy serves the listener on the backend port, which the Service doesn't expose
+			cspec.Env[0].Value = strconv.Itoa(int(m.RESTProxyBackendPort))
+			cspec.Ports = []corev1.ContainerPort{{Name: "rest-proxy", ContainerPort: int32(m.RESTProxyBackendPort)}}
+		}
+		podSpec.Containers = append(append(podSpec.Containers, cspec), sidecars...)
 	}
 	return nil
 }
+
+// openAIProxyContainer returns the OpenAI API sidecar, which is configured with the
+// environment variables of the REST proxy besides its listen port, and its own listener options
+func (m *Deployment) openAIProxyContainer(restProxy *corev1.Container, podSpec *corev1.PodSpec) corev1.Container {
+	cspec := corev1.Container{
+		Image:   m.OpenAIProxyImage,
+		Name:    OpenAIProxyContainerName,
+		Command: m.OpenAIProxyCommand,
+		Env:     sidecarEnv(restProxy, openai.OpenAIProxyEnvPrefix, m.OpenAIProxyPort),
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "openai",
+				ContainerPort: int32(m.OpenAIProxyPort),
+			},
+		},
+		Resources: *m.OpenAIProxyResources,
+	}
+	m.configureHTTPListener(&cspec, podSpec, openai.OpenAIProxyEnvPrefix, m.OpenAIProxyListener)
+	m.configureOpenAIProxyAccessLog(&cspec)
+	return cspec
+}
+
+// restProxyListenerContainer returns the sidecar which serves the REST port in front of the
+// REST proxy, enforcing the listener options which the REST proxy image doesn't support
+func (m *Deployment) restProxyListenerContainer(restProxy *corev1.Container, podSpec *corev1.PodSpec) corev1.Container {
+	cspec := corev1.Container{
+		Image:   m.RESTProxyListenerImage,
+		Name:    RESTProxyListenerContainerName,
+		Command: m.RESTProxyListenerCommand,
+		Env: append(sidecarEnv(restProxy, openai.RESTListenerEnvPrefix, m.RESTProxyPort), corev1.EnvVar{
+			Name: openai.RESTListenerEnvPrefix + openai.BackendPortEnvVar, Value: strconv.Itoa(int(m.RESTProxyBackendPort)),
+		}),
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "http",
+				ContainerPort: int32(m.RESTProxyPort),
+			},
+		},
+		Resources: *m.RESTProxyListenerResources,
+	}
+	m.configureHTTPListener(&cspec, podSpec, openai.RESTListenerEnvPrefix, m.RESTProxyListener)
+	return cspec
+}
+
+// sidecarEnv returns the environment variables of the REST proxy with the sidecar's own listen port
+func sidecarEnv(restProxy *corev1.Container, envPrefix string, listenPort uint16) []corev1.EnvVar {
+	env := []corev1.EnvVar{{Name: envPrefix + openai.ListenPortEnvVar, Value: strconv.Itoa(int(listenPort))}}
+	for _, e := range restProxy.Env {
+		if e.Name != restProxyPortEnvVar {
+			env = append(env, e)
+		}
+	}
+	return env
+}
+
+// configureOpenAIProxyAccessLog enables the access log of the OpenAI API sidecar along with
+// model-mesh's, with the format of model-mesh's logs, the same request headers and the
+// runtime's sample ratio
+func (m *Deployment) configureOpenAIProxyAccessLog(cspec *corev1.Container) {
+	if !m.EnableAccessLogging {
+		return
+	}
+	format := m.ModelMeshLogFormat
+	if format == "" {
+		format = config.LogFormatText
+	}
+	prefix := openai.OpenAIProxyEnvPrefix
+	cspec.Env = append(cspec.Env,
+		corev1.EnvVar{Name: prefix + openai.AccessLogEnvVar, Value: format},
+		corev1.EnvVar{
+			Name: prefix + openai.AccessLogRatioEnvVar, Value: strconv.FormatFloat(m.OpenAIAccessLogSampleRatio, 'g', -1, 64),
+		},
+	)
+	if len(m.AccessLogRequestHeaders) > 0 {
+		cspec.Env = append(cspec.Env, corev1.EnvVar{
+			Name: prefix + openai.AccessLogHdrsEnvVar, Value: strings.Join(m.AccessLogRequestHeaders, ","),
+		})
+	}
+}
+
+// configureHTTPListener sets the CORS, request limit, authentication and TLS options of a
+// sidecar's HTTP listener, mounting the Secrets which they refer to in /opt/kserve/<sidecar>
+func (m *Deployment) configureHTTPListener(cspec *corev1.Container, podSpec *corev1.PodSpec, envPrefix string,
+	listener config.HTTPListenerConfig) {
+	tlsVolume, tlsMountPath := cspec.Name+"-tls", "/opt/kserve/"+cspec.Name+"/tls"
+	authVolume, authMountPath := cspec.Name+"-auth", "/opt/kserve/"+cspec.Name+"/auth"
+	if len(listener.CORSAllowedOrigins) > 0 {
+		cspec.Env = append(cspec.Env, corev1.EnvVar{
+			Name: envPrefix + openai.CORSOriginsEnvVar, Value: strings.Join(listener.CORSAllowedOrigins, ","),
+		})
+	}
+	if listener.MaxRequestBodyBytes > 0 {
+		cspec.Env = append(cspec.Env, corev1.EnvVar{
+			Name: envPrefix + openai.MaxBodySizeEnvVar, Value: strconv.Itoa(listener.MaxRequestBodyBytes),
+		})
+	}
+	secretName := listener.TLS.SecretName
+	if secretName == "" {
+		secretName = m.TLSSecretName
+	}
+	if secretName != "" {
+		cspec.Env = append(cspec.Env,
+			corev1.EnvVar{Name: envPrefix + openai.ListenCertEnvVar, Value: tlsMountPath + "/" + TLSSecretCertKey},
+			corev1.EnvVar{Name: envPrefix + openai.ListenKeyEnvVar, Value: tlsMountPath + "/" + TLSSecretKeyKey},
+		)
+		addSecretVolume(cspec, podSpec, tlsVolume, secretName, tlsMountPath)
+	}
+	switch listener.Auth.Type {
+	case config.HTTPListenerAuthBearer:
+		cspec.Env = append(cspec.Env,
+			corev1.EnvVar{Name: envPrefix + openai.AuthTypeEnvVar, Value: config.HTTPListenerAuthBearer},
+			corev1.EnvVar{Name: envPrefix + openai.AuthTokensEnvVar, Value: authMountPath + "/" + httpListenerTokensKey},
+		)
+		addSecretVolume(cspec, podSpec, authVolume, listener.Auth.TokenSecretName, authMountPath)
+	case config.HTTPListenerAuthMTLS:
+		// the client CAs are in the listener's TLS secret
+		cspec.Env = append(cspec.Env,
+			corev1.EnvVar{Name: envPrefix + openai.AuthTypeEnvVar, Value: config.HTTPListenerAuthMTLS},
+			corev1.EnvVar{Name: envPrefix + openai.ListenClientCAEnvVar, Value: tlsMountPath + "/" + TLSClientCertKey},
+		)
+	}
+}
+
+func addSecretVolume(cspec *corev1.Container, podSpec *corev1.PodSpec, volumeName, secretName, mountPath string) {
+	cspec.VolumeMounts = append(cspec.VolumeMounts, corev1.VolumeMount{
+		Name: volumeName, MountPath: mountPath, ReadOnly: true,
+	})
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secretName},
+		},
+	})
+}
@@ -15,22 +15,27 @@
 package modelmesh
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
 
 	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	kserveConstants "github.com/kserve/kserve/pkg/constants"
+	"github.com/kserve/modelmesh-serving/controllers/autoscaler"
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	ModelsDir     string  = "/models"
-	PVCRootDir    string  = "/pvc_mounts"
-	ModelDirScale float64 = 1.5
+	ModelsDir  string = "/models"
+	PVCRootDir string = "/pvc_mounts"
 )
 
 // Sets the model mesh grace period to match the deployment grace period
@@ -46,19 +51,21 @@ func (m *Deployment) syncGracePeriod(deployment *appsv1.Deployment) error {
 	return nil
 }
 
-func (m *Deployment) addVolumesToDeployment(deployment *appsv1.Deployment) error {
+func (m *Deployment) addVolumesToDeployment(ctx context.Context, deployment *appsv1.Deployment) error {
 	rts := m.SRSpec
-	modelsDirSize := calculateModelDirSize(rts)
+	modelsDirVolume, err := m.modelCacheVolume(ctx)
+	if err != nil {
+		return err
+	}
+	if modelsDirVolume.PersistentVolumeClaim != nil {
+		// the Pod being replaced must not delete models from the claim while its replacement loads them
+		deployment.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
 
 	// start from the volumes specified in the runtime spec
 	volumes := rts.Volumes
 
-	volumes = append(volumes, corev1.Volume{
-		Name: ModelsDirVolume,
-		VolumeSource: corev1.VolumeSource{
-			EmptyDir: &corev1.EmptyDirVolumeSource{Medium: "", SizeLimit: modelsDirSize},
-		},
-	})
+	volumes = append(volumes, *modelsDirVolume)
 
 	if hasUnixSockets, _, _ := unixDomainSockets(rts); hasUnixSockets {
 		volumes = append(volumes, corev1.Volume{
@@ -104,8 +111,106 @@ func (m *Deployment) addVolumesToDeployment(deployment *appsv1.Deployment) error
 	return nil
 }
 
-// calculate emptyDir Size
-func calculateModelDirSize(rts *kserveapi.ServingRuntimeSpec) *resource.Quantity {
+// Builds the volume for the models directory from the ModelCache config and the runtime's overrides
+func (m *Deployment) modelCacheVolume(ctx context.Context) (*corev1.Volume, error) {
+	mc, err := runtimeModelCacheConfig(m.ModelCache, m.Owner.GetAnnotations())
+	if err != nil {
+		return nil, fmt.Errorf("invalid model cache configuration for runtime %s: %w", m.Name, err)
+	}
+	size := calculateModelCacheSize(m.SRSpec, mc.SizeMultiplier)
+
+	volume := &corev1.Volume{Name: ModelsDirVolume}
+	switch mc.Type {
+	case config.ModelCacheMemory:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory, SizeLimit: size}
+	case config.ModelCacheEphemeral:
+		if size.IsZero() {
+			return nil, fmt.Errorf("an %s model cache requires memory limits on the runtime containers to size the volume", mc.Type)
+		}
+		claimSpec := corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: *size},
+			},
+		}
+		if mc.StorageClassName != "" {
+			claimSpec.StorageClassName = &mc.StorageClassName
+		}
+		volume.Ephemeral = &corev1.EphemeralVolumeSource{
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{Spec: claimSpec},
+		}
+	case config.ModelCachePVC:
+		if err = m.validateModelCacheClaim(ctx, mc.ClaimName); err != nil {
+			return nil, err
+		}
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: mc.ClaimName}
+	default:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumDefault, SizeLimit: size}
+	}
+	return volume, nil
+}
+
+// The PVC of a pvc model cache can only be used by a single Pod, since model-mesh deletes the files
+// of the models it unloads from the /models directory, including those another Pod is serving
+func (m *Deployment) validateModelCacheClaim(ctx context.Context, claimName string) error {
+	if m.Replicas > 1 {
+		return fmt.Errorf("the model cache PVC %s of runtime %s can only be used by a single Pod, got %d replicas",
+			claimName, m.Name, m.Replicas)
+	}
+	if class, ok := m.Owner.GetAnnotations()[kserveConstants.AutoscalerClass]; ok && class != autoscaler.AutoscalerClassNone {
+		return fmt.Errorf("the model cache PVC %s of runtime %s can only be used by a single Pod, so the runtime can't be autoscaled",
+			claimName, m.Name)
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := m.Client.Get(ctx, client.ObjectKey{Name: claimName, Namespace: m.Namespace}, pvc); err != nil {
+		return fmt.Errorf("could not get the model cache PVC %s of runtime %s: %w", claimName, m.Name, err)
+	}
+	return nil
+}
+
+// Applies the model cache overrides from the runtime's annotations to the configured defaults
+func runtimeModelCacheConfig(mc config.ModelCacheConfig, annotations map[string]string) (config.ModelCacheConfig, error) {
+	if mc.Type == "" {
+		mc.Type = config.ModelCacheEmptyDir
+	}
+	if mc.SizeMultiplier == 0 {
+		mc.SizeMultiplier = config.DefaultModelCacheSizeMultiplier
+	}
+	if value, ok := annotations[constants.ModelCacheTypeAnnotationKey]; ok {
+		mc.Type = value
+	}
+	if value, ok := annotations[constants.ModelCacheSizeMultiplierAnnotationKey]; ok {
+		multiplier, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return mc, fmt.Errorf("could not parse annotation %s: %w", constants.ModelCacheSizeMultiplierAnnotationKey, err)
+		}
+		mc.SizeMultiplier = multiplier
+	}
+	if value, ok := annotations[constants.ModelCacheStorageClassNameAnnotationKey]; ok {
+		mc.StorageClassName = value
+	}
+	if value, ok := annotations[constants.ModelCacheClaimNameAnnotationKey]; ok {
+		mc.ClaimName = value
+	}
+	return mc, mc.Validate()
+}
+
+// Applies the runtime's boolean annotation with the given key to the configured default,
+// which is returned along with the error if the annotation can't be parsed
+func runtimeBoolAnnotation(annotations map[string]string, key string, value bool) (bool, error) {
+	annotation, ok := annotations[key]
+	if !ok {
+		return value, nil
+	}
+	override, err := strconv.ParseBool(annotation)
+	if err != nil {
+		return value, fmt.Errorf("could not parse annotation %s: %w", key, err)
+	}
+	return override, nil
+}
+
+// calculate the model cache size from the memory limits of the runtime containers
+func calculateModelCacheSize(rts *kserveapi.ServingRuntimeSpec, multiplier float64) *resource.Quantity {
 
 	memorySize := resource.MustParse("0")
 
@@ -113,7 +218,7 @@ func calculateModelDirSize(rts *kserveapi.ServingRuntimeSpec) *resource.Quantity
 		memorySize.Add(cspec.Resources.Limits[corev1.ResourceMemory])
 	}
 
-	return resource.NewQuantity(int64(float64(memorySize.Value())*ModelDirScale), resource.BinarySI)
+	return resource.NewQuantity(int64(float64(memorySize.Value())*multiplier), resource.BinarySI)
 }
 
 // Adds the provided runtime to the deployment
@@ -15,6 +15,7 @@
 package modelmesh
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -27,9 +28,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 
 	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	kserveConstants "github.com/kserve/kserve/pkg/constants"
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 )
 
 func newMockModelMeshDeployment(t *testing.T, rt *kserveapi.ServingRuntime) *Deployment {
@@ -231,7 +238,7 @@ func TestAddVolumesToDeployment(t *testing.T) {
 			rt := tt.servingRuntime
 
 			m := Deployment{Owner: rt, SRSpec: &rt.Spec}
-			if err := m.addVolumesToDeployment(deployment); err != nil {
+			if err := m.addVolumesToDeployment(context.Background(), deployment); err != nil {
 				t.Errorf("Call to add volumes failed: %v", err)
 			}
 
@@ -269,6 +276,172 @@ func TestAddVolumesToDeployment(t *testing.T) {
 	}
 }
 
+func TestModelCacheVolume(t *testing.T) {
+	memoryLimited := func(annotations map[string]string) *kserveapi.ServingRuntime {
+		rt := &kserveapi.ServingRuntime{
+			ObjectMeta: metav1.ObjectMeta{Name: "rt", Annotations: annotations},
+			Spec: kserveapi.ServingRuntimeSpec{
+				ServingRuntimePodSpec: kserveapi.ServingRuntimePodSpec{
+					Containers: []v1.Container{{
+						Name: "server",
+						Resources: v1.ResourceRequirements{
+							Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+						},
+					}},
+				},
+			},
+		}
+		return rt
+	}
+	storageClass := "local-ssd"
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-cache", Namespace: "user-ns"},
+	}).Build()
+
+	for _, tt := range []struct {
+		name           string
+		modelCache     config.ModelCacheConfig
+		servingRuntime *kserveapi.ServingRuntime
+		replicas       uint16
+		expectedVolume v1.VolumeSource
+		expectError    bool
+	}{
+		{
+			name:           "default-empty-dir",
+			servingRuntime: memoryLimited(nil),
+			expectedVolume: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: resource.NewQuantity(1536*1024*1024, resource.BinarySI)},
+			},
+		},
+		{
+			name:           "configured-multiplier",
+			modelCache:     config.ModelCacheConfig{Type: config.ModelCacheEmptyDir, SizeMultiplier: 2},
+			servingRuntime: memoryLimited(nil),
+			expectedVolume: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{SizeLimit: resource.NewQuantity(2*1024*1024*1024, resource.BinarySI)},
+			},
+		},
+		{
+			name: "memory-annotation",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey:           config.ModelCacheMemory,
+				constants.ModelCacheSizeMultiplierAnnotationKey: "0.5",
+			}),
+			expectedVolume: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{
+					Medium:    v1.StorageMediumMemory,
+					SizeLimit: resource.NewQuantity(512*1024*1024, resource.BinarySI),
+				},
+			},
+		},
+		{
+			name:           "ephemeral",
+			modelCache:     config.ModelCacheConfig{Type: config.ModelCacheEphemeral, SizeMultiplier: 1, StorageClassName: storageClass},
+			servingRuntime: memoryLimited(nil),
+			expectedVolume: v1.VolumeSource{
+				Ephemeral: &v1.EphemeralVolumeSource{
+					VolumeClaimTemplate: &v1.PersistentVolumeClaimTemplate{
+						Spec: v1.PersistentVolumeClaimSpec{
+							AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+							StorageClassName: &storageClass,
+							Resources: v1.ResourceRequirements{
+								Requests: v1.ResourceList{v1.ResourceStorage: *resource.NewQuantity(1024*1024*1024, resource.BinarySI)},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:           "ephemeral-without-memory-limits",
+			modelCache:     config.ModelCacheConfig{Type: config.ModelCacheEphemeral, SizeMultiplier: 1},
+			servingRuntime: &kserveapi.ServingRuntime{},
+			expectError:    true,
+		},
+		{
+			name: "pvc-annotation",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey:      config.ModelCachePVC,
+				constants.ModelCacheClaimNameAnnotationKey: "model-cache",
+			}),
+			expectedVolume: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: "model-cache"},
+			},
+		},
+		{
+			name: "pvc-multiple-replicas",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey:      config.ModelCachePVC,
+				constants.ModelCacheClaimNameAnnotationKey: "model-cache",
+			}),
+			replicas:    2,
+			expectError: true,
+		},
+		{
+			name: "pvc-autoscaled",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey:      config.ModelCachePVC,
+				constants.ModelCacheClaimNameAnnotationKey: "model-cache",
+				kserveConstants.AutoscalerClass:            string(kserveConstants.AutoscalerClassHPA),
+			}),
+			expectError: true,
+		},
+		{
+			name: "pvc-not-found",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey:      config.ModelCachePVC,
+				constants.ModelCacheClaimNameAnnotationKey: "missing",
+			}),
+			expectError: true,
+		},
+		{
+			name: "pvc-without-claim-name",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheTypeAnnotationKey: config.ModelCachePVC,
+			}),
+			expectError: true,
+		},
+		{
+			name: "invalid-multiplier-annotation",
+			servingRuntime: memoryLimited(map[string]string{
+				constants.ModelCacheSizeMultiplierAnnotationKey: "big",
+			}),
+			expectError: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := tt.servingRuntime
+			m := Deployment{Owner: rt, SRSpec: &rt.Spec, ModelCache: tt.modelCache, Namespace: "user-ns", Client: cl,
+				Replicas: tt.replicas}
+
+			deployment := &appsv1.Deployment{}
+			err := m.addVolumesToDeployment(context.Background(), deployment)
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var volume *v1.Volume
+			for i := range deployment.Spec.Template.Spec.Volumes {
+				if deployment.Spec.Template.Spec.Volumes[i].Name == ModelsDirVolume {
+					volume = &deployment.Spec.Template.Spec.Volumes[i]
+				}
+			}
+			if assert.NotNil(t, volume) {
+				if diff := cmp.Diff(tt.expectedVolume, volume.VolumeSource); diff != "" {
+					t.Errorf("Unexpected model cache volume (-want +got):\n%s", diff)
+				}
+			}
+			// a Pod using the claim is only replaced once it's gone
+			if tt.expectedVolume.PersistentVolumeClaim != nil {
+				assert.Equal(t, appsv1.RecreateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+			} else {
+				assert.Empty(t, deployment.Spec.Strategy.Type)
+			}
+		})
+	}
+}
+
 func TestAddPassThroughPodFieldsToDeployment(t *testing.T) {
 	t.Run("defaults-to-no-changes", func(t *testing.T) {
 		d := &appsv1.Deployment{}
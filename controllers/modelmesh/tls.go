@@ -16,12 +16,16 @@ package modelmesh
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 )
 
 const (
@@ -37,7 +41,8 @@ const (
 )
 
 // NOTE: the inspected contents of the Secret might not match what gets mounted
-// if the Secret is updated. Currently not triggering a reconcile when Secret changes.
+// if the Secret is updated. Only Secrets issued by cert-manager trigger a reconcile
+// when they change, which rolls the pods via the TLS secret hash annotation.
 // TODO: react to tls secret content changes - (#611)
 func (m *Deployment) configureMMDeploymentForTLSSecret(deployment *appsv1.Deployment) error {
 	clientParam := m.Client
@@ -64,7 +69,9 @@ func (m *Deployment) configureMMDeploymentForTLSSecret(deployment *appsv1.Deploy
 	podSpec := &deployment.Spec.Template.Spec
 	for ci := range podSpec.Containers {
 		container := &podSpec.Containers[ci]
-		if container.Name == ModelMeshContainerName || (m.RESTProxyEnabled && container.Name == RESTProxyContainerName) {
+		if container.Name == ModelMeshContainerName || (m.RESTProxyEnabled &&
+			(container.Name == RESTProxyContainerName || container.Name == OpenAIProxyContainerName ||
+				container.Name == RESTProxyListenerContainerName)) {
 			container.Env = append(container.Env, corev1.EnvVar{
 				Name: tlsCertEnvVar, Value: tlsSecretMountPath + "/" + TLSSecretCertKey,
 			})
@@ -119,6 +126,23 @@ func (m *Deployment) configureMMDeploymentForTLSSecret(deployment *appsv1.Deploy
 		},
 	}
 
+	if m.TLSCertManaged {
+		if deployment.Spec.Template.Annotations == nil {
+			deployment.Spec.Template.Annotations = map[string]string{}
+		}
+		deployment.Spec.Template.Annotations[constants.TLSSecretHashAnnotationKey] = tlsSecretHash(tlsSecret)
+	}
+
 	return nil
 
 }
+
+// tlsSecretHash returns a hash of the certificates and key of the TLS secret
+func tlsSecretHash(tlsSecret *corev1.Secret) string {
+	h := sha256.New()
+	for _, key := range []string{TLSSecretCertKey, TLSSecretKeyKey, TLSClientCertKey} {
+		h.Write([]byte(key))
+		h.Write(tlsSecret.Data[key])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
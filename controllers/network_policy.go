@@ -0,0 +1,156 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+const (
+	networkPolicySuffix = "-runtimes"
+	// the NetworkPolicy of the runtimes installed in the controller namespace, which allows
+	// inference and metrics traffic from anywhere
+	installedNetworkPolicyName = "modelmesh-runtimes"
+	// label set on namespaces by Kubernetes since 1.21
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// port ranges reserved for internal model-mesh communication between runtime pods,
+// see validateContainer
+var meshPortRanges = [][2]int32{{8080, 8090}, {11881, 11899}}
+
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+
+// reconcileNetworkPolicy creates, updates or deletes the NetworkPolicy restricting ingress
+// to the runtime Pods behind the given inference Service
+func (r *ServiceReconciler) reconcileNetworkPolicy(ctx context.Context, cfg *config.Config, s *corev1.Service) error {
+	np := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+		Name:      s.Name + networkPolicySuffix,
+		Namespace: s.Namespace,
+	}}
+
+	if !cfg.NetworkPolicy.Enabled {
+		if err := r.Client.Delete(ctx, np); err != nil && !k8serr.IsNotFound(err) {
+			return fmt.Errorf("could not delete NetworkPolicy %s: %w", np.Name, err)
+		}
+		return nil
+	}
+
+	// NetworkPolicies are additive, the installed one would allow all the traffic which the generated one restricts
+	if s.Namespace == r.ControllerDeployment.Namespace {
+		installed := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{
+			Name:      installedNetworkPolicyName,
+			Namespace: s.Namespace,
+		}}
+		if err := r.Client.Delete(ctx, installed); err == nil {
+			r.Log.Info("Deleted the installed NetworkPolicy, superseded by the generated one",
+				"name", installed.Name, "namespace", installed.Namespace)
+		} else if !k8serr.IsNotFound(err) {
+			return fmt.Errorf("could not delete NetworkPolicy %s: %w", installed.Name, err)
+		}
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, np, func() error {
+		np.Labels = s.Labels
+		np.Spec = runtimeNetworkPolicySpec(cfg, s, r.ControllerDeployment.Namespace)
+		return controllerutil.SetControllerReference(s, np, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("could not create or update NetworkPolicy %s: %w", np.Name, err)
+	}
+	if result != controllerutil.OperationResultNone {
+		r.Log.Info("Reconciled NetworkPolicy", "name", np.Name, "namespace", np.Namespace, "result", result)
+	}
+	return nil
+}
+
+func runtimeNetworkPolicySpec(cfg *config.Config, s *corev1.Service, controllerNamespace string) networkingv1.NetworkPolicySpec {
+	tcp := corev1.ProtocolTCP
+	namedPort := func(name string) networkingv1.NetworkPolicyPort {
+		port := intstr.FromString(name)
+		return networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &port}
+	}
+	runtimePods := metav1.LabelSelector{MatchLabels: map[string]string{"modelmesh-service": s.Name}}
+
+	inferencePorts := []networkingv1.NetworkPolicyPort{namedPort("grpc")}
+	if servicePort(s, "http") != 0 {
+		inferencePorts = append(inferencePorts, namedPort("http"))
+	}
+	if servicePort(s, "openai") != 0 {
+		inferencePorts = append(inferencePorts, namedPort("openai"))
+	}
+
+	// mesh traffic between the runtime pods
+	meshPorts := []networkingv1.NetworkPolicyPort{namedPort("grpc")}
+	for _, pr := range meshPortRanges {
+		port, endPort := intstr.FromInt(int(pr[0])), pr[1]
+		meshPorts = append(meshPorts, networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &port, EndPort: &endPort})
+	}
+	rules := []networkingv1.NetworkPolicyIngressRule{{
+		From:  []networkingv1.NetworkPolicyPeer{{PodSelector: &runtimePods}},
+		Ports: meshPorts,
+	}}
+
+	// the controller calls the model-mesh management API via the gRPC port
+	rules = append(rules, networkingv1.NetworkPolicyIngressRule{
+		From: []networkingv1.NetworkPolicyPeer{{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"control-plane": "modelmesh-controller"}},
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: controllerNamespace},
+			},
+		}},
+		Ports: []networkingv1.NetworkPolicyPort{namedPort("grpc")},
+	})
+
+	// inference requests, from any pod unless clients are configured
+	var clients []networkingv1.NetworkPolicyPeer
+	for _, c := range cfg.NetworkPolicy.InferenceClients {
+		peer := networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: c.PodLabels}}
+		if c.NamespaceLabels != nil {
+			peer.NamespaceSelector = &metav1.LabelSelector{MatchLabels: c.NamespaceLabels}
+		}
+		clients = append(clients, peer)
+	}
+	rules = append(rules, networkingv1.NetworkPolicyIngressRule{From: clients, Ports: inferencePorts})
+
+	if servicePort(s, "prometheus") != 0 {
+		var scrapers []networkingv1.NetworkPolicyPeer
+		if ns := cfg.NetworkPolicy.MonitoringNamespace; ns != "" {
+			scrapers = []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{namespaceNameLabel: ns},
+			}}}
+		}
+		rules = append(rules, networkingv1.NetworkPolicyIngressRule{
+			From:  scrapers,
+			Ports: []networkingv1.NetworkPolicyPort{namedPort("prometheus")},
+		})
+	}
+
+	return networkingv1.NetworkPolicySpec{
+		PodSelector: runtimePods,
+		Ingress:     rules,
+		PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+func TestRuntimeNetworkPolicySpec(t *testing.T) {
+	s := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "modelmesh-serving", Namespace: "team-a"},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "grpc", Port: 8033},
+			{Name: "http", Port: 8008},
+			{Name: "openai", Port: 8009},
+			{Name: "prometheus", Port: 2112},
+		}},
+	}
+	cfg := &config.Config{NetworkPolicy: config.NetworkPolicyConfig{
+		Enabled: true,
+		InferenceClients: []config.NetworkPolicyPeer{
+			{PodLabels: map[string]string{"app": "client"}},
+			{NamespaceLabels: map[string]string{"team": "a"}},
+		},
+		MonitoringNamespace: "monitoring",
+	}}
+
+	spec := runtimeNetworkPolicySpec(cfg, s, "modelmesh-serving")
+
+	expectedSelector := metav1.LabelSelector{MatchLabels: map[string]string{"modelmesh-service": "modelmesh-serving"}}
+	if !reflect.DeepEqual(spec.PodSelector, expectedSelector) {
+		t.Errorf("Unexpected pod selector %v", spec.PodSelector)
+	}
+	if len(spec.Ingress) != 4 {
+		t.Fatalf("Expected 4 ingress rules (mesh, controller, inference, metrics) but got %d", len(spec.Ingress))
+	}
+
+	mesh := spec.Ingress[0]
+	if !reflect.DeepEqual(*mesh.From[0].PodSelector, expectedSelector) || len(mesh.Ports) != 3 {
+		t.Errorf("Unexpected mesh rule %v", mesh)
+	}
+	if *mesh.Ports[1].EndPort != 8090 || *mesh.Ports[2].EndPort != 11899 {
+		t.Errorf("Unexpected mesh port ranges %v", mesh.Ports)
+	}
+
+	controller := spec.Ingress[1].From[0]
+	if controller.NamespaceSelector.MatchLabels[namespaceNameLabel] != "modelmesh-serving" {
+		t.Errorf("Unexpected controller peer %v", controller)
+	}
+
+	inference := spec.Ingress[2]
+	if len(inference.From) != 2 || len(inference.Ports) != 3 {
+		t.Errorf("Unexpected inference rule %v", inference)
+	}
+	if inference.From[0].NamespaceSelector != nil {
+		t.Errorf("Expected client without namespace labels to be restricted to the runtime namespace")
+	}
+	if inference.From[1].NamespaceSelector.MatchLabels["team"] != "a" {
+		t.Errorf("Unexpected namespace selector %v", inference.From[1].NamespaceSelector)
+	}
+
+	metrics := spec.Ingress[3]
+	if metrics.From[0].NamespaceSelector.MatchLabels[namespaceNameLabel] != "monitoring" ||
+		metrics.Ports[0].Port.StrVal != "prometheus" {
+		t.Errorf("Unexpected metrics rule %v", metrics)
+	}
+
+	// without configured clients or metrics, inference is open and there is no metrics rule
+	cfg.NetworkPolicy.InferenceClients = nil
+	s.Spec.Ports = s.Spec.Ports[:1]
+	spec = runtimeNetworkPolicySpec(cfg, s, "modelmesh-serving")
+	if len(spec.Ingress) != 3 || spec.Ingress[2].From != nil || len(spec.Ingress[2].Ports) != 1 {
+		t.Errorf("Unexpected ingress rules %v", spec.Ingress)
+	}
+}
+
+func TestReconcileNetworkPolicyDeletesInstalledPolicy(t *testing.T) {
+	ctx := context.Background()
+	r, svc := newExternalRouteReconciler(t, false, false)
+	r.ControllerDeployment = types.NamespacedName{Name: "modelmesh-controller", Namespace: "team-a"}
+	installed := types.NamespacedName{Name: installedNetworkPolicyName, Namespace: "team-a"}
+	require.NoError(t, r.Create(ctx, &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: installed.Name, Namespace: installed.Namespace},
+	}))
+
+	// kept while the generated NetworkPolicy is disabled
+	require.NoError(t, r.reconcileNetworkPolicy(ctx, &config.Config{}, svc))
+	require.NoError(t, r.Get(ctx, installed, &networkingv1.NetworkPolicy{}))
+
+	cfg := &config.Config{NetworkPolicy: config.NetworkPolicyConfig{Enabled: true}}
+	require.NoError(t, r.reconcileNetworkPolicy(ctx, cfg, svc))
+	err := r.Get(ctx, installed, &networkingv1.NetworkPolicy{})
+	assert.True(t, k8serr.IsNotFound(err), "the installed NetworkPolicy should be deleted")
+	require.NoError(t, r.Get(ctx, types.NamespacedName{Name: "modelmesh-serving-runtimes", Namespace: "team-a"},
+		&networkingv1.NetworkPolicy{}))
+
+	// and isn't needed again
+	require.NoError(t, r.reconcileNetworkPolicy(ctx, cfg, svc))
+}
@@ -0,0 +1,156 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+)
+
+const testPayloadProcessorDestinations = `{
+	"audit": {"url": "http://audit.compliance:8080/consumer/kserve/v2", "method": "inference.GRPCInferenceService/ModelInfer"},
+	"log": {"url": "logger://"}
+}`
+
+func newPayloadProcessorsClient(t *testing.T, destinations string, objects ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	require.NoError(t, api.AddToScheme(s))
+	require.NoError(t, corev1.AddToScheme(s))
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "team-a",
+		Annotations: map[string]string{constants.PayloadProcessorDestinationsAnnotationKey: destinations},
+	}}
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(append(objects, namespace)...).Build()
+}
+
+func payloadProcessorsPredictor(name, runtime, processors string) *api.Predictor {
+	p := &api.Predictor{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+		Spec: api.PredictorSpec{
+			Model:   api.Model{Type: api.ModelType{Name: "sklearn"}, Path: name},
+			Runtime: &api.PredictorRuntime{RuntimeRef: &api.RuntimeRef{Name: runtime}},
+		},
+	}
+	if processors != "" {
+		p.Annotations = map[string]string{constants.PayloadProcessorsAnnotationKey: processors}
+	}
+	return p
+}
+
+func TestGetPayloadProcessors(t *testing.T) {
+	fraudDetector := payloadProcessorsPredictor("fraud-detector", "runtime-a", "audit, log")
+	cl := newPayloadProcessorsClient(t, testPayloadProcessorDestinations,
+		fraudDetector,
+		payloadProcessorsPredictor("not-selecting", "runtime-a", ""),
+		payloadProcessorsPredictor("other-runtime", "runtime-b", "audit"),
+		payloadProcessorsPredictor("undefined", "runtime-a", "missing"),
+	)
+	r := &ServingRuntimeReconciler{
+		Client:       cl,
+		ClusterScope: true,
+		RegistryMap: map[string]predictor_source.PredictorRegistry{
+			PredictorCRSourceId: predictor_source.PredictorCRRegistry{Client: cl},
+		},
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "runtime-a", Namespace: "team-a"}}
+	cfg := &config.Config{PayloadProcessors: []string{"logger://*"}}
+
+	// the payloads are filtered by the vmodel id of the Predictor, which doesn't change with its spec
+	processors, err := r.getPayloadProcessors(context.Background(), req, &kserveapi.ServingRuntimeSpec{}, true, cfg)
+	require.NoError(t, err)
+	expected := []string{
+		"logger://*",
+		"http://audit.compliance:8080/consumer/kserve/v2?fraud-detector#inference.GRPCInferenceService/ModelInfer",
+		"logger://?fraud-detector",
+	}
+	assert.Equal(t, expected, processors)
+	fraudDetector.Spec.Model.Path = "fraud-detector-v2"
+	require.NoError(t, cl.Update(context.Background(), fraudDetector))
+	processors, err = r.getPayloadProcessors(context.Background(), req, &kserveapi.ServingRuntimeSpec{}, true, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, expected, processors)
+
+	// namespace annotations aren't read without cluster scope
+	r.ClusterScope = false
+	processors, err = r.getPayloadProcessors(context.Background(), req, &kserveapi.ServingRuntimeSpec{}, true, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logger://*"}, processors)
+
+	// invalid destinations are skipped without failing the reconcile
+	cl = newPayloadProcessorsClient(t, `{"audit": {"url": "ftp://audit"}, "log": {"url": "logger://"}}`, fraudDetector)
+	r.Client = cl
+	r.RegistryMap[PredictorCRSourceId] = predictor_source.PredictorCRRegistry{Client: cl}
+	r.ClusterScope = true
+	processors, err = r.getPayloadProcessors(context.Background(), req, &kserveapi.ServingRuntimeSpec{}, true, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logger://*", "logger://?fraud-detector"}, processors)
+}
+
+func TestValidatePayloadProcessors(t *testing.T) {
+	pr := &PredictorReconciler{
+		Client:       newPayloadProcessorsClient(t, testPayloadProcessorDestinations),
+		ClusterScope: true,
+	}
+	for processors, expected := range map[string]string{
+		"":              "",
+		"audit, log":    "",
+		"audit,missing": "Payload processor destination 'missing' is not defined by the namespace",
+	} {
+		message, err := pr.validatePayloadProcessors(context.Background(),
+			payloadProcessorsPredictor("fraud-detector", "runtime-a", processors))
+		require.NoError(t, err)
+		assert.Equal(t, expected, message, processors)
+	}
+
+	pr.Client = newPayloadProcessorsClient(t, `["audit"]`)
+	message, err := pr.validatePayloadProcessors(context.Background(),
+		payloadProcessorsPredictor("fraud-detector", "runtime-a", "audit"))
+	require.NoError(t, err)
+	assert.Contains(t, message, "Payload processor destination 'audit' is not defined by the namespace or is invalid: "+
+		"could not parse annotation")
+
+	// only the Predictors selecting an invalid destination are invalid
+	pr.Client = newPayloadProcessorsClient(t, `{"audit": {"url": "ftp://audit"}, "log": {"url": "logger://"}}`)
+	message, err = pr.validatePayloadProcessors(context.Background(),
+		payloadProcessorsPredictor("fraud-detector", "runtime-a", "log"))
+	require.NoError(t, err)
+	assert.Empty(t, message)
+	message, err = pr.validatePayloadProcessors(context.Background(),
+		payloadProcessorsPredictor("fraud-detector", "runtime-a", "audit"))
+	require.NoError(t, err)
+	assert.Contains(t, message, "unsupported url scheme 'ftp'")
+
+	pr.ClusterScope = false
+	message, err = pr.validatePayloadProcessors(context.Background(),
+		payloadProcessorsPredictor("fraud-detector", "runtime-a", "audit"))
+	require.NoError(t, err)
+	assert.Equal(t, "Payload processor destinations can only be selected when the controller is cluster-scoped", message)
+}
@@ -26,6 +26,8 @@ import (
 
 	"github.com/kserve/kserve/pkg/apis/serving/v1beta1"
 	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
 
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
@@ -59,6 +61,8 @@ type PredictorReconciler struct {
 	client.Client
 	Log        logr.Logger
 	MMServices *MMServiceMap
+	// whether the controller has cluster scope permissions
+	ClusterScope bool
 
 	RegistryLookup map[string]predictor_source.PredictorRegistry
 }
@@ -70,9 +74,13 @@ type PredictorReconciler struct {
 // +kubebuilder:rbac:groups=serving.kserve.io,resources=inferenceservices/finalizers,verbs=get;update;patch
 // +kubebuilder:rbac:groups=serving.kserve.io,resources=inferenceservices/status,verbs=get;update;patch
 // This one is used by the kube-based grpc resolver but need to set it here so that kubebuilder picks it up
-// +kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 
 func (pr *PredictorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return tracing.Reconcile(ctx, "predictor", req, pr.reconcile)
+}
+
+func (pr *PredictorReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	// if no explict source prefix we default to "ksp" (for Predictor CR)
 	nname, source := predictor_source.ResolveSource(req.NamespacedName, PredictorCRSourceId)
 	registry, ok := pr.RegistryLookup[source]
@@ -92,12 +100,35 @@ func (pr *PredictorReconciler) getMMClient(namespace string) mmeshapi.ModelMeshC
 	return nil
 }
 
+// Returns the timeout of model-mesh management RPCs in a namespace
+func (pr *PredictorReconciler) mmRequestTimeout(namespace string) time.Duration {
+	if mms := pr.MMServices.Get(namespace); mms != nil {
+		return mms.RequestTimeout()
+	}
+	return DefaultGrpcRequestTimeout
+}
+
+// Returns how long to defer reconciles while the circuit breaker of the model-mesh
+// client of a namespace is open, 0 if it's closed
+func (pr *PredictorReconciler) mmCircuitRetryAfter(namespace string) time.Duration {
+	if mms := pr.MMServices.Get(namespace); mms != nil {
+		return mms.CircuitRetryAfter()
+	}
+	return 0
+}
+
 func (pr *PredictorReconciler) ReconcilePredictor(ctx context.Context, nname types.NamespacedName,
 	sourceId string, registry predictor_source.PredictorRegistry) (ctrl.Result, error) {
 	resourceType := registry.GetSourceName()
 	log := pr.Log.WithValues("namespacedName", nname, "source", resourceType)
 	log.V(1).Info("ReconcilePredictor called")
 
+	if retryAfter := pr.mmCircuitRetryAfter(nname.Namespace); retryAfter > 0 {
+		// model-mesh is unavailable, don't pile up more requests
+		log.V(1).Info("Deferring reconcile while model-mesh is unavailable", "retryAfter", retryAfter)
+		return ctrl.Result{RequeueAfter: retryAfter}, nil
+	}
+
 	predictor, err := registry.Get(ctx, nname)
 	if (predictor == nil && err == nil) || errors.IsNotFound(err) {
 		return pr.handlePredictorNotFound(ctx, nname, sourceId)
@@ -115,16 +146,24 @@ func (pr *PredictorReconciler) ReconcilePredictor(ctx context.Context, nname typ
 	var finalErr error
 
 	invalidPredictorMessage := validatePredictor(predictor)
+	if invalidPredictorMessage == "" {
+		if invalidPredictorMessage, err = pr.validatePayloadProcessors(ctx, predictor); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to validate the payload processors of predictor %s: %w",
+				nname.Name, err)
+		}
+	}
 
 	if invalidPredictorMessage != "" {
 		log.Info("Invalid Predictor specification", "Spec", predictor.Spec)
 		if mmc != nil {
 			// Don't update invalid spec but still check vmodel status to sync the existing model states
-			vModelState, err := mmc.GetVModelStatus(ctx, &mmeshapi.GetVModelStatusRequest{
+			statusCtx, cancel := context.WithTimeout(ctx, pr.mmRequestTimeout(nname.Namespace))
+			defer cancel()
+			vModelState, err := mmc.GetVModelStatus(statusCtx, &mmeshapi.GetVModelStatusRequest{
 				VModelId: predictor.Name, Owner: sourceId,
 			})
 			if err != nil {
-				if isNoAddresses(err) {
+				if isNoAddresses(err) || mmesh.IsCircuitOpen(err) {
 					mmc = nil // will mean we return retry result
 				} else {
 					// don't return yet because we may want to update status first
@@ -168,6 +207,8 @@ func (pr *PredictorReconciler) ReconcilePredictor(ctx context.Context, nname typ
 				Message: "Waiting for runtime Pod to become available",
 				ModelId: concreteModelName(predictor, sourceId),
 			})
+		} else if mmesh.IsCircuitOpen(err) {
+			mmc = nil // will mean we return retry result
 		} else if grpcstatus.Convert(err).Code() == codes.AlreadyExists {
 			//TODO here should also extract the conflicting owner string, and also trigger a reconcile with that
 			// other source id (in case it no longer exists)
@@ -186,7 +227,12 @@ func (pr *PredictorReconciler) ReconcilePredictor(ctx context.Context, nname typ
 	if updateStatus {
 		updateStatusCtx, cancel := context.WithTimeout(ctx, K8sStatusUpdateTimeout)
 		defer cancel()
-		if succ, err := registry.UpdateStatus(updateStatusCtx, predictor); err != nil {
+		var succ bool
+		err := tracing.Span(updateStatusCtx, "predictor status update", func(ctx context.Context) (err error) {
+			succ, err = registry.UpdateStatus(ctx, predictor)
+			return
+		}, attribute.String("namespace", predictor.GetNamespace()), attribute.String("name", predictor.GetName()))
+		if err != nil {
 			finalErr = fmt.Errorf("unable to update Status of %s %s: %w", resourceType, predictor.GetName(), err)
 		} else if !succ {
 			// this can occur during normal operations
@@ -253,6 +299,32 @@ func validatePredictor(predictor *api.Predictor) string {
 	return ""
 }
 
+// validatePayloadProcessors checks that the payload processor destinations selected by the Predictor
+// are defined by its namespace
+func (pr *PredictorReconciler) validatePayloadProcessors(ctx context.Context, predictor *api.Predictor) (string, error) {
+	names := modelmesh.SelectedPayloadProcessorDestinations(predictor.Annotations)
+	if len(names) == 0 {
+		return "", nil
+	}
+	if !pr.ClusterScope {
+		return "Payload processor destinations can only be selected when the controller is cluster-scoped", nil
+	}
+	destinations, invalidErr, err := namespacePayloadProcessorDestinations(ctx, pr.Client, predictor.Namespace, pr.ClusterScope)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		if _, ok := destinations[name]; !ok {
+			if invalidErr != nil {
+				return fmt.Sprintf("Payload processor destination '%s' is not defined by the namespace or is invalid: %s",
+					name, invalidErr), nil
+			}
+			return fmt.Sprintf("Payload processor destination '%s' is not defined by the namespace", name), nil
+		}
+	}
+	return "", nil
+}
+
 // passed in ModelInfo.Key field of registration requests
 type ModelKeyInfo struct {
 	StorageKey    *string           `json:"storage_key,omitempty"`
@@ -262,8 +334,9 @@ type ModelKeyInfo struct {
 }
 
 const (
-	GrpcRequestTimeout     = 10 * time.Second
-	K8sStatusUpdateTimeout = 10 * time.Second
+	// used until the model-mesh client of a namespace is configured
+	DefaultGrpcRequestTimeout = 10 * time.Second
+	K8sStatusUpdateTimeout    = 10 * time.Second
 )
 
 var modelStateMap = map[mmeshapi.ModelStatusInfo_ModelStatus]api.ModelState{
@@ -287,10 +360,13 @@ func (pr *PredictorReconciler) handlePredictorNotFound(ctx context.Context,
 	if mmc == nil {
 		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 	}
-	deleteCtx, cancel := context.WithTimeout(ctx, GrpcRequestTimeout)
+	deleteCtx, cancel := context.WithTimeout(ctx, pr.mmRequestTimeout(name.Namespace))
 	defer cancel()
 	_, err := mmc.DeleteVModel(deleteCtx, &mmeshapi.DeleteVModelRequest{VModelId: name.Name, Owner: sourceId})
 	if err != nil {
+		if mmesh.IsCircuitOpen(err) {
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
 		if isNoAddresses(err) {
 			// Work-around to prevent Non-MM InferenceService indefinite reconcile loop
 			// when there are no model-mesh pods running.
@@ -310,7 +386,7 @@ func (pr *PredictorReconciler) setVModel(ctx context.Context, mmc mmeshapi.Model
 	predictor *api.Predictor, loadNow bool, sourceId string) (*mmeshapi.VModelStatusInfo, error) {
 	spec := &predictor.Spec
 
-	setVmodelCtx, cancel := context.WithTimeout(ctx, GrpcRequestTimeout)
+	setVmodelCtx, cancel := context.WithTimeout(ctx, pr.mmRequestTimeout(predictor.Namespace))
 	defer cancel()
 
 	path, schemaPath, storageKey, storageParams := extractModelFields(predictor)
@@ -588,11 +664,11 @@ func Hash(predictorSpec *api.PredictorSpec) string {
 
 // ---------
 
-func (pr *PredictorReconciler) SetupWithManager(mgr ctrl.Manager, eventStream *mmesh.ModelMeshEventStream,
+func (pr *PredictorReconciler) SetupWithManager(mgr ctrl.Manager, eventStream mmesh.ModelEventSource,
 	watchInferenceServices bool, sourcePluginEvents <-chan event.GenericEvent) error {
 	builder := ctrl.NewControllerManagedBy(mgr).
 		For(&api.Predictor{}).
-		WatchesRawSource(&src.Channel{Source: eventStream.MMEvents}, &handler.EnqueueRequestForObject{})
+		WatchesRawSource(&src.Channel{Source: eventStream.Events()}, pr.modelEventHandler())
 
 	if sourcePluginEvents != nil {
 		builder.WatchesRawSource(&src.Channel{Source: sourcePluginEvents}, &handler.EnqueueRequestForObject{})
@@ -604,6 +680,31 @@ func (pr *PredictorReconciler) SetupWithManager(mgr ctrl.Manager, eventStream *m
 	return builder.Complete(pr)
 }
 
+// modelEventHandler enqueues the Predictor of each model-mesh event, or all of the
+// Predictors in the namespace for a resync event sent after events were dropped
+func (pr *PredictorReconciler) modelEventHandler() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+		namespace, resync := mmesh.IsResyncEvent(o)
+		if !resync {
+			return []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(o)}}
+		}
+		pr.Log.Info("Resyncing all Predictors after dropped model-mesh events", "namespace", namespace)
+		var requests []reconcile.Request
+		for sourceId, registry := range pr.RegistryLookup {
+			if _, err := registry.Find(ctx, namespace, func(p *api.Predictor) bool {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: fmt.Sprintf("%s_%s", sourceId, namespace),
+					Name:      p.GetName(),
+				}})
+				return false
+			}); err != nil {
+				pr.Log.Error(err, "Could not list Predictors to resync", "namespace", namespace, "source", sourceId)
+			}
+		}
+		return requests
+	})
+}
+
 func prefixName(prefix string) handler.EventHandler {
 	return handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
 		// Prepend prefix
@@ -34,18 +34,24 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/kserve/modelmesh-serving/pkg/mmesh"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
 
 	"github.com/go-logr/logr"
+	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 )
 
 const (
@@ -73,6 +79,7 @@ func (m *MMServiceMap) GetOrCreate(namespace string, tlsConfig mmesh.TLSConfigLo
 
 func (m *MMServiceMap) Delete(namespace string) {
 	(*sync.Map)(m).Delete(namespace)
+	mmesh.DeleteClientMetrics(namespace)
 }
 
 // ServiceReconciler reconciles a ServingRuntime object
@@ -84,24 +91,71 @@ type ServiceReconciler struct {
 	ConfigMapName        types.NamespacedName
 	ControllerDeployment types.NamespacedName
 	ClusterScope         bool
+	// whether the controller is enabled to read and watch secrets
+	EnableSecretWatch bool
 
 	MMServices       *MMServiceMap
-	ModelEventStream *mmesh.ModelMeshEventStream
+	ModelEventStream mmesh.ModelEventSource
 
 	ServiceMonitorCRDExists bool
+	// whether the Gateway API route CRDs are installed, GRPCRoute may be missing without HTTPRoute
+	HTTPRouteCRDExists bool
+	GRPCRouteCRDExists bool
+	// records the events of the Services, e.g. skipped external routes
+	Recorder record.EventRecorder
+	// whether ClusterServingRuntimes are reconciled, they may have the REST proxy too
+	EnableCSRWatch bool
 }
 
-func (r *ServiceReconciler) getMMService(namespace string,
-	cp *config.ConfigProvider, newConfig bool) (*mmesh.MMService, *config.Config, bool) {
+func (r *ServiceReconciler) getMMService(ctx context.Context, namespace string,
+	cp *config.ConfigProvider, newConfig bool) (*mmesh.MMService, *config.Config, bool, error) {
 	mms, newSvc := r.MMServices.GetOrCreate(namespace, r.tlsConfigFromSecret)
-	if newSvc || newConfig {
+	cfg := cp.GetConfig()
+	restProxyEnabled, err := r.namespaceRESTProxyEnabled(ctx, namespace, cfg.RESTProxy.Enabled)
+	if err != nil {
+		if !newSvc {
+			return mms, cfg, false, err
+		}
+		restProxyEnabled = cfg.RESTProxy.Enabled
+	}
+	if newSvc || newConfig || restProxyEnabled != mms.RESTProxyEnabled() {
 		if newSvc {
 			r.Log.Info("MMService created for namespace", "namespace", namespace)
 		}
-		cfg, changed := mms.UpdateConfig(cp)
-		return mms, cfg, changed
+		cfg, changed := mms.UpdateConfig(cp, restProxyEnabled)
+		return mms, cfg, changed, err
+	}
+	return mms, cfg, false, nil
+}
+
+// namespaceRESTProxyEnabled returns whether any of the multi-model runtimes which can be used in
+// the namespace has the REST proxy, so that the Service exposes its port
+func (r *ServiceReconciler) namespaceRESTProxyEnabled(ctx context.Context, namespace string, enabled bool) (bool, error) {
+	hasRESTProxy := func(annotations map[string]string, spec *kserveapi.ServingRuntimeSpec) bool {
+		rtEnabled, _ := modelmesh.RuntimeRESTProxyEnabled(annotations, enabled)
+		return rtEnabled && spec.IsMultiModelRuntime()
+	}
+	runtimes := &kserveapi.ServingRuntimeList{}
+	if err := r.Client.List(ctx, runtimes, client.InNamespace(namespace)); err != nil {
+		return enabled, fmt.Errorf("could not list the ServingRuntimes: %w", err)
+	}
+	for i := range runtimes.Items {
+		if rt := &runtimes.Items[i]; hasRESTProxy(rt.Annotations, &rt.Spec) {
+			return true, nil
+		}
+	}
+	if r.EnableCSRWatch {
+		csrs := &kserveapi.ClusterServingRuntimeList{}
+		if err := r.Client.List(ctx, csrs); err != nil {
+			return enabled, fmt.Errorf("could not list the ClusterServingRuntimes: %w", err)
+		}
+		for i := range csrs.Items {
+			if crt := &csrs.Items[i]; hasRESTProxy(crt.Annotations, &crt.Spec) {
+				return true, nil
+			}
+		}
 	}
-	return mms, cp.GetConfig(), false
+	return false, nil
 }
 
 // +kubebuilder:rbac:groups="",resources=services;services/finalizers,verbs=get;list;watch;create;update;patch;delete
@@ -109,6 +163,10 @@ func (r *ServiceReconciler) getMMService(namespace string,
 // +kubebuilder:rbac:groups="monitoring.coreos.com",resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return tracing.Reconcile(ctx, "service", req, r.reconcile)
+}
+
+func (r *ServiceReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	r.Log.V(1).Info("Service reconciler called", "name", req.NamespacedName)
 
 	var namespace string
@@ -170,7 +228,10 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 		owner = d
 	}
-	mms, cfg, _ := r.getMMService(namespace, r.ConfigProvider, false)
+	mms, cfg, _, err := r.getMMService(ctx, namespace, r.ConfigProvider, false)
+	if err != nil {
+		return RequeueResult, err
+	}
 
 	var s *corev1.Service
 	svc, err2, requeue := r.reconcileService(ctx, mms, namespace, owner)
@@ -180,9 +241,27 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 	s = svc
 
-	if err := r.ModelEventStream.UpdateWatchedService(ctx, cfg.GetEtcdSecretName(), cfg.InferenceServiceName, namespace); err != nil {
+	kvSecret, err := mmesh.NamespaceKVStoreSecret(ctx, r.Client, namespace, r.ControllerDeployment.Namespace, cfg.GetEtcdSecretName())
+	if err != nil {
 		return RequeueResult, err
 	}
+	if err = r.ModelEventStream.UpdateWatchedService(ctx, kvSecret, cfg.InferenceServiceName, namespace); err != nil {
+		return RequeueResult, err
+	}
+
+	if s != nil {
+		grpcEndpoint, httpEndpoint, err := r.reconcileExternalRoutes(ctx, cfg, s)
+		if err != nil {
+			return RequeueResult, err
+		}
+		mms.SetExternalEndpoints(grpcEndpoint, httpEndpoint)
+		if err := r.reconcileNetworkPolicy(ctx, cfg, s); err != nil {
+			return RequeueResult, err
+		}
+		if err := r.reconcileCertificate(ctx, cfg, s); err != nil {
+			return RequeueResult, err
+		}
+	}
 
 	// Service Monitor reconciliation should be called towards the end of the Service Reconcile method so that
 	// errors returned from here should not impact any other functions.
@@ -196,38 +275,119 @@ func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	return ctrl.Result{}, nil
 }
 
-func (r *ServiceReconciler) tlsConfigFromSecret(ctx context.Context, secretName string) (*tls.Config, error) {
+func (r *ServiceReconciler) tlsConfigFromSecret(ctx context.Context, secretNamespace, secretName,
+	clientCertSecretName string) (*tls.Config, error) {
 	if secretName == "" {
 		return nil, nil
 	}
-	tlsSecret := corev1.Secret{}
-	err := r.Client.Get(ctx, client.ObjectKey{
-		Name:      secretName,
-		Namespace: r.ControllerDeployment.Namespace}, &tlsSecret)
+	managed := secretNamespace != ""
+	if !managed {
+		secretNamespace = r.ControllerDeployment.Namespace
+	}
+	secret := types.NamespacedName{Name: secretName, Namespace: secretNamespace}
+	trusted, certificate, err := r.keyPairFromSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+	certPool, err := trustedCertPool(trusted)
 	if err != nil {
-		r.Log.Error(err, "Unable to access TLS secret", "secretName", secretName)
-		return nil, fmt.Errorf("unable to access TLS secret '%s': %v", secretName, err)
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{*certificate}, RootCAs: certPool}
+	if clientCertSecretName != "" {
+		clientCertSecret := types.NamespacedName{Name: clientCertSecretName, Namespace: r.ControllerDeployment.Namespace}
+		// fail fast if the client key pair can't be loaded
+		if _, _, err = r.keyPairFromSecret(ctx, clientCertSecret); err != nil {
+			return nil, err
+		}
+		// the key pair is read from the (cached) secret on each handshake, so that
+		// new connections use the rotated certificate without reconnecting
+		tlsConfig.Certificates = nil
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			_, certificate, err := r.keyPairFromSecret(context.Background(), clientCertSecret)
+			return certificate, err
+		}
+	}
+	if managed {
+		// cert-manager renews the secret in place, so both the key pair and the trusted
+		// certificates are read from it on each handshake rather than only when connecting
+		if clientCertSecretName == "" {
+			tlsConfig.Certificates = nil
+			tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				_, certificate, err := r.keyPairFromSecret(context.Background(), secret)
+				return certificate, err
+			}
+		}
+		// the default verification is replaced by VerifyConnection, which uses the current roots
+		tlsConfig.RootCAs = nil
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			trusted, _, err := r.keyPairFromSecret(context.Background(), secret)
+			if err != nil {
+				return err
+			}
+			return verifyServerCertificate(cs, trusted)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// trustedCertPool returns the system cert pool with the given PEM encoded certificates added
+func trustedCertPool(trusted []byte) (*x509.CertPool, error) {
+	certPool, _ := x509.SystemCertPool() // this returns a copy
+	if certPool == nil {
+		certPool = x509.NewCertPool()
+	}
+	if ok := certPool.AppendCertsFromPEM(trusted); !ok {
+		return nil, errors.New("failed to append ca certs")
+	}
+	return certPool, nil
+}
+
+// verifyServerCertificate performs the verification of the server's certificate chain which
+// crypto/tls does by default, against the given PEM encoded trusted certificates
+func verifyServerCertificate(cs tls.ConnectionState, trusted []byte) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	roots, err := trustedCertPool(trusted)
+	if err != nil {
+		return err
+	}
+	opts := x509.VerifyOptions{DNSName: cs.ServerName, Roots: roots, Intermediates: x509.NewCertPool()}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+// keyPairFromSecret loads the key pair of the TLS type secret, along with the PEM encoded
+// certificates to trust: its certificate and CA certificate, if it has one
+func (r *ServiceReconciler) keyPairFromSecret(ctx context.Context, secret types.NamespacedName) ([]byte, *tls.Certificate, error) {
+	secretName := secret.Name
+	tlsSecret := corev1.Secret{}
+	if err := r.Client.Get(ctx, secret, &tlsSecret); err != nil {
+		r.Log.Error(err, "Unable to access TLS secret", "secretName", secretName, "namespace", secret.Namespace)
+		return nil, nil, fmt.Errorf("unable to access TLS secret '%s': %v", secretName, err)
 	}
 	cert, ok2 := tlsSecret.Data[modelmesh.TLSSecretCertKey]
 	key, ok := tlsSecret.Data[modelmesh.TLSSecretKeyKey]
 	if !ok || !ok2 {
-		r.Log.Error(err, "TLS secret missing required keys", "secretName", secretName)
-		return nil, fmt.Errorf("TLS secret '%s' missing %s and/or %s",
+		r.Log.Error(nil, "TLS secret missing required keys", "secretName", secretName, "namespace", secret.Namespace)
+		return nil, nil, fmt.Errorf("TLS secret '%s' missing %s and/or %s",
 			secretName, modelmesh.TLSSecretCertKey, modelmesh.TLSSecretKeyKey)
 	}
 	certificate, err := tls.X509KeyPair(cert, key)
 	if err != nil {
-		r.Log.Error(err, "Could not load client key pair")
-		return nil, fmt.Errorf("could not load client key pair: %v", err)
+		r.Log.Error(err, "Could not load client key pair", "secretName", secretName, "namespace", secret.Namespace)
+		return nil, nil, fmt.Errorf("could not load client key pair from secret '%s': %v", secretName, err)
 	}
-	certPool, _ := x509.SystemCertPool() // this returns a copy
-	if certPool == nil {
-		certPool = x509.NewCertPool()
+	trusted := cert
+	if ca, ok := tlsSecret.Data[modelmesh.TLSClientCertKey]; ok {
+		trusted = append(append(append([]byte{}, cert...), '\n'), ca...)
 	}
-	if ok := certPool.AppendCertsFromPEM(cert); !ok {
-		return nil, errors.New("failed to append ca certs")
-	}
-	return &tls.Config{Certificates: []tls.Certificate{certificate}, RootCAs: certPool}, nil
+	return trusted, &certificate, nil
 }
 
 func (r *ServiceReconciler) reconcileService(ctx context.Context, mms *mmesh.MMService,
@@ -425,7 +585,16 @@ func (r *ServiceReconciler) reconcileServiceMonitor(ctx context.Context, metrics
 }
 
 func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	builder := ctrl.NewControllerManagedBy(mgr).Named("ServiceReconciler").Owns(&corev1.Service{})
+	builder := ctrl.NewControllerManagedBy(mgr).Named("ServiceReconciler").
+		Owns(&corev1.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&networkingv1.NetworkPolicy{})
+	if r.HTTPRouteCRDExists {
+		builder.Owns(&gatewayv1.HTTPRoute{})
+	}
+	if r.GRPCRouteCRDExists {
+		builder.Owns(&gatewayv1alpha2.GRPCRoute{})
+	}
 	if r.ClusterScope {
 		// Services are owned by Namespace resources
 		r.setupForClusterScope(builder)
@@ -433,9 +602,78 @@ func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// Service is owned by controller Deployment (same namespace only)
 		r.setupForNamespaceScope(builder)
 	}
+	if r.EnableSecretWatch {
+		r.setupSecretWatch(builder)
+	}
+	r.setupRuntimeWatch(builder)
 	return builder.Complete(r)
 }
 
+// setupRuntimeWatch reconciles the Services when runtimes are added, removed or annotated,
+// since the REST proxy ports are only exposed when some runtime has the REST proxy
+func (r *ServiceReconciler) setupRuntimeWatch(builder *bld.Builder) {
+	runtimeChangePredicate := predicate.Or(predicate.AnnotationChangedPredicate{}, predicate.GenerationChangedPredicate{})
+	namespaceRequest := func(namespace string) reconcile.Request {
+		if r.ClusterScope {
+			return reconcile.Request{NamespacedName: types.NamespacedName{Name: namespace}}
+		}
+		return reconcile.Request{NamespacedName: r.ControllerDeployment}
+	}
+	builder.Watches(&kserveapi.ServingRuntime{},
+		handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
+			if !r.ClusterScope && o.GetNamespace() != r.ControllerDeployment.Namespace {
+				return []reconcile.Request{}
+			}
+			return []reconcile.Request{namespaceRequest(o.GetNamespace())}
+		}), bld.WithPredicates(runtimeChangePredicate))
+	if !r.EnableCSRWatch {
+		return
+	}
+	builder.Watches(&kserveapi.ClusterServingRuntime{},
+		handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, _ client.Object) []reconcile.Request {
+			if !r.ClusterScope {
+				return []reconcile.Request{namespaceRequest(r.ControllerDeployment.Namespace)}
+			}
+			list := &corev1.NamespaceList{}
+			if err := r.Client.List(ctx, list); err != nil {
+				r.Log.Error(err, "Error listing Namespaces to reconcile after ClusterServingRuntime change")
+				return []reconcile.Request{}
+			}
+			var requests []reconcile.Request
+			for i := range list.Items {
+				if n := &list.Items[i]; modelMeshEnabled(n, r.ControllerDeployment.Namespace) {
+					requests = append(requests, namespaceRequest(n.Name))
+				}
+			}
+			return requests
+		}), bld.WithPredicates(runtimeChangePredicate))
+}
+
+// setupSecretWatch watches the KV store secrets so that the model event stream picks up rotated
+// certificates and credentials. The controller's secret is shared by all namespaces
+// without their own so only the controller namespace's service needs reconciling.
+func (r *ServiceReconciler) setupSecretWatch(builder *bld.Builder) {
+	builder.Watches(&corev1.Secret{},
+		handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+			ns := r.ControllerDeployment.Namespace
+			if o.GetNamespace() != ns {
+				if r.ClusterScope && isNamespaceKVStoreSecret(ctx, r.Client, o) {
+					r.Log.Info("Triggering service reconciliation after KV store secret change", "namespace", o.GetNamespace())
+					return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: o.GetNamespace()}}}
+				}
+				return []reconcile.Request{}
+			}
+			if o.GetName() != r.ConfigProvider.GetConfig().GetEtcdSecretName() {
+				return []reconcile.Request{}
+			}
+			r.Log.Info("Triggering service reconciliation after KV store secret change")
+			if r.ClusterScope {
+				return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: ns}}}
+			}
+			return []reconcile.Request{{NamespacedName: r.ControllerDeployment}}
+		}))
+}
+
 func (r *ServiceReconciler) setupForNamespaceScope(builder *bld.Builder) {
 	filter := func(meta metav1.Object) bool {
 		return meta.GetName() == r.ControllerDeployment.Name &&
@@ -448,7 +686,11 @@ func (r *ServiceReconciler) setupForNamespaceScope(builder *bld.Builder) {
 	})).
 		Watches(&corev1.ConfigMap{},
 			config.ConfigWatchHandler(r.ConfigMapName, func() []reconcile.Request {
-				if _, _, changed := r.getMMService(r.ControllerDeployment.Namespace, r.ConfigProvider, true); changed {
+				_, _, changed, err := r.getMMService(context.TODO(), r.ControllerDeployment.Namespace, r.ConfigProvider, true)
+				if err != nil {
+					r.Log.Error(err, "Error checking the REST proxy of the runtimes after config change")
+				}
+				if changed || err != nil {
 					r.Log.Info("Triggering service reconciliation after config change")
 					return []reconcile.Request{{NamespacedName: r.ControllerDeployment}}
 				}
@@ -479,7 +721,12 @@ func (r *ServiceReconciler) setupForClusterScope(builder *bld.Builder) {
 				requests := make([]reconcile.Request, 0, len(list.Items))
 				for i := range list.Items {
 					if n := &list.Items[i]; modelMeshEnabled(n, r.ControllerDeployment.Namespace) {
-						if _, _, changed := r.getMMService(n.Name, r.ConfigProvider, true); changed {
+						_, _, changed, err := r.getMMService(context.TODO(), n.Name, r.ConfigProvider, true)
+						if err != nil {
+							r.Log.Error(err, "Error checking the REST proxy of the runtimes after config change",
+								"namespace", n.Name)
+						}
+						if changed || err != nil {
 							requests = append(requests, reconcile.Request{
 								NamespacedName: types.NamespacedName{Name: n.Name, Namespace: n.Namespace},
 							})
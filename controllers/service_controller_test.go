@@ -0,0 +1,85 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+func TestRuntimeRESTProxyServicePorts(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, kserveapi.AddToScheme(s))
+	multiModel := true
+	rt := &kserveapi.ServingRuntime{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "triton",
+			Namespace:   "team-a",
+			Annotations: map[string]string{constants.RESTProxyAnnotationKey: "true"},
+		},
+		Spec: kserveapi.ServingRuntimeSpec{MultiModel: &multiModel},
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(rt).Build()
+
+	// the REST proxy is disabled by default
+	cfg, err := config.NewMergedConfigFromString("restProxy:\n  enabled: false\n  openai:\n    enabled: true")
+	require.NoError(t, err)
+	cp := config.NewConfigProviderForTest()
+	config.SetConfigForTest(cp, cfg)
+	r := &ServiceReconciler{Client: cl, Log: logr.Discard(), MMServices: &MMServiceMap{}}
+	ctx := context.Background()
+
+	servicePorts := func() *corev1.Service {
+		name, spec := r.MMServices.Get("team-a").GetNameAndSpec()
+		return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"}, Spec: *spec}
+	}
+
+	// the runtime opting in exposes the REST proxy and OpenAI API ports of the Service
+	_, _, changed, err := r.getMMService(ctx, "team-a", cp, false)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	svc := servicePorts()
+	assert.Equal(t, int32(cfg.RESTProxy.Port), servicePort(svc, "http"))
+	assert.Equal(t, int32(cfg.RESTProxy.OpenAI.Port), servicePort(svc, "openai"))
+	npSpec := runtimeNetworkPolicySpec(&config.Config{}, svc, "modelmesh-serving")
+	assert.Len(t, npSpec.Ingress[2].Ports, 3)
+
+	// and they are removed once it opts out
+	rt.Annotations[constants.RESTProxyAnnotationKey] = "false"
+	require.NoError(t, cl.Update(ctx, rt))
+	_, _, changed, err = r.getMMService(ctx, "team-a", cp, false)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	svc = servicePorts()
+	assert.Zero(t, servicePort(svc, "http"))
+	assert.Zero(t, servicePort(svc, "openai"))
+
+	// nothing changes without runtime or config changes
+	_, _, changed, err = r.getMMService(ctx, "team-a", cp, false)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
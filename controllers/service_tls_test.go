@@ -0,0 +1,149 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+)
+
+func testKeyPairSecret(t *testing.T, name, commonName string) *corev1.Secret {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "modelmesh-serving"},
+		Data: map[string][]byte{
+			modelmesh.TLSSecretCertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			modelmesh.TLSSecretKeyKey:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}),
+		},
+	}
+}
+
+func clientCertCommonName(t *testing.T, tlsConfig *tls.Config) string {
+	var certificate *tls.Certificate
+	if tlsConfig.GetClientCertificate != nil {
+		var err error
+		certificate, err = tlsConfig.GetClientCertificate(&tls.CertificateRequestInfo{})
+		require.NoError(t, err)
+	} else {
+		certificate = &tlsConfig.Certificates[0]
+	}
+	cert, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func TestTLSConfigFromSecret(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		testKeyPairSecret(t, "server-cert", "modelmesh-serving"),
+		testKeyPairSecret(t, "client-cert", "modelmesh-controller"),
+	).Build()
+	r := &ServiceReconciler{
+		Client:               cl,
+		Log:                  ctrl.Log.WithName("test"),
+		ControllerDeployment: types.NamespacedName{Name: "modelmesh-controller", Namespace: "modelmesh-serving"},
+	}
+
+	tlsConfig, err := r.tlsConfigFromSecret(ctx, "", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	// the server's key pair is presented by default
+	tlsConfig, err = r.tlsConfigFromSecret(ctx, "", "server-cert", "")
+	require.NoError(t, err)
+	assert.Equal(t, "modelmesh-serving", clientCertCommonName(t, tlsConfig))
+
+	tlsConfig, err = r.tlsConfigFromSecret(ctx, "", "server-cert", "client-cert")
+	require.NoError(t, err)
+	assert.Equal(t, "modelmesh-controller", clientCertCommonName(t, tlsConfig))
+
+	// the rotated client certificate is used for new connections
+	require.NoError(t, cl.Update(ctx, testKeyPairSecret(t, "client-cert", "rotated")))
+	assert.Equal(t, "rotated", clientCertCommonName(t, tlsConfig))
+
+	_, err = r.tlsConfigFromSecret(ctx, "", "server-cert", "missing")
+	assert.ErrorContains(t, err, "missing")
+}
+
+func TestTLSConfigFromManagedSecret(t *testing.T) {
+	ctx := context.Background()
+	const serverName = "modelmesh-serving.user-ns"
+	newSecret := func() *corev1.Secret {
+		secret := testKeyPairSecret(t, "server-cert", serverName)
+		secret.Namespace = "user-ns"
+		return secret
+	}
+	secret := newSecret()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+	r := &ServiceReconciler{
+		Client:               cl,
+		Log:                  ctrl.Log.WithName("test"),
+		ControllerDeployment: types.NamespacedName{Name: "modelmesh-controller", Namespace: "modelmesh-serving"},
+	}
+
+	// the secret is read from the namespace of the service
+	tlsConfig, err := r.tlsConfigFromSecret(ctx, "user-ns", "server-cert", "")
+	require.NoError(t, err)
+	assert.Equal(t, serverName, clientCertCommonName(t, tlsConfig))
+
+	verify := func(s *corev1.Secret) error {
+		block, _ := pem.Decode(s.Data[modelmesh.TLSSecretCertKey])
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		return tlsConfig.VerifyConnection(tls.ConnectionState{
+			ServerName: serverName, PeerCertificates: []*x509.Certificate{cert},
+		})
+	}
+	assert.NoError(t, verify(secret))
+
+	// the renewed certificate is trusted by the existing config
+	renewed := newSecret()
+	assert.Error(t, verify(renewed))
+	require.NoError(t, cl.Update(ctx, renewed))
+	assert.NoError(t, verify(renewed))
+	assert.Error(t, verify(secret))
+}
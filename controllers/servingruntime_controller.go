@@ -34,6 +34,7 @@ import (
 	"github.com/kserve/modelmesh-serving/pkg/config"
 	"github.com/kserve/modelmesh-serving/pkg/mmesh"
 	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -47,6 +48,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const (
@@ -71,6 +73,10 @@ type ServingRuntimeReconciler struct {
 	// store some information about current runtimes for making scaling decisions
 	runtimeInfoMap      map[types.NamespacedName]*runtimeInfo
 	runtimeInfoMapMutex sync.Mutex
+	// last status written to each runtime, used to avoid redundant status updates
+	runtimeStatuses        map[types.NamespacedName]api.RuntimeDeploymentStatus
+	clusterRuntimeStatuses map[string]map[string]api.RuntimeDeploymentStatus
+	runtimeStatusMutex     sync.Mutex
 
 	RegistryMap map[string]predictor_source.PredictorRegistry
 }
@@ -88,6 +94,10 @@ type runtimeInfo struct {
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 
 func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return tracing.Reconcile(ctx, "servingruntime", req, r.reconcile)
+}
+
+func (r *ServingRuntimeReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("servingruntime", req.NamespacedName)
 	log.V(1).Info("ServingRuntime reconciler called")
 	// Make sure the namespace has serving enabled
@@ -109,75 +119,87 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	}
 
-	// build the map for ServingRuntimeSpec
+	cfg := r.ConfigProvider.GetConfig()
+
+	// build the map for ServingRuntimeSpec, along with whether each runtime has the REST proxy
 	srSpecs := make(map[string]*kserveapi.ServingRuntimeSpec)
+	restProxyEnabled := make(map[string]bool)
 	for i := range csrs.Items {
 		crt := &csrs.Items[i]
 		if crt.Spec.IsMultiModelRuntime() {
 			srSpecs[crt.GetName()] = &crt.Spec
+			restProxyEnabled[crt.GetName()], _ = modelmesh.RuntimeRESTProxyEnabled(crt.GetAnnotations(), cfg.RESTProxy.Enabled)
 		}
 	}
 	// rt spec would override crt spec by design
 	for i := range runtimes.Items {
 		rt := &runtimes.Items[i]
 		srSpecs[rt.GetName()] = &rt.Spec
+		restProxyEnabled[rt.GetName()], _ = modelmesh.RuntimeRESTProxyEnabled(rt.GetAnnotations(), cfg.RESTProxy.Enabled)
 	}
 
-	cfg := r.ConfigProvider.GetConfig()
-	cc := modelmesh.ClusterConfig{SRSpecs: srSpecs, Scheme: r.Scheme}
+	cc := modelmesh.ClusterConfig{SRSpecs: srSpecs, RESTProxyEnabled: restProxyEnabled, Scheme: r.Scheme}
 	if err = cc.Reconcile(ctx, req.Namespace, r.Client, cfg); err != nil {
 		return RequeueResult, fmt.Errorf("could not reconcile the modelmesh type-constraints configmap: %w", err)
 	}
 
-	// Delete etcd secret when there is no ServingRuntimes in a namespace
 	etcdSecretName := cfg.GetEtcdSecretName()
-	if len(srSpecs) == 0 {
-		// We don't delete the etcd secret in the controller namespace
-		if req.Namespace != r.ControllerNamespace {
-			s := &corev1.Secret{}
-			err = r.Client.Get(ctx, types.NamespacedName{
-				Name:      etcdSecretName,
-				Namespace: req.Namespace,
-			}, s)
+	// the namespace may have opted into its own KV store, whose secret is used as is
+	kvSecret, err := mmesh.NamespaceKVStoreSecret(ctx, r.Client, req.Namespace, r.ControllerNamespace, etcdSecretName)
+	if err != nil {
+		return RequeueResult, fmt.Errorf("Could not determine the KV store secret of the namespace: %w", err)
+	}
+	ownKVStore := kvSecret.Namespace != r.ControllerNamespace
 
-			if err == nil {
-				err = r.Delete(ctx, s)
-			} else if errors.IsNotFound(err) {
-				err = nil
-			}
-			if err != nil {
-				return RequeueResult, err
-			}
-		}
-	} else if req.Namespace != r.ControllerNamespace {
-		// If not controller namespace then read etcd secret from controller namespace,
-		// replace rootprefix with ns-specific one, and then create/update etcd secret (with same name)
-		// in _this_ namespace and include labels similar to the tc-config configmap
+	// Delete the copy of the etcd secret when there is no ServingRuntimes in a namespace, or
+	// when the namespace has its own. We don't delete the etcd secret in the controller namespace.
+	if req.Namespace != r.ControllerNamespace && (len(srSpecs) == 0 || ownKVStore) &&
+		!(ownKVStore && kvSecret.Name == etcdSecretName) {
 		s := &corev1.Secret{}
-		if err = r.Client.Get(ctx, types.NamespacedName{
+		err = r.Client.Get(ctx, types.NamespacedName{
 			Name:      etcdSecretName,
-			Namespace: r.ControllerNamespace,
-		}, s); err != nil {
-			return RequeueResult, fmt.Errorf("Could not get the controller etcd secret: %w", err)
+			Namespace: req.Namespace,
+		}, s)
+
+		if err == nil {
+			err = r.Delete(ctx, s)
+		} else if errors.IsNotFound(err) {
+			err = nil
 		}
+		if err != nil {
+			return RequeueResult, err
+		}
+	}
 
-		data := s.Data[modelmesh.EtcdSecretKey]
-		etcdConfig := mmesh.EtcdConfig{}
-		if err = json.Unmarshal(data, &etcdConfig); err != nil {
-			return RequeueResult, fmt.Errorf("failed to parse etcd config json: %w", err)
+	var kvConfig mmesh.KVStoreConfig
+	if len(srSpecs) != 0 {
+		// Read the KV store (etcd or ZooKeeper) secret of the namespace. If it's the controller's secret and
+		// not controller namespace then replace rootprefix with ns-specific one, and then create/update
+		// the secret (with same name) in _this_ namespace and include labels similar to the tc-config configmap
+		s := &corev1.Secret{}
+		if err = r.Client.Get(ctx, kvSecret, s); err != nil {
+			return RequeueResult, fmt.Errorf("Could not get the KV store secret %s: %w", kvSecret, err)
 		}
 
-		es := mmesh.EtcdSecret{
-			Log:                 ctrl.Log.WithName("etcdSecret"),
-			Name:                etcdSecretName,
-			Namespace:           req.Namespace,
-			ControllerNamespace: r.ControllerNamespace,
-			EtcdConfig:          &etcdConfig,
-			Scheme:              r.Scheme,
+		if kvConfig, err = mmesh.KVStoreConfigFromSecret(kvSecret.Name, s.Data); err != nil {
+			return RequeueResult, err
 		}
 
-		if err = es.Apply(ctx, r.Client); err != nil {
-			return RequeueResult, fmt.Errorf("Could not apply the modelmesh etcd secret: %w", err)
+		if req.Namespace != r.ControllerNamespace && !ownKVStore {
+			es := mmesh.KVStoreSecret{
+				Log:                 ctrl.Log.WithName("kvStoreSecret"),
+				Name:                etcdSecretName,
+				Namespace:           req.Namespace,
+				ControllerNamespace: r.ControllerNamespace,
+				Config:              kvConfig,
+				Data:                s.Data,
+				Scheme:              r.Scheme,
+			}
+
+			if err = es.Apply(ctx, r.Client); err != nil {
+				return RequeueResult, fmt.Errorf("Could not apply the modelmesh %s secret: %w", kvConfig.Type(), err)
+			}
+			kvConfig = kvConfig.ForNamespace(req.Namespace)
 		}
 	}
 
@@ -185,25 +207,30 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	rt := &kserveapi.ServingRuntime{}
 	crt := &kserveapi.ClusterServingRuntime{}
 	var owner mf.Owner
+	var runtimeObj client.Object
 	var spec *kserveapi.ServingRuntimeSpec
 
 	if err = r.Client.Get(ctx, req.NamespacedName, rt); err == nil {
 		spec = &rt.Spec
 		owner = rt
+		runtimeObj = rt
 	} else if errors.IsNotFound(err) {
 		log.Info("Runtime is not found in namespace")
 
 		if !r.EnableCSRWatch {
+			r.forgetRuntimeStatus(req.NamespacedName, false)
 			return r.removeRuntimeFromInfoMap(req)
 		}
 		// try to find the runtime in cluster ServingRuntimes
 		if err = r.Client.Get(ctx, types.NamespacedName{Name: req.Name}, crt); err == nil {
 			spec = &crt.Spec
 			owner = crt
+			runtimeObj = crt
 		} else if errors.IsNotFound(err) {
 			log.Info("Runtime is not found in cluster")
 
 			// remove runtime from info map
+			r.forgetRuntimeStatus(req.NamespacedName, true)
 			return r.removeRuntimeFromInfoMap(req)
 		} else {
 			return ctrl.Result{}, fmt.Errorf("error retrieving ClusterServingRuntime %s: %w", req.Name, err)
@@ -213,15 +240,44 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 
 	// Check that ServerType is provided in runtime spec and that this value matches that of the specified container
-	if err = validateServingRuntimeSpec(spec, cfg); err != nil {
+	err = validateServingRuntimeSpec(spec, cfg)
+	var rtRESTProxyEnabled, rtAccessLogging bool
+	var rtOpenAIAccessLogSampleRatio float64
+	if err == nil {
+		// the runtime may opt in or out of the REST proxy
+		rtRESTProxyEnabled, err = modelmesh.RuntimeRESTProxyEnabled(runtimeObj.GetAnnotations(), cfg.RESTProxy.Enabled)
+	}
+	if err == nil {
+		// and of the access logs
+		rtAccessLogging, err = modelmesh.RuntimeAccessLoggingEnabled(runtimeObj.GetAnnotations(), cfg.AccessLog.Enabled)
+	}
+	if err == nil {
+		rtOpenAIAccessLogSampleRatio, err = modelmesh.RuntimeOpenAIAccessLogSampleRatio(runtimeObj.GetAnnotations(),
+			cfg.AccessLog.OpenAISampleRatio)
+	}
+	if err != nil {
+		if spec.IsMultiModelRuntime() {
+			if serr := r.updateRuntimeStatus(ctx, req.Namespace, runtimeObj, api.RuntimeDeploymentStatus{
+				State:   api.RuntimeInvalid,
+				Reason:  api.InvalidRuntimeSpec,
+				Message: err.Error(),
+			}); serr != nil {
+				log.Error(serr, "Could not update runtime status")
+			}
+		}
 		return ctrl.Result{}, fmt.Errorf("Invalid runtime Spec: %w", err)
 	}
 
 	var pvcs []string
-	if pvcs, err = r.getPVCs(ctx, req, spec, cfg); err != nil {
+	if pvcs, err = r.getPVCs(ctx, req, spec, rtRESTProxyEnabled, cfg); err != nil {
 		return ctrl.Result{}, fmt.Errorf("Could not get pvcs: %w", err)
 	}
 
+	var payloadProcessors []string
+	if payloadProcessors, err = r.getPayloadProcessors(ctx, req, spec, rtRESTProxyEnabled, cfg); err != nil {
+		return ctrl.Result{}, fmt.Errorf("Could not get payload processors: %w", err)
+	}
+
 	// construct the deployment
 	mmDeployment := modelmesh.Deployment{
 		ServiceName:                cfg.InferenceServiceName,
@@ -235,39 +291,75 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		Metrics:                    cfg.Metrics.Enabled,
 		PrometheusPort:             cfg.Metrics.Port,
 		PrometheusScheme:           cfg.Metrics.Scheme,
-		PayloadProcessors:          strings.Join(cfg.PayloadProcessors, " "),
+		PayloadProcessors:          strings.Join(payloadProcessors, " "),
 		ModelMeshImage:             cfg.ModelMeshImage.TaggedImage(),
 		ModelMeshResources:         cfg.ModelMeshResources.ToKubernetesType(),
 		ModelMeshAdditionalEnvVars: cfg.InternalModelMeshEnvVars.ToKubernetesType(),
-		RESTProxyEnabled:           cfg.RESTProxy.Enabled,
+		RESTProxyEnabled:           rtRESTProxyEnabled,
 		RESTProxyImage:             cfg.RESTProxy.Image.TaggedImage(),
 		RESTProxyPort:              cfg.RESTProxy.Port,
 		RESTProxySkipVerify:        cfg.RESTProxy.SkipVerify,
 		RESTProxyResources:         cfg.RESTProxy.Resources.ToKubernetesType(),
+		RESTProxyListener:          cfg.RESTProxy.HTTPListenerConfig,
+		RESTProxyListenerImage:     cfg.RESTProxy.Listener.Image.TaggedImage(),
+		RESTProxyListenerCommand:   cfg.RESTProxy.Listener.Image.Command,
+		RESTProxyListenerResources: cfg.RESTProxy.Listener.Resources.ToKubernetesType(),
+		RESTProxyBackendPort:       cfg.RESTProxy.Listener.BackendPort,
 		PullerImage:                cfg.StorageHelperImage.TaggedImage(),
 		PullerImageCommand:         cfg.StorageHelperImage.Command,
 		PullerResources:            cfg.StorageHelperResources.ToKubernetesType(),
 		Port:                       cfg.InferenceServicePort,
 		GrpcMaxMessageSize:         cfg.GrpcMaxMessageSizeBytes,
 		PVCs:                       pvcs,
+		ModelCache:                 cfg.ModelCache,
+		// OpenAI API sidecar of the REST proxy
+		OpenAIProxyEnabled:   cfg.RESTProxy.OpenAI.Enabled,
+		OpenAIProxyImage:     cfg.RESTProxy.OpenAI.Image.TaggedImage(),
+		OpenAIProxyCommand:   cfg.RESTProxy.OpenAI.Image.Command,
+		OpenAIProxyPort:      cfg.RESTProxy.OpenAI.Port,
+		OpenAIProxyResources: cfg.RESTProxy.OpenAI.Resources.ToKubernetesType(),
+		OpenAIProxyListener:  cfg.RESTProxy.OpenAI.HTTPListenerConfig,
 		// Replicas is set below
 		TLSSecretName:       cfg.TLS.SecretName,
 		TLSClientAuth:       cfg.TLS.ClientAuth,
-		EtcdSecretName:      cfg.GetEtcdSecretName(),
+		TLSCertManaged:      cfg.TLS.CertManager.Enabled(),
+		EtcdSecretName:      kvSecret.Name,
+		KVStoreType:         modelmesh.KVStoreEtcd,
 		ServiceAccountName:  cfg.ServiceAccountName,
-		EnableAccessLogging: cfg.EnableAccessLogging,
+		EnableAccessLogging: rtAccessLogging,
 		Client:              r.Client,
 		AnnotationsMap:      cfg.RuntimePodAnnotations,
 		LabelsMap:           cfg.RuntimePodLabels,
 		ImagePullSecrets:    cfg.ImagePullSecrets,
+		ModelMeshLogFormat:  cfg.ModelMeshLogFormat,
+		// options of the access logs when enabled
+		AccessLogRequestHeaders:    cfg.AccessLog.RequestHeaders,
+		OpenAIAccessLogSampleRatio: rtOpenAIAccessLogSampleRatio,
+	}
+	if zkConfig, ok := kvConfig.(mmesh.ZookeeperConfig); ok {
+		mmDeployment.KVStoreType = modelmesh.KVStoreZookeeper
+		mmDeployment.ZookeeperConnection = zkConfig.ModelMeshConnection()
 	}
 	// if the runtime is disabled, delete the deployment
 	if spec.IsDisabled() || !spec.IsMultiModelRuntime() || !mmEnabled {
 		log.Info("Runtime is disabled, incompatible with modelmesh, or namespace is not modelmesh-enabled")
+		runtimeReplicas.DeleteLabelValues(req.Namespace, req.Name)
 		if err = mmDeployment.Delete(ctx, r.Client); err != nil {
 			return ctrl.Result{}, fmt.Errorf("could not delete the model mesh deployment: %w", err)
 		}
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.updateDisabledRuntimeStatus(ctx, req.Namespace, runtimeObj, spec, mmEnabled)
+	}
+
+	if mmDeployment.TLSCertManaged {
+		tlsSecret := &corev1.Secret{}
+		err = r.Client.Get(ctx, types.NamespacedName{Name: cfg.TLS.SecretName, Namespace: req.Namespace}, tlsSecret)
+		if errors.IsNotFound(err) {
+			// the secret watch will trigger another reconcile once the certificate is issued
+			log.Info("Waiting for cert-manager to issue the TLS certificate", "secretName", cfg.TLS.SecretName)
+			return RequeueResult, nil
+		} else if err != nil {
+			return ctrl.Result{}, fmt.Errorf("could not get the TLS secret %s: %w", cfg.TLS.SecretName, err)
+		}
 	}
 
 	// At the moment, ModelMesh deployment name is the combined of ServingRuntime and deploymentObject name.
@@ -285,10 +377,11 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		log.Error(err, "fails to create an autoscaler controller: %w", "skip to create HPA")
 	}
 
-	replicas, requeueDuration, err := r.determineReplicasAndRequeueDuration(ctx, log, cfg, spec, req.NamespacedName)
+	replicas, requeueDuration, err := r.determineReplicasAndRequeueDuration(ctx, log, cfg, spec, rtRESTProxyEnabled, req.NamespacedName)
 	if err != nil {
 		return RequeueResult, fmt.Errorf("could not determine replicas: %w", err)
 	}
+	runtimeReplicas.WithLabelValues(req.Namespace, req.Name).Set(float64(replicas))
 
 	//ScaleToZero or None autoscaler case
 	if replicas == uint16(0) || as.Autoscaler.AutoscalerClass == autoscaler.AutoscalerClassNone {
@@ -331,10 +424,23 @@ func (r *ServingRuntimeReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 		return ctrl.Result{}, fmt.Errorf("could not apply the model mesh deployment: %w", err)
 	}
+
+	deployment := &appsv1.Deployment{}
+	if err = r.Client.Get(ctx, types.NamespacedName{Name: mmDeploymentName, Namespace: req.Namespace}, deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, fmt.Errorf("could not get the model mesh deployment: %w", err)
+		}
+		// not yet in the cache, the deployment watch will trigger another reconcile
+		return ctrl.Result{RequeueAfter: requeueDuration}, nil
+	}
+	if err = r.updateRuntimeStatus(ctx, req.Namespace, runtimeObj, deployedRuntimeStatus(deployment)); err != nil {
+		return RequeueResult, err
+	}
 	return ctrl.Result{RequeueAfter: requeueDuration}, nil
 }
 
-func (r *ServingRuntimeReconciler) getPVCs(ctx context.Context, req ctrl.Request, rt *kserveapi.ServingRuntimeSpec, cfg *config.Config) ([]string, error) {
+func (r *ServingRuntimeReconciler) getPVCs(ctx context.Context, req ctrl.Request, rt *kserveapi.ServingRuntimeSpec,
+	restProxyEnabled bool, cfg *config.Config) ([]string, error) {
 	// get the PVCs from the storage-config Secret
 	storageConfigPVCsMap := make(map[string]struct{})
 	s := &corev1.Secret{}
@@ -367,8 +473,6 @@ func (r *ServingRuntimeReconciler) getPVCs(ctx context.Context, req ctrl.Request
 	// collect PVCs from Predictors when the global flag 'allowAnyPVC' is enabled
 	predictorPVCsMap := make(map[string]struct{})
 	if cfg.AllowAnyPVC {
-		restProxyEnabled := cfg.RESTProxy.Enabled
-
 		// use a predicate function to extract the PVCs from Predictors in the registry
 		f := func(p *api.Predictor) bool {
 			if runtimeSupportsPredictor(rt, p, restProxyEnabled, req.Name) &&
@@ -441,6 +545,53 @@ func (r *ServingRuntimeReconciler) getPVCs(ctx context.Context, req ctrl.Request
 	return pvcs, nil
 }
 
+// getPayloadProcessors returns the configured payload processors along with those sending the payloads
+// of each Predictor supported by the runtime to the destinations of its namespace which it selects
+func (r *ServingRuntimeReconciler) getPayloadProcessors(ctx context.Context, req ctrl.Request, rt *kserveapi.ServingRuntimeSpec,
+	restProxyEnabled bool, cfg *config.Config) ([]string, error) {
+	// the selected destinations by the vmodel id of each Predictor, which unlike the id
+	// of its concrete model doesn't change with its spec
+	selected := make(map[string]sets.Set[string])
+	for _, pr := range r.RegistryMap {
+		f := func(p *api.Predictor) bool {
+			if names := modelmesh.SelectedPayloadProcessorDestinations(p.Annotations); len(names) > 0 &&
+				runtimeSupportsPredictor(rt, p, restProxyEnabled, req.Name) {
+				if selected[p.Name] == nil {
+					selected[p.Name] = sets.New[string]()
+				}
+				selected[p.Name].Insert(names...)
+			}
+			return false
+		}
+		if _, err := pr.Find(ctx, req.Namespace, f); err != nil {
+			return nil, err
+		}
+	}
+	if len(selected) == 0 {
+		return cfg.PayloadProcessors, nil
+	}
+
+	destinations, invalidErr, err := namespacePayloadProcessorDestinations(ctx, r.Client, req.Namespace, r.ClusterScope)
+	if err != nil {
+		return nil, err
+	}
+	if invalidErr != nil {
+		// the valid destinations are still used, the selecting Predictors report the invalid ones
+		r.Log.Error(invalidErr, "Ignoring invalid payload processor destinations of namespace",
+			"namespace", req.Namespace, "runtime", req.Name)
+	}
+	predictorProcessors := sets.New[string]()
+	for vModelId, names := range selected {
+		for name := range names {
+			// undefined destinations are reported in the status of the Predictor
+			if d, ok := destinations[name]; ok {
+				predictorProcessors.Insert(d.Processor(vModelId))
+			}
+		}
+	}
+	return append(append([]string{}, cfg.PayloadProcessors...), sets.List(predictorProcessors)...), nil
+}
+
 func (r *ServingRuntimeReconciler) removeRuntimeFromInfoMap(req ctrl.Request) (ctrl.Result, error) {
 	// remove runtime from info map
 	r.runtimeInfoMapMutex.Lock()
@@ -450,11 +601,12 @@ func (r *ServingRuntimeReconciler) removeRuntimeFromInfoMap(req ctrl.Request) (c
 		// this is safe even if the entry doesn't exist
 		delete(r.runtimeInfoMap, req.NamespacedName)
 	}
+	runtimeReplicas.DeleteLabelValues(req.Namespace, req.Name)
 	return ctrl.Result{}, nil
 }
 
 func (r *ServingRuntimeReconciler) determineReplicasAndRequeueDuration(ctx context.Context, log logr.Logger,
-	config *config.Config, rt *kserveapi.ServingRuntimeSpec, rtName types.NamespacedName) (uint16, time.Duration, error) {
+	config *config.Config, rt *kserveapi.ServingRuntimeSpec, restProxyEnabled bool, rtName types.NamespacedName) (uint16, time.Duration, error) {
 
 	var err error
 	const scaledToZero = uint16(0)
@@ -465,7 +617,7 @@ func (r *ServingRuntimeReconciler) determineReplicasAndRequeueDuration(ctx conte
 	}
 
 	// check if the runtime has predictors before locking the mutex
-	hasPredictors, err := r.runtimeHasPredictors(ctx, rt, rtName)
+	hasPredictors, err := r.runtimeHasPredictors(ctx, rt, restProxyEnabled, rtName)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -536,8 +688,8 @@ func (r *ServingRuntimeReconciler) determineReplicas(rt *kserveapi.ServingRuntim
 }
 
 // runtimeHasPredictors returns true if the runtime supports an existing Predictor
-func (r *ServingRuntimeReconciler) runtimeHasPredictors(ctx context.Context, rt *kserveapi.ServingRuntimeSpec, rtName types.NamespacedName) (bool, error) {
-	restProxyEnabled := r.ConfigProvider.GetConfig().RESTProxy.Enabled
+func (r *ServingRuntimeReconciler) runtimeHasPredictors(ctx context.Context, rt *kserveapi.ServingRuntimeSpec,
+	restProxyEnabled bool, rtName types.NamespacedName) (bool, error) {
 	f := func(p *api.Predictor) bool {
 		return runtimeSupportsPredictor(rt, p, restProxyEnabled, rtName.Name)
 	}
@@ -574,6 +726,11 @@ func (r *ServingRuntimeReconciler) getRuntimesSupportingPredictor(ctx context.Co
 	}
 
 	restProxyEnabled := r.ConfigProvider.GetConfig().RESTProxy.Enabled
+	// whether the REST proxy is injected into the pods of the given runtime
+	hasRESTProxy := func(rt client.Object) bool {
+		enabled, _ := modelmesh.RuntimeRESTProxyEnabled(rt.GetAnnotations(), restProxyEnabled)
+		return enabled
+	}
 	srnns := make(map[string]struct{})
 
 	// list all cluster serving runtimes
@@ -584,7 +741,7 @@ func (r *ServingRuntimeReconciler) getRuntimesSupportingPredictor(ctx context.Co
 		}
 		for i := range csrs.Items {
 			crt := &csrs.Items[i]
-			if crt.Spec.IsMultiModelRuntime() && runtimeSupportsPredictor(&crt.Spec, p, restProxyEnabled, crt.Name) {
+			if crt.Spec.IsMultiModelRuntime() && runtimeSupportsPredictor(&crt.Spec, p, hasRESTProxy(crt), crt.Name) {
 				srnns[crt.Name] = struct{}{}
 			}
 		}
@@ -592,7 +749,7 @@ func (r *ServingRuntimeReconciler) getRuntimesSupportingPredictor(ctx context.Co
 
 	for i := range runtimes.Items {
 		rt := &runtimes.Items[i]
-		if rt.Spec.IsMultiModelRuntime() && runtimeSupportsPredictor(&rt.Spec, p, restProxyEnabled, rt.Name) {
+		if rt.Spec.IsMultiModelRuntime() && runtimeSupportsPredictor(&rt.Spec, p, hasRESTProxy(rt), rt.Name) {
 			srnns[rt.Name] = struct{}{}
 		}
 	}
@@ -643,7 +800,13 @@ func (r *ServingRuntimeReconciler) SetupWithManager(mgr ctrl.Manager,
 		builder = builder.Watches(&kserveapi.ClusterServingRuntime{},
 			handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
 				return r.clusterServingRuntimeRequests(o.(*kserveapi.ClusterServingRuntime))
-			}))
+			})).
+			// deployments owned by ClusterServingRuntimes aren't covered by Owns() above,
+			// watch them so that the per-namespace runtime status is kept up to date
+			Watches(&appsv1.Deployment{},
+				handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
+					return clusterServingRuntimeDeploymentRequests(o)
+				}))
 	}
 
 	if r.EnableSecretWatch {
@@ -651,6 +814,32 @@ func (r *ServingRuntimeReconciler) SetupWithManager(mgr ctrl.Manager,
 			handler.EnqueueRequestsFromMapFunc(func(_ context.Context, o client.Object) []reconcile.Request {
 				return r.storageSecretRequests(o.(*corev1.Secret))
 			}))
+
+		// watch the controller's KV store secret and reconcile all runtimes when it changes,
+		// to propagate rotated certificates and credentials to the user namespace secrets.
+		// A namespace's own KV store secret and cert-manager issued TLS secret only affect
+		// the runtimes in the namespace.
+		builder = builder.Watches(&corev1.Secret{},
+			handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, o client.Object) []reconcile.Request {
+				// the runtimes roll out cert-manager issued TLS certificates of their namespace
+				if tlsConfig := r.ConfigProvider.GetConfig().TLS; tlsConfig.CertManager.Enabled() && o.GetName() == tlsConfig.SecretName {
+					return r.requestsForRuntimes(o.GetNamespace(), nil)
+				}
+				if o.GetNamespace() != r.ControllerNamespace {
+					if r.ClusterScope && isNamespaceKVStoreSecret(ctx, r.Client, o) {
+						return r.requestsForRuntimes(o.GetNamespace(), nil)
+					}
+					return []reconcile.Request{}
+				}
+				if o.GetName() != r.ConfigProvider.GetConfig().GetEtcdSecretName() {
+					return []reconcile.Request{}
+				}
+				return r.requestsForRuntimes("", func(namespace string) bool {
+					mme, err := modelMeshEnabled2(context.TODO(), namespace,
+						r.ControllerNamespace, r.Client, r.ClusterScope)
+					return err != nil || mme // in case of error just reconcile anyhow
+				})
+			}))
 	}
 
 	if sourcePluginEvents != nil {
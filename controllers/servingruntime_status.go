@@ -0,0 +1,301 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// statusPatch replaces the whole status of the target object. A JSON patch is used rather
+// than a typed update because the KServe ServingRuntime types have an empty Status struct,
+// and an "add" operation is used because it also works when the status is not yet present.
+func statusPatch(status interface{}) (client.Patch, error) {
+	b, err := json.Marshal([]interface{}{map[string]interface{}{
+		"op":    "add",
+		"path":  "/status",
+		"value": status,
+	}})
+	if err != nil {
+		return nil, fmt.Errorf("error json-marshalling runtime status: %w", err)
+	}
+	return client.RawPatch(types.JSONPatchType, b), nil
+}
+
+// deployedRuntimeStatus derives the status of a runtime from its model-mesh Deployment
+func deployedRuntimeStatus(d *appsv1.Deployment) api.RuntimeDeploymentStatus {
+	var replicas int32 = 1
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	status := api.RuntimeDeploymentStatus{
+		Replicas:      replicas,
+		ReadyReplicas: d.Status.ReadyReplicas,
+	}
+	switch {
+	case replicas == 0:
+		status.State = api.RuntimeScaledToZero
+		status.Reason = api.RuntimeHasNoPredictors
+	case d.Status.ReadyReplicas >= replicas && d.Status.ObservedGeneration >= d.Generation:
+		status.State = api.RuntimeReady
+	default:
+		status.State = api.RuntimeProgressing
+	}
+	return status
+}
+
+func isRuntimeDeployed(state api.RuntimeState) bool {
+	return state == api.RuntimeReady || state == api.RuntimeProgressing || state == api.RuntimeScaledToZero
+}
+
+// updateRuntimeStatus writes the status of the runtime in the given namespace to the owning
+// ServingRuntime or ClusterServingRuntime. Status is cached so that unchanged statuses are not
+// re-written on every reconciliation.
+func (r *ServingRuntimeReconciler) updateRuntimeStatus(ctx context.Context, namespace string,
+	owner client.Object, status api.RuntimeDeploymentStatus) error {
+	r.runtimeStatusMutex.Lock()
+	defer r.runtimeStatusMutex.Unlock()
+
+	if _, ok := owner.(*kserveapi.ClusterServingRuntime); ok {
+		statuses, err := r.loadClusterRuntimeStatuses(ctx, owner.GetName())
+		if err != nil {
+			return err
+		}
+		if current, ok := statuses[namespace]; ok && current == status {
+			return nil
+		}
+		updated := make(map[string]api.RuntimeDeploymentStatus, len(statuses)+1)
+		for ns, s := range statuses {
+			updated[ns] = s
+		}
+		updated[namespace] = status
+		return r.patchClusterRuntimeStatus(ctx, owner, updated)
+	}
+
+	nn := types.NamespacedName{Name: owner.GetName(), Namespace: namespace}
+	if current, ok := r.runtimeStatuses[nn]; ok && current == status {
+		return nil
+	}
+	patch, err := statusPatch(api.ServingRuntimeStatus{RuntimeDeploymentStatus: status})
+	if err != nil {
+		return err
+	}
+	if err = r.patchStatus(ctx, owner, patch); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not update status of ServingRuntime %s: %w", nn, err)
+	}
+	if r.runtimeStatuses == nil {
+		r.runtimeStatuses = make(map[types.NamespacedName]api.RuntimeDeploymentStatus)
+	}
+	r.runtimeStatuses[nn] = status
+
+	// a ServingRuntime overrides a ClusterServingRuntime of the same name in its namespace
+	if r.EnableCSRWatch {
+		return r.removeClusterRuntimeStatus(ctx, nn.Name, namespace)
+	}
+	return nil
+}
+
+// removeClusterRuntimeStatus removes the given namespace from the status of a ClusterServingRuntime,
+// called with runtimeStatusMutex held
+func (r *ServingRuntimeReconciler) removeClusterRuntimeStatus(ctx context.Context, name, namespace string) error {
+	statuses, err := r.loadClusterRuntimeStatuses(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, ok := statuses[namespace]; !ok {
+		return nil
+	}
+	crt := &kserveapi.ClusterServingRuntime{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, crt); err != nil {
+		if errors.IsNotFound(err) {
+			delete(r.clusterRuntimeStatuses, name)
+			return nil
+		}
+		return err
+	}
+	updated := make(map[string]api.RuntimeDeploymentStatus, len(statuses))
+	for ns, s := range statuses {
+		if ns != namespace {
+			updated[ns] = s
+		}
+	}
+	return r.patchClusterRuntimeStatus(ctx, crt, updated)
+}
+
+// loadClusterRuntimeStatuses returns the cached per-namespace statuses of a ClusterServingRuntime.
+// They're read from its current status when not cached yet, e.g. after the controller restarts,
+// so that the namespaces which aren't reconciled again are kept by the next patch.
+// Called with runtimeStatusMutex held.
+func (r *ServingRuntimeReconciler) loadClusterRuntimeStatuses(ctx context.Context,
+	name string) (map[string]api.RuntimeDeploymentStatus, error) {
+	if statuses, ok := r.clusterRuntimeStatuses[name]; ok {
+		return statuses, nil
+	}
+	// the KServe type has no status fields, and unstructured objects are read from the API server
+	crt := &unstructured.Unstructured{}
+	crt.SetGroupVersionKind(kserveapi.SchemeGroupVersion.WithKind("ClusterServingRuntime"))
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, crt); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("could not get status of ClusterServingRuntime %s: %w", name, err)
+	}
+	crtStatus := api.ClusterServingRuntimeStatus{}
+	if status, ok := crt.Object["status"].(map[string]interface{}); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(status, &crtStatus); err != nil {
+			return nil, fmt.Errorf("could not parse status of ClusterServingRuntime %s: %w", name, err)
+		}
+	}
+	statuses := make(map[string]api.RuntimeDeploymentStatus, len(crtStatus.Namespaces))
+	for _, ns := range crtStatus.Namespaces {
+		statuses[ns.Namespace] = ns.RuntimeDeploymentStatus
+	}
+	if r.clusterRuntimeStatuses == nil {
+		r.clusterRuntimeStatuses = make(map[string]map[string]api.RuntimeDeploymentStatus)
+	}
+	r.clusterRuntimeStatuses[name] = statuses
+	return statuses, nil
+}
+
+// patchStatus patches the status of the ServingRuntime or ClusterServingRuntime within a span
+func (r *ServingRuntimeReconciler) patchStatus(ctx context.Context, runtime client.Object, patch client.Patch) error {
+	return tracing.Span(ctx, "servingruntime status update", func(ctx context.Context) error {
+		return r.Client.Status().Patch(ctx, runtime, patch)
+	}, attribute.String("namespace", runtime.GetNamespace()), attribute.String("name", runtime.GetName()))
+}
+
+// called with runtimeStatusMutex held
+func (r *ServingRuntimeReconciler) patchClusterRuntimeStatus(ctx context.Context, crt client.Object,
+	statuses map[string]api.RuntimeDeploymentStatus) error {
+	patch, err := statusPatch(clusterServingRuntimeStatus(statuses))
+	if err != nil {
+		return err
+	}
+	if err = r.patchStatus(ctx, crt, patch); err != nil {
+		if errors.IsNotFound(err) {
+			delete(r.clusterRuntimeStatuses, crt.GetName())
+			return nil
+		}
+		return fmt.Errorf("could not update status of ClusterServingRuntime %s: %w", crt.GetName(), err)
+	}
+	if r.clusterRuntimeStatuses == nil {
+		r.clusterRuntimeStatuses = make(map[string]map[string]api.RuntimeDeploymentStatus)
+	}
+	r.clusterRuntimeStatuses[crt.GetName()] = statuses
+	return nil
+}
+
+func clusterServingRuntimeStatus(statuses map[string]api.RuntimeDeploymentStatus) api.ClusterServingRuntimeStatus {
+	namespaces := make([]string, 0, len(statuses))
+	for ns := range statuses {
+		namespaces = append(namespaces, ns)
+	}
+	// sort so that the same set of statuses always produces the same patch
+	sort.Strings(namespaces)
+
+	crtStatus := api.ClusterServingRuntimeStatus{}
+	for _, ns := range namespaces {
+		s := statuses[ns]
+		if isRuntimeDeployed(s.State) {
+			crtStatus.DeployedNamespaces = append(crtStatus.DeployedNamespaces, ns)
+		}
+		crtStatus.Namespaces = append(crtStatus.Namespaces, api.NamespacedRuntimeStatus{
+			Namespace:               ns,
+			RuntimeDeploymentStatus: s,
+		})
+	}
+	return crtStatus
+}
+
+// forgetRuntimeStatus drops any cached status of a runtime which no longer exists
+func (r *ServingRuntimeReconciler) forgetRuntimeStatus(nn types.NamespacedName, clusterRuntimeDeleted bool) {
+	r.runtimeStatusMutex.Lock()
+	defer r.runtimeStatusMutex.Unlock()
+
+	delete(r.runtimeStatuses, nn)
+	if clusterRuntimeDeleted {
+		delete(r.clusterRuntimeStatuses, nn.Name)
+	}
+}
+
+// updateDisabledRuntimeStatus records why a runtime has no model-mesh Deployment in the given namespace
+func (r *ServingRuntimeReconciler) updateDisabledRuntimeStatus(ctx context.Context, namespace string,
+	owner client.Object, spec *kserveapi.ServingRuntimeSpec, mmEnabled bool) error {
+	_, isClusterRuntime := owner.(*kserveapi.ClusterServingRuntime)
+	status := api.RuntimeDeploymentStatus{State: api.RuntimeDisabled}
+	switch {
+	case !spec.IsMultiModelRuntime():
+		// runtimes not managed by model-mesh are left alone, unless they previously were
+		r.runtimeStatusMutex.Lock()
+		_, known := r.runtimeStatuses[types.NamespacedName{Name: owner.GetName(), Namespace: namespace}]
+		if isClusterRuntime {
+			statuses, err := r.loadClusterRuntimeStatuses(ctx, owner.GetName())
+			if err != nil {
+				r.runtimeStatusMutex.Unlock()
+				return err
+			}
+			_, known = statuses[namespace]
+		}
+		r.runtimeStatusMutex.Unlock()
+		if !known {
+			return nil
+		}
+		status.Reason = api.RuntimeNotMultiModel
+	case !mmEnabled:
+		if isClusterRuntime {
+			// only namespaces which are modelmesh-enabled are listed in the ClusterServingRuntime status
+			r.runtimeStatusMutex.Lock()
+			defer r.runtimeStatusMutex.Unlock()
+			return r.removeClusterRuntimeStatus(ctx, owner.GetName(), namespace)
+		}
+		status.Reason = api.RuntimeNamespaceNotEnabled
+	default:
+		status.Reason = api.RuntimeDisabledInSpec
+	}
+	return r.updateRuntimeStatus(ctx, namespace, owner, status)
+}
+
+// clusterServingRuntimeDeploymentRequests maps a Deployment controlled by a ClusterServingRuntime
+// to the request for that runtime in the Deployment's namespace
+func clusterServingRuntimeDeploymentRequests(o client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(o)
+	if owner == nil || owner.Kind != "ClusterServingRuntime" {
+		return []reconcile.Request{}
+	}
+	if gv, err := schema.ParseGroupVersion(owner.APIVersion); err != nil || gv.Group != kserveapi.SchemeGroupVersion.Group {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: owner.Name, Namespace: o.GetNamespace()}}}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+)
+
+func TestDeployedRuntimeStatus(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	for _, tt := range []struct {
+		name       string
+		deployment *appsv1.Deployment
+		expected   api.RuntimeDeploymentStatus
+	}{
+		{
+			name: "scaled to zero",
+			deployment: &appsv1.Deployment{
+				Spec: appsv1.DeploymentSpec{Replicas: replicas(0)},
+			},
+			expected: api.RuntimeDeploymentStatus{
+				State:  api.RuntimeScaledToZero,
+				Reason: api.RuntimeHasNoPredictors,
+			},
+		},
+		{
+			name: "progressing",
+			deployment: &appsv1.Deployment{
+				Spec:   appsv1.DeploymentSpec{Replicas: replicas(2)},
+				Status: appsv1.DeploymentStatus{ReadyReplicas: 1},
+			},
+			expected: api.RuntimeDeploymentStatus{
+				State:         api.RuntimeProgressing,
+				Replicas:      2,
+				ReadyReplicas: 1,
+			},
+		},
+		{
+			name: "ready",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: replicas(2)},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: 2, ObservedGeneration: 3},
+			},
+			expected: api.RuntimeDeploymentStatus{
+				State:         api.RuntimeReady,
+				Replicas:      2,
+				ReadyReplicas: 2,
+			},
+		},
+		{
+			name: "updated spec not yet observed",
+			deployment: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 4},
+				Spec:       appsv1.DeploymentSpec{Replicas: replicas(2)},
+				Status:     appsv1.DeploymentStatus{ReadyReplicas: 2, ObservedGeneration: 3},
+			},
+			expected: api.RuntimeDeploymentStatus{
+				State:         api.RuntimeProgressing,
+				Replicas:      2,
+				ReadyReplicas: 2,
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := deployedRuntimeStatus(tt.deployment); s != tt.expected {
+				t.Errorf("Expected %+v but got %+v", tt.expected, s)
+			}
+		})
+	}
+}
+
+func TestClusterServingRuntimeStatus(t *testing.T) {
+	s := clusterServingRuntimeStatus(map[string]api.RuntimeDeploymentStatus{
+		"ns-c": {State: api.RuntimeReady, Replicas: 1, ReadyReplicas: 1},
+		"ns-a": {State: api.RuntimeScaledToZero, Reason: api.RuntimeHasNoPredictors},
+		"ns-b": {State: api.RuntimeDisabled, Reason: api.RuntimeDisabledInSpec},
+	})
+
+	expectedDeployed := []string{"ns-a", "ns-c"}
+	if !reflect.DeepEqual(s.DeployedNamespaces, expectedDeployed) {
+		t.Errorf("Expected deployed namespaces %v but got %v", expectedDeployed, s.DeployedNamespaces)
+	}
+	var namespaces []string
+	for _, ns := range s.Namespaces {
+		namespaces = append(namespaces, ns.Namespace)
+	}
+	if expected := []string{"ns-a", "ns-b", "ns-c"}; !reflect.DeepEqual(namespaces, expected) {
+		t.Errorf("Expected namespaces %v but got %v", expected, namespaces)
+	}
+}
+
+func TestClusterServingRuntimeDeploymentRequests(t *testing.T) {
+	controller := true
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:      "modelmesh-serving-my-runtime",
+		Namespace: "my-ns",
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "serving.kserve.io/v1alpha1",
+			Kind:       "ClusterServingRuntime",
+			Name:       "my-runtime",
+			Controller: &controller,
+		}},
+	}}
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "my-runtime", Namespace: "my-ns"}}}
+	if requests := clusterServingRuntimeDeploymentRequests(d); !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected %v but got %v", expected, requests)
+	}
+
+	d.OwnerReferences[0].Kind = "ServingRuntime"
+	if requests := clusterServingRuntimeDeploymentRequests(d); len(requests) != 0 {
+		t.Errorf("Expected no requests for a ServingRuntime-owned deployment but got %v", requests)
+	}
+}
+
+func TestClusterServingRuntimeStatusAfterRestart(t *testing.T) {
+	s := runtime.NewScheme()
+	require.NoError(t, kserveapi.AddToScheme(s))
+	crt := &kserveapi.ClusterServingRuntime{ObjectMeta: metav1.ObjectMeta{Name: "my-runtime"}}
+
+	// the KServe type has no status fields, so the status held by the API server is faked
+	status := map[string]interface{}{
+		"deployedNamespaces": []interface{}{"ns-a"},
+		"namespaces": []interface{}{
+			map[string]interface{}{"namespace": "ns-a", "state": "Ready", "replicas": int64(1), "readyReplicas": int64(1)},
+			map[string]interface{}{"namespace": "ns-b", "state": "Disabled", "reason": "DisabledInSpec"},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(s).WithObjects(crt).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := c.Get(ctx, key, obj, opts...); err != nil {
+				return err
+			}
+			if u, ok := obj.(*unstructured.Unstructured); ok {
+				u.Object["status"] = status
+			}
+			return nil
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object,
+			patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			data, err := patch.Data(obj)
+			if err != nil {
+				return err
+			}
+			var ops []struct {
+				Value map[string]interface{} `json:"value"`
+			}
+			if err = json.Unmarshal(data, &ops); err != nil {
+				return err
+			}
+			status = ops[0].Value
+			return nil
+		},
+	}).Build()
+	r := &ServingRuntimeReconciler{Client: cl, EnableCSRWatch: true}
+	ctx := context.Background()
+
+	// the namespaces which aren't reconciled since the restart are kept
+	require.NoError(t, r.updateRuntimeStatus(ctx, "ns-c", crt, api.RuntimeDeploymentStatus{State: api.RuntimeProgressing, Replicas: 1}))
+	namespaces := func() []string {
+		var namespaces []string
+		for _, ns := range status["namespaces"].([]interface{}) {
+			namespaces = append(namespaces, ns.(map[string]interface{})["namespace"].(string))
+		}
+		return namespaces
+	}
+	assert.Equal(t, []string{"ns-a", "ns-b", "ns-c"}, namespaces())
+	assert.Equal(t, []interface{}{"ns-a", "ns-c"}, status["deployedNamespaces"])
+
+	// and removed ones stay removed, with the statuses cached after the first read
+	require.NoError(t, r.removeClusterRuntimeStatus(ctx, "my-runtime", "ns-a"))
+	assert.Equal(t, []string{"ns-b", "ns-c"}, namespaces())
+	assert.Equal(t, []interface{}{"ns-c"}, status["deployedNamespaces"])
+}
@@ -154,6 +154,8 @@ func checkName(name string, internalNames sets.Set[string], logStr string) error
 var internalContainerNames = sets.New[string](
 	modelmesh.ModelMeshContainerName,
 	modelmesh.RESTProxyContainerName,
+	modelmesh.OpenAIProxyContainerName,
+	modelmesh.RESTProxyListenerContainerName,
 	modelmesh.PullerContainerName,
 )
 
@@ -164,7 +166,7 @@ var internalOnlyVolumeMounts = sets.New[string](
 	modelmesh.SocketVolume,
 )
 
-var internalNamedPorts = sets.New[string]("grpc", "http", "prometheus")
+var internalNamedPorts = sets.New[string]("grpc", "http", "openai", "prometheus")
 
 var internalPorts = sets.New[int32](
 	8080, // is used for LiteLinks communication in Model Mesh
@@ -21,6 +21,9 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
 )
 
 func modelMeshEnabled(n *corev1.Namespace, controllerNamespace string) bool {
@@ -50,3 +53,45 @@ func modelMeshEnabled2(ctx context.Context, namespace, controllerNamespace strin
 	}
 	return modelMeshEnabled(n, controllerNamespace), nil
 }
+
+// ModelMeshNamespaceEnabled returns whether model-mesh is enabled in the given namespace,
+// it's not enabled in namespaces which don't exist
+func ModelMeshNamespaceEnabled(ctx context.Context, cl client.Client, namespace, controllerNamespace string) (bool, error) {
+	n := &corev1.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: namespace}, n); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return modelMeshEnabled(n, controllerNamespace), nil
+}
+
+// isNamespaceKVStoreSecret returns whether the secret is the one which its namespace
+// names as its own KV store secret
+func isNamespaceKVStoreSecret(ctx context.Context, cl client.Client, secret client.Object) bool {
+	n := &corev1.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: secret.GetNamespace()}, n); err != nil {
+		return false
+	}
+	return n.Annotations[constants.KVStoreSecretAnnotationKey] == secret.GetName()
+}
+
+// namespacePayloadProcessorDestinations returns the payload processor destinations defined by the
+// namespace, none if it doesn't exist. The error of invalid destinations is returned separately.
+func namespacePayloadProcessorDestinations(ctx context.Context, cl client.Client, namespace string, clusterScope bool) (
+	destinations map[string]modelmesh.PayloadProcessorDestination, invalidErr error, err error) {
+	if !clusterScope {
+		// Namespaces can only be read with cluster scope permissions
+		return nil, nil, nil
+	}
+	n := &corev1.Namespace{}
+	if err = cl.Get(ctx, types.NamespacedName{Name: namespace}, n); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	destinations, invalidErr = modelmesh.ParsePayloadProcessorDestinations(n.Annotations)
+	return destinations, invalidErr, nil
+}
@@ -53,9 +53,9 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/yaml"
 
-	inference "github.com/kserve/modelmesh-serving/fvt/generated"
 	tfsapi "github.com/kserve/modelmesh-serving/fvt/generated/tensorflow_serving/apis"
 	torchserveapi "github.com/kserve/modelmesh-serving/fvt/generated/torchserve/apis"
+	"github.com/kserve/modelmesh-serving/generated/inference"
 )
 
 const PredictorTimeout = time.Second * 120        // absolute time to wait for predictor to become ready
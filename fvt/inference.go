@@ -26,7 +26,7 @@ import (
 
 	"github.com/moverest/mnist"
 
-	inference "github.com/kserve/modelmesh-serving/fvt/generated"
+	"github.com/kserve/modelmesh-serving/generated/inference"
 
 	tfsframework "github.com/kserve/modelmesh-serving/fvt/generated/tensorflow/core/framework"
 	tfsapi "github.com/kserve/modelmesh-serving/fvt/generated/tensorflow_serving/apis"
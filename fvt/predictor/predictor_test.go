@@ -19,9 +19,9 @@ import (
 	"fmt"
 	"time"
 
-	inference "github.com/kserve/modelmesh-serving/fvt/generated"
 	tfsframework "github.com/kserve/modelmesh-serving/fvt/generated/tensorflow/core/framework"
 	tfsapi "github.com/kserve/modelmesh-serving/fvt/generated/tensorflow_serving/apis"
+	"github.com/kserve/modelmesh-serving/generated/inference"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
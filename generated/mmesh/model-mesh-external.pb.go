@@ -14,7 +14,7 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.26.0
+// 	protoc-gen-go v1.33.0
 // 	protoc        v3.17.3
 // source: mmesh/model-mesh-external.proto
 
@@ -158,6 +158,107 @@ func (VModelStatusInfo_VModelStatus) EnumDescriptor() ([]byte, []int) {
 	return file_mmesh_model_mesh_external_proto_rawDescGZIP(), []int{7, 0}
 }
 
+type ModelEvent_EventType int32
+
+const (
+	// the model or vmodel was registered
+	ModelEvent_ADDED ModelEvent_EventType = 0
+	// the model or vmodel was modified, for example a vmodel's
+	// target or active model changed
+	ModelEvent_UPDATED ModelEvent_EventType = 1
+	// the model or vmodel was deleted
+	ModelEvent_DELETED ModelEvent_EventType = 2
+)
+
+// Enum value maps for ModelEvent_EventType.
+var (
+	ModelEvent_EventType_name = map[int32]string{
+		0: "ADDED",
+		1: "UPDATED",
+		2: "DELETED",
+	}
+	ModelEvent_EventType_value = map[string]int32{
+		"ADDED":   0,
+		"UPDATED": 1,
+		"DELETED": 2,
+	}
+)
+
+func (x ModelEvent_EventType) Enum() *ModelEvent_EventType {
+	p := new(ModelEvent_EventType)
+	*p = x
+	return p
+}
+
+func (x ModelEvent_EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ModelEvent_EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_mmesh_model_mesh_external_proto_enumTypes[2].Descriptor()
+}
+
+func (ModelEvent_EventType) Type() protoreflect.EnumType {
+	return &file_mmesh_model_mesh_external_proto_enumTypes[2]
+}
+
+func (x ModelEvent_EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ModelEvent_EventType.Descriptor instead.
+func (ModelEvent_EventType) EnumDescriptor() ([]byte, []int) {
+	return file_mmesh_model_mesh_external_proto_rawDescGZIP(), []int{13, 0}
+}
+
+type ModelEvent_ModelKind int32
+
+const (
+	// a concrete model
+	ModelEvent_MODEL ModelEvent_ModelKind = 0
+	// a vmodel (alias)
+	ModelEvent_VMODEL ModelEvent_ModelKind = 1
+)
+
+// Enum value maps for ModelEvent_ModelKind.
+var (
+	ModelEvent_ModelKind_name = map[int32]string{
+		0: "MODEL",
+		1: "VMODEL",
+	}
+	ModelEvent_ModelKind_value = map[string]int32{
+		"MODEL":  0,
+		"VMODEL": 1,
+	}
+)
+
+func (x ModelEvent_ModelKind) Enum() *ModelEvent_ModelKind {
+	p := new(ModelEvent_ModelKind)
+	*p = x
+	return p
+}
+
+func (x ModelEvent_ModelKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ModelEvent_ModelKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_mmesh_model_mesh_external_proto_enumTypes[3].Descriptor()
+}
+
+func (ModelEvent_ModelKind) Type() protoreflect.EnumType {
+	return &file_mmesh_model_mesh_external_proto_enumTypes[3]
+}
+
+func (x ModelEvent_ModelKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ModelEvent_ModelKind.Descriptor instead.
+func (ModelEvent_ModelKind) EnumDescriptor() ([]byte, []int) {
+	return file_mmesh_model_mesh_external_proto_rawDescGZIP(), []int{13, 1}
+}
+
 type RegisterModelRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -964,6 +1065,146 @@ func (x *GetVModelStatusRequest) GetOwner() string {
 	return ""
 }
 
+type WatchModelEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// whether to send events for concrete models
+	Models bool `protobuf:"varint,1,opt,name=models,proto3" json:"models,omitempty"`
+	// whether to send events for vmodels
+	VModels bool `protobuf:"varint,2,opt,name=vModels,proto3" json:"vModels,omitempty"`
+	// if true, an ADDED event is first sent for each existing model
+	// and/or vmodel, followed by subsequent change events
+	IncludeExisting bool `protobuf:"varint,3,opt,name=includeExisting,proto3" json:"includeExisting,omitempty"`
+}
+
+func (x *WatchModelEventsRequest) Reset() {
+	*x = WatchModelEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mmesh_model_mesh_external_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchModelEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchModelEventsRequest) ProtoMessage() {}
+
+func (x *WatchModelEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mmesh_model_mesh_external_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchModelEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchModelEventsRequest) Descriptor() ([]byte, []int) {
+	return file_mmesh_model_mesh_external_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchModelEventsRequest) GetModels() bool {
+	if x != nil {
+		return x.Models
+	}
+	return false
+}
+
+func (x *WatchModelEventsRequest) GetVModels() bool {
+	if x != nil {
+		return x.VModels
+	}
+	return false
+}
+
+func (x *WatchModelEventsRequest) GetIncludeExisting() bool {
+	if x != nil {
+		return x.IncludeExisting
+	}
+	return false
+}
+
+type ModelEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type ModelEvent_EventType `protobuf:"varint,1,opt,name=type,proto3,enum=mmesh.ModelEvent_EventType" json:"type,omitempty"`
+	Kind ModelEvent_ModelKind `protobuf:"varint,2,opt,name=kind,proto3,enum=mmesh.ModelEvent_ModelKind" json:"kind,omitempty"`
+	// id of the model or vmodel
+	Id string `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	// the owner of the vmodel, if any; set only for VMODEL events
+	Owner string `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
+}
+
+func (x *ModelEvent) Reset() {
+	*x = ModelEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mmesh_model_mesh_external_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ModelEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModelEvent) ProtoMessage() {}
+
+func (x *ModelEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mmesh_model_mesh_external_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModelEvent.ProtoReflect.Descriptor instead.
+func (*ModelEvent) Descriptor() ([]byte, []int) {
+	return file_mmesh_model_mesh_external_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ModelEvent) GetType() ModelEvent_EventType {
+	if x != nil {
+		return x.Type
+	}
+	return ModelEvent_ADDED
+}
+
+func (x *ModelEvent) GetKind() ModelEvent_ModelKind {
+	if x != nil {
+		return x.Kind
+	}
+	return ModelEvent_MODEL
+}
+
+func (x *ModelEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ModelEvent) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
 type ModelStatusInfo_ModelCopyInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -980,7 +1221,7 @@ type ModelStatusInfo_ModelCopyInfo struct {
 func (x *ModelStatusInfo_ModelCopyInfo) Reset() {
 	*x = ModelStatusInfo_ModelCopyInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mmesh_model_mesh_external_proto_msgTypes[12]
+		mi := &file_mmesh_model_mesh_external_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -993,7 +1234,7 @@ func (x *ModelStatusInfo_ModelCopyInfo) String() string {
 func (*ModelStatusInfo_ModelCopyInfo) ProtoMessage() {}
 
 func (x *ModelStatusInfo_ModelCopyInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_mmesh_model_mesh_external_proto_msgTypes[12]
+	mi := &file_mmesh_model_mesh_external_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1152,43 +1393,70 @@ var file_mmesh_model_mesh_external_proto_rawDesc = []byte{
 	0x12, 0x1a, 0x0a, 0x08, 0x76, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x09, 0x52, 0x08, 0x76, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05,
 	0x6f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e,
-	0x65, 0x72, 0x32, 0x8b, 0x04, 0x0a, 0x09, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x4d, 0x65, 0x73, 0x68,
-	0x12, 0x46, 0x0a, 0x0d, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65,
-	0x6c, 0x12, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74,
-	0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16,
-	0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x0f, 0x75, 0x6e, 0x72, 0x65,
-	0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x1d, 0x2e, 0x6d, 0x6d,
-	0x65, 0x73, 0x68, 0x2e, 0x55, 0x6e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f,
-	0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x6d, 0x6d, 0x65,
-	0x73, 0x68, 0x2e, 0x55, 0x6e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f, 0x64,
-	0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x43, 0x0a, 0x0e,
-	0x67, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x17,
-	0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e,
-	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22,
-	0x00, 0x12, 0x44, 0x0a, 0x0c, 0x65, 0x6e, 0x73, 0x75, 0x72, 0x65, 0x4c, 0x6f, 0x61, 0x64, 0x65,
-	0x64, 0x12, 0x1a, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x45, 0x6e, 0x73, 0x75, 0x72, 0x65,
-	0x4c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e,
-	0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x09, 0x73, 0x65, 0x74, 0x56, 0x4d,
-	0x6f, 0x64, 0x65, 0x6c, 0x12, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x53, 0x65, 0x74,
-	0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e,
-	0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74,
-	0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x0c, 0x64, 0x65, 0x6c, 0x65,
-	0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x1a, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68,
-	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x44, 0x65, 0x6c,
-	0x65, 0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x00, 0x12, 0x4b, 0x0a, 0x0f, 0x67, 0x65, 0x74, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
-	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1d, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x47,
-	0x65, 0x74, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x56, 0x4d,
-	0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00,
-	0x42, 0x28, 0x0a, 0x1c, 0x63, 0x6f, 0x6d, 0x2e, 0x69, 0x62, 0x6d, 0x2e, 0x77, 0x61, 0x74, 0x73,
-	0x6f, 0x6e, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x61, 0x70, 0x69,
-	0x50, 0x01, 0x5a, 0x06, 0x2f, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x65, 0x72, 0x22, 0x75, 0x0a, 0x17, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x6d,
+	0x6f, 0x64, 0x65, 0x6c, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x76, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x12,
+	0x28, 0x0a, 0x0f, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x45, 0x78, 0x69, 0x73, 0x74, 0x69,
+	0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0f, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64,
+	0x65, 0x45, 0x78, 0x69, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x22, 0xea, 0x01, 0x0a, 0x0a, 0x4d, 0x6f,
+	0x64, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x2f, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x4d,
+	0x6f, 0x64, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54,
+	0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x2f, 0x0a, 0x04, 0x6b, 0x69, 0x6e,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e,
+	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
+	0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6f, 0x77,
+	0x6e, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6f, 0x77, 0x6e, 0x65, 0x72,
+	0x22, 0x30, 0x0a, 0x09, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x09, 0x0a,
+	0x05, 0x41, 0x44, 0x44, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x50, 0x44, 0x41,
+	0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x4c, 0x45, 0x54, 0x45, 0x44,
+	0x10, 0x02, 0x22, 0x22, 0x0a, 0x09, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x4b, 0x69, 0x6e, 0x64, 0x12,
+	0x09, 0x0a, 0x05, 0x4d, 0x4f, 0x44, 0x45, 0x4c, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x56, 0x4d,
+	0x4f, 0x44, 0x45, 0x4c, 0x10, 0x01, 0x32, 0xd6, 0x04, 0x0a, 0x09, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
+	0x4d, 0x65, 0x73, 0x68, 0x12, 0x46, 0x0a, 0x0d, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
+	0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x52, 0x65,
+	0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x16, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x52, 0x0a, 0x0f,
+	0x75, 0x6e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12,
+	0x1d, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x55, 0x6e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x65, 0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e,
+	0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x55, 0x6e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65,
+	0x72, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x43, 0x0a, 0x0e, 0x67, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x12, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x6d, 0x6d,
+	0x65, 0x73, 0x68, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49,
+	0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x44, 0x0a, 0x0c, 0x65, 0x6e, 0x73, 0x75, 0x72, 0x65, 0x4c,
+	0x6f, 0x61, 0x64, 0x65, 0x64, 0x12, 0x1a, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x45, 0x6e,
+	0x73, 0x75, 0x72, 0x65, 0x4c, 0x6f, 0x61, 0x64, 0x65, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x16, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x09, 0x73,
+	0x65, 0x74, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x53, 0x65, 0x74, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e, 0x66, 0x6f, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x0c,
+	0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x12, 0x1a, 0x2e, 0x6d,
+	0x6d, 0x65, 0x73, 0x68, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65,
+	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4b, 0x0a, 0x0f, 0x67, 0x65, 0x74, 0x56, 0x4d,
+	0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1d, 0x2e, 0x6d, 0x6d, 0x65,
+	0x73, 0x68, 0x2e, 0x47, 0x65, 0x74, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x6d, 0x6d, 0x65, 0x73,
+	0x68, 0x2e, 0x56, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x49, 0x6e,
+	0x66, 0x6f, 0x22, 0x00, 0x12, 0x49, 0x0a, 0x10, 0x77, 0x61, 0x74, 0x63, 0x68, 0x4d, 0x6f, 0x64,
+	0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1e, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x6d, 0x6d, 0x65, 0x73, 0x68,
+	0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x42,
+	0x28, 0x0a, 0x1c, 0x63, 0x6f, 0x6d, 0x2e, 0x69, 0x62, 0x6d, 0x2e, 0x77, 0x61, 0x74, 0x73, 0x6f,
+	0x6e, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x6d, 0x65, 0x73, 0x68, 0x2e, 0x61, 0x70, 0x69, 0x50,
+	0x01, 0x5a, 0x06, 0x2f, 0x6d, 0x6d, 0x65, 0x73, 0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
 }
 
 var (
@@ -1203,53 +1471,61 @@ func file_mmesh_model_mesh_external_proto_rawDescGZIP() []byte {
 	return file_mmesh_model_mesh_external_proto_rawDescData
 }
 
-var file_mmesh_model_mesh_external_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_mmesh_model_mesh_external_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_mmesh_model_mesh_external_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_mmesh_model_mesh_external_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_mmesh_model_mesh_external_proto_goTypes = []interface{}{
 	(ModelStatusInfo_ModelStatus)(0),      // 0: mmesh.ModelStatusInfo.ModelStatus
 	(VModelStatusInfo_VModelStatus)(0),    // 1: mmesh.VModelStatusInfo.VModelStatus
-	(*RegisterModelRequest)(nil),          // 2: mmesh.RegisterModelRequest
-	(*ModelInfo)(nil),                     // 3: mmesh.ModelInfo
-	(*ModelStatusInfo)(nil),               // 4: mmesh.ModelStatusInfo
-	(*UnregisterModelRequest)(nil),        // 5: mmesh.UnregisterModelRequest
-	(*UnregisterModelResponse)(nil),       // 6: mmesh.UnregisterModelResponse
-	(*GetStatusRequest)(nil),              // 7: mmesh.GetStatusRequest
-	(*EnsureLoadedRequest)(nil),           // 8: mmesh.EnsureLoadedRequest
-	(*VModelStatusInfo)(nil),              // 9: mmesh.VModelStatusInfo
-	(*DeleteVModelRequest)(nil),           // 10: mmesh.DeleteVModelRequest
-	(*DeleteVModelResponse)(nil),          // 11: mmesh.DeleteVModelResponse
-	(*SetVModelRequest)(nil),              // 12: mmesh.SetVModelRequest
-	(*GetVModelStatusRequest)(nil),        // 13: mmesh.GetVModelStatusRequest
-	(*ModelStatusInfo_ModelCopyInfo)(nil), // 14: mmesh.ModelStatusInfo.ModelCopyInfo
+	(ModelEvent_EventType)(0),             // 2: mmesh.ModelEvent.EventType
+	(ModelEvent_ModelKind)(0),             // 3: mmesh.ModelEvent.ModelKind
+	(*RegisterModelRequest)(nil),          // 4: mmesh.RegisterModelRequest
+	(*ModelInfo)(nil),                     // 5: mmesh.ModelInfo
+	(*ModelStatusInfo)(nil),               // 6: mmesh.ModelStatusInfo
+	(*UnregisterModelRequest)(nil),        // 7: mmesh.UnregisterModelRequest
+	(*UnregisterModelResponse)(nil),       // 8: mmesh.UnregisterModelResponse
+	(*GetStatusRequest)(nil),              // 9: mmesh.GetStatusRequest
+	(*EnsureLoadedRequest)(nil),           // 10: mmesh.EnsureLoadedRequest
+	(*VModelStatusInfo)(nil),              // 11: mmesh.VModelStatusInfo
+	(*DeleteVModelRequest)(nil),           // 12: mmesh.DeleteVModelRequest
+	(*DeleteVModelResponse)(nil),          // 13: mmesh.DeleteVModelResponse
+	(*SetVModelRequest)(nil),              // 14: mmesh.SetVModelRequest
+	(*GetVModelStatusRequest)(nil),        // 15: mmesh.GetVModelStatusRequest
+	(*WatchModelEventsRequest)(nil),       // 16: mmesh.WatchModelEventsRequest
+	(*ModelEvent)(nil),                    // 17: mmesh.ModelEvent
+	(*ModelStatusInfo_ModelCopyInfo)(nil), // 18: mmesh.ModelStatusInfo.ModelCopyInfo
 }
 var file_mmesh_model_mesh_external_proto_depIdxs = []int32{
-	3,  // 0: mmesh.RegisterModelRequest.modelInfo:type_name -> mmesh.ModelInfo
+	5,  // 0: mmesh.RegisterModelRequest.modelInfo:type_name -> mmesh.ModelInfo
 	0,  // 1: mmesh.ModelStatusInfo.status:type_name -> mmesh.ModelStatusInfo.ModelStatus
-	14, // 2: mmesh.ModelStatusInfo.modelCopyInfos:type_name -> mmesh.ModelStatusInfo.ModelCopyInfo
+	18, // 2: mmesh.ModelStatusInfo.modelCopyInfos:type_name -> mmesh.ModelStatusInfo.ModelCopyInfo
 	1,  // 3: mmesh.VModelStatusInfo.status:type_name -> mmesh.VModelStatusInfo.VModelStatus
-	4,  // 4: mmesh.VModelStatusInfo.activeModelStatus:type_name -> mmesh.ModelStatusInfo
-	4,  // 5: mmesh.VModelStatusInfo.targetModelStatus:type_name -> mmesh.ModelStatusInfo
-	3,  // 6: mmesh.SetVModelRequest.modelInfo:type_name -> mmesh.ModelInfo
-	0,  // 7: mmesh.ModelStatusInfo.ModelCopyInfo.copyStatus:type_name -> mmesh.ModelStatusInfo.ModelStatus
-	2,  // 8: mmesh.ModelMesh.registerModel:input_type -> mmesh.RegisterModelRequest
-	5,  // 9: mmesh.ModelMesh.unregisterModel:input_type -> mmesh.UnregisterModelRequest
-	7,  // 10: mmesh.ModelMesh.getModelStatus:input_type -> mmesh.GetStatusRequest
-	8,  // 11: mmesh.ModelMesh.ensureLoaded:input_type -> mmesh.EnsureLoadedRequest
-	12, // 12: mmesh.ModelMesh.setVModel:input_type -> mmesh.SetVModelRequest
-	10, // 13: mmesh.ModelMesh.deleteVModel:input_type -> mmesh.DeleteVModelRequest
-	13, // 14: mmesh.ModelMesh.getVModelStatus:input_type -> mmesh.GetVModelStatusRequest
-	4,  // 15: mmesh.ModelMesh.registerModel:output_type -> mmesh.ModelStatusInfo
-	6,  // 16: mmesh.ModelMesh.unregisterModel:output_type -> mmesh.UnregisterModelResponse
-	4,  // 17: mmesh.ModelMesh.getModelStatus:output_type -> mmesh.ModelStatusInfo
-	4,  // 18: mmesh.ModelMesh.ensureLoaded:output_type -> mmesh.ModelStatusInfo
-	9,  // 19: mmesh.ModelMesh.setVModel:output_type -> mmesh.VModelStatusInfo
-	11, // 20: mmesh.ModelMesh.deleteVModel:output_type -> mmesh.DeleteVModelResponse
-	9,  // 21: mmesh.ModelMesh.getVModelStatus:output_type -> mmesh.VModelStatusInfo
-	15, // [15:22] is the sub-list for method output_type
-	8,  // [8:15] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	6,  // 4: mmesh.VModelStatusInfo.activeModelStatus:type_name -> mmesh.ModelStatusInfo
+	6,  // 5: mmesh.VModelStatusInfo.targetModelStatus:type_name -> mmesh.ModelStatusInfo
+	5,  // 6: mmesh.SetVModelRequest.modelInfo:type_name -> mmesh.ModelInfo
+	2,  // 7: mmesh.ModelEvent.type:type_name -> mmesh.ModelEvent.EventType
+	3,  // 8: mmesh.ModelEvent.kind:type_name -> mmesh.ModelEvent.ModelKind
+	0,  // 9: mmesh.ModelStatusInfo.ModelCopyInfo.copyStatus:type_name -> mmesh.ModelStatusInfo.ModelStatus
+	4,  // 10: mmesh.ModelMesh.registerModel:input_type -> mmesh.RegisterModelRequest
+	7,  // 11: mmesh.ModelMesh.unregisterModel:input_type -> mmesh.UnregisterModelRequest
+	9,  // 12: mmesh.ModelMesh.getModelStatus:input_type -> mmesh.GetStatusRequest
+	10, // 13: mmesh.ModelMesh.ensureLoaded:input_type -> mmesh.EnsureLoadedRequest
+	14, // 14: mmesh.ModelMesh.setVModel:input_type -> mmesh.SetVModelRequest
+	12, // 15: mmesh.ModelMesh.deleteVModel:input_type -> mmesh.DeleteVModelRequest
+	15, // 16: mmesh.ModelMesh.getVModelStatus:input_type -> mmesh.GetVModelStatusRequest
+	16, // 17: mmesh.ModelMesh.watchModelEvents:input_type -> mmesh.WatchModelEventsRequest
+	6,  // 18: mmesh.ModelMesh.registerModel:output_type -> mmesh.ModelStatusInfo
+	8,  // 19: mmesh.ModelMesh.unregisterModel:output_type -> mmesh.UnregisterModelResponse
+	6,  // 20: mmesh.ModelMesh.getModelStatus:output_type -> mmesh.ModelStatusInfo
+	6,  // 21: mmesh.ModelMesh.ensureLoaded:output_type -> mmesh.ModelStatusInfo
+	11, // 22: mmesh.ModelMesh.setVModel:output_type -> mmesh.VModelStatusInfo
+	13, // 23: mmesh.ModelMesh.deleteVModel:output_type -> mmesh.DeleteVModelResponse
+	11, // 24: mmesh.ModelMesh.getVModelStatus:output_type -> mmesh.VModelStatusInfo
+	17, // 25: mmesh.ModelMesh.watchModelEvents:output_type -> mmesh.ModelEvent
+	18, // [18:26] is the sub-list for method output_type
+	10, // [10:18] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_mmesh_model_mesh_external_proto_init() }
@@ -1403,6 +1679,30 @@ func file_mmesh_model_mesh_external_proto_init() {
 			}
 		}
 		file_mmesh_model_mesh_external_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchModelEventsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mmesh_model_mesh_external_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ModelEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mmesh_model_mesh_external_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*ModelStatusInfo_ModelCopyInfo); i {
 			case 0:
 				return &v.state
@@ -1420,8 +1720,8 @@ func file_mmesh_model_mesh_external_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_mmesh_model_mesh_external_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   13,
+			NumEnums:      4,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -38,6 +38,11 @@ type ModelMeshClient interface {
 	// If the vmodel is not found, the returned VModelStatusInfo will have empty
 	// active and target model ids and an active model status of NOT_FOUND
 	GetVModelStatus(ctx context.Context, in *GetVModelStatusRequest, opts ...grpc.CallOption) (*VModelStatusInfo, error)
+	// Streams events for changes to the models and/or vmodels registered in
+	// this model-mesh cluster. The stream remains open until cancelled by the
+	// client, which should re-establish it with includeExisting set if it
+	// is closed by the server
+	WatchModelEvents(ctx context.Context, in *WatchModelEventsRequest, opts ...grpc.CallOption) (ModelMesh_WatchModelEventsClient, error)
 }
 
 type modelMeshClient struct {
@@ -111,6 +116,38 @@ func (c *modelMeshClient) GetVModelStatus(ctx context.Context, in *GetVModelStat
 	return out, nil
 }
 
+func (c *modelMeshClient) WatchModelEvents(ctx context.Context, in *WatchModelEventsRequest, opts ...grpc.CallOption) (ModelMesh_WatchModelEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ModelMesh_ServiceDesc.Streams[0], "/mmesh.ModelMesh/watchModelEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &modelMeshWatchModelEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ModelMesh_WatchModelEventsClient interface {
+	Recv() (*ModelEvent, error)
+	grpc.ClientStream
+}
+
+type modelMeshWatchModelEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *modelMeshWatchModelEventsClient) Recv() (*ModelEvent, error) {
+	m := new(ModelEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ModelMeshServer is the server API for ModelMesh service.
 // All implementations must embed UnimplementedModelMeshServer
 // for forward compatibility
@@ -135,6 +172,11 @@ type ModelMeshServer interface {
 	// If the vmodel is not found, the returned VModelStatusInfo will have empty
 	// active and target model ids and an active model status of NOT_FOUND
 	GetVModelStatus(context.Context, *GetVModelStatusRequest) (*VModelStatusInfo, error)
+	// Streams events for changes to the models and/or vmodels registered in
+	// this model-mesh cluster. The stream remains open until cancelled by the
+	// client, which should re-establish it with includeExisting set if it
+	// is closed by the server
+	WatchModelEvents(*WatchModelEventsRequest, ModelMesh_WatchModelEventsServer) error
 	mustEmbedUnimplementedModelMeshServer()
 }
 
@@ -163,6 +205,9 @@ func (UnimplementedModelMeshServer) DeleteVModel(context.Context, *DeleteVModelR
 func (UnimplementedModelMeshServer) GetVModelStatus(context.Context, *GetVModelStatusRequest) (*VModelStatusInfo, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetVModelStatus not implemented")
 }
+func (UnimplementedModelMeshServer) WatchModelEvents(*WatchModelEventsRequest, ModelMesh_WatchModelEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchModelEvents not implemented")
+}
 func (UnimplementedModelMeshServer) mustEmbedUnimplementedModelMeshServer() {}
 
 // UnsafeModelMeshServer may be embedded to opt out of forward compatibility for this service.
@@ -302,6 +347,27 @@ func _ModelMesh_GetVModelStatus_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ModelMesh_WatchModelEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchModelEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ModelMeshServer).WatchModelEvents(m, &modelMeshWatchModelEventsServer{stream})
+}
+
+type ModelMesh_WatchModelEventsServer interface {
+	Send(*ModelEvent) error
+	grpc.ServerStream
+}
+
+type modelMeshWatchModelEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *modelMeshWatchModelEventsServer) Send(m *ModelEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // ModelMesh_ServiceDesc is the grpc.ServiceDesc for ModelMesh service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -338,6 +404,12 @@ var ModelMesh_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ModelMesh_GetVModelStatus_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "watchModelEvents",
+			Handler:       _ModelMesh_WatchModelEvents_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "mmesh/model-mesh-external.proto",
 }
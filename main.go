@@ -16,17 +16,21 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
 	"github.com/kserve/kserve/pkg/apis/serving/v1beta1"
 	config2 "github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/openai"
 	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
 	corev1 "k8s.io/api/core/v1"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,6 +42,7 @@ import (
 	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -48,10 +53,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	monitoringv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	gatewayv1 "sigs.k8s.io/gateway-api/apis/v1"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
 
 	servingv1alpha1 "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
 	"github.com/kserve/modelmesh-serving/controllers"
@@ -86,6 +94,7 @@ const (
 	NamespaceScopeEnvVar              = "NAMESPACE_SCOPE"
 	TrueString                        = "true"
 	FalseString                       = "false"
+	tracingShutdownTimeout            = 5 * time.Second
 )
 
 func init() {
@@ -100,6 +109,8 @@ func init() {
 	_ = v1beta1.AddToScheme(scheme)
 	_ = v1alpha1.AddToScheme(scheme)
 	_ = monitoringv1.AddToScheme(scheme)
+	_ = gatewayv1.AddToScheme(scheme)
+	_ = gatewayv1alpha2.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -107,6 +118,31 @@ func main() {
 	_, devLoggingSetting := os.LookupEnv(DevModeLoggingEnvVar)
 	ctrl.SetLogger(zap.New(zap.UseDevMode(devLoggingSetting)))
 
+	// the OpenAI API proxy runs in the runtime Pods in place of the controller
+	if len(os.Args) > 1 && os.Args[1] == "openai-proxy" {
+		opts, err := openai.OptionsFromEnv(openai.OpenAIProxyEnvPrefix)
+		if err == nil {
+			err = openai.Run(ctrl.SetupSignalHandler(), opts, ctrl.Log.WithName("OpenAIProxy"))
+		}
+		if err != nil {
+			setupLog.Error(err, "OpenAI proxy failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	// so does the listener in front of the REST proxy
+	if len(os.Args) > 1 && os.Args[1] == "rest-proxy-listener" {
+		opts, err := openai.OptionsFromEnv(openai.RESTListenerEnvPrefix)
+		if err == nil {
+			err = openai.RunRESTListener(ctrl.SetupSignalHandler(), opts, ctrl.Log.WithName("RESTProxyListener"))
+		}
+		if err != nil {
+			setupLog.Error(err, "REST proxy listener failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// ----- mmesh related envar setup -----
 	controllerNamespace := os.Getenv(ControllerNamespaceEnvVar)
 	if controllerNamespace == "" {
@@ -155,6 +191,25 @@ func main() {
 	}
 	conf := cp.GetConfig()
 
+	// backup and restore of the model-mesh registries run in place of the controller
+	if len(os.Args) > 1 && (os.Args[1] == "backup" || os.Args[1] == "restore") {
+		if err = runRegistryCommand(os.Args[1], os.Args[2:], cl, conf); err != nil {
+			setupLog.Error(err, "Registry "+os.Args[1]+" failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), conf.Tracing)
+	if err != nil {
+		setupLog.Error(err, "Unable to set up tracing")
+		os.Exit(1)
+	}
+	if conf.Tracing.Endpoint != "" {
+		setupLog.Info("Exporting traces", "endpoint", conf.Tracing.Endpoint,
+			"samplingRatio", conf.Tracing.SamplingRatio)
+	}
+
 	setupLog.Info("Using adapter", "image", conf.StorageHelperImage.TaggedImage())
 	setupLog.Info("Using modelmesh", "image", conf.ModelMeshImage.TaggedImage())
 
@@ -286,12 +341,71 @@ func main() {
 
 	mmServiceMap := &controllers.MMServiceMap{}
 
-	modelEventStream, err := mmesh.NewModelEventStream(ctrl.Log.WithName("ModelMeshEventStream"),
-		mgr.GetClient(), ControllerNamespace)
+	// the event source type is only read at startup
+	modelEventStream, err := mmesh.NewModelEventSource(conf.ModelEventSource,
+		ctrl.Log.WithName("ModelMeshEventStream"), mgr.GetClient(), ControllerNamespace, mmServiceMap.Get)
 	if err != nil {
 		setupLog.Error(err, "Failed to Initialize Model Event Stream, exit")
 		os.Exit(1)
 	}
+	if err = mgr.Add(modelEventStream); err != nil {
+		setupLog.Error(err, "unable to add Model Event Stream to the manager")
+		os.Exit(1)
+	}
+
+	if clusterScopeMode {
+		// user namespaces only have their own KV store data in cluster scope mode
+		kvStoreCleanup := mmesh.NewKVStoreCleanup(ctrl.Log.WithName("KVStoreCleanup"), mgr.GetClient(), cp, ControllerNamespace,
+			func(ctx context.Context, namespace string) (bool, error) {
+				return controllers.ModelMeshNamespaceEnabled(ctx, mgr.GetClient(), namespace, ControllerNamespace)
+			})
+		if err = mgr.Add(kvStoreCleanup); err != nil {
+			setupLog.Error(err, "unable to add KV store cleanup to the manager")
+			os.Exit(1)
+		}
+	}
+
+	checkSecretVar := func(envVar string, resourceName string, resourceObject client.Object) bool {
+		// default is true
+		envVarVal, _ := os.LookupEnv(envVar)
+		if envVarVal != FalseString {
+			err = cl.Get(context.Background(), client.ObjectKey{Name: "storage-config", Namespace: ControllerNamespace}, resourceObject)
+			if err == nil || errors.IsNotFound(err) {
+				setupLog.Info(fmt.Sprintf("Reconciliation of %s is enabled", resourceName))
+				return true
+			} else if envVarVal == TrueString {
+				// If env var is explicitly true, require that specified CRD is present
+				setupLog.Error(err, fmt.Sprintf("Unable to access %s resource", resourceName))
+				os.Exit(1)
+			} else {
+				setupLog.Error(err, fmt.Sprintf("%s CRD not accessible, will not reconcile", resourceName))
+			}
+		}
+		return false
+	}
+	enableSecretWatch := checkSecretVar(EnableSecretEnvVar, "Secret", &corev1.Secret{})
+
+	checkCSRVar := func(envVar string, resourceName string, resourceObject client.Object) bool {
+		// default is true
+		envVarVal, _ := os.LookupEnv(envVar)
+		if envVarVal != FalseString {
+			err = cl.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: ControllerNamespace}, resourceObject)
+			if err == nil || errors.IsNotFound(err) {
+				setupLog.Info(fmt.Sprintf("Reconciliation of %s is enabled", resourceName))
+				return true
+			} else if envVarVal == TrueString {
+				// If env var is explicitly true, require that specified CRD is present
+				setupLog.Error(err, fmt.Sprintf("Unable to access %s Custom Resource", resourceName))
+				os.Exit(1)
+			} else if meta.IsNoMatchError(err) {
+				setupLog.Info(fmt.Sprintf("%s CRD not found, will not reconcile", resourceName))
+			} else {
+				setupLog.Error(err, fmt.Sprintf("%s CRD not accessible, will not reconcile", resourceName))
+			}
+		}
+		return false
+	}
+	enableCSRWatch := checkCSRVar(EnableClusterServingRuntimeEnvVar, "ClusterServingRuntime", &v1alpha1.ClusterServingRuntime{})
 
 	// Check if the ServiceMonitor CRD exists in the cluster
 	sm := &monitoringv1.ServiceMonitor{}
@@ -305,6 +419,22 @@ func main() {
 		setupLog.Error(err, "Unable to access Service Monitor CRD", "CRDName", serviceMonitorCRDName)
 	}
 
+	// Check which of the Gateway API route CRDs exist in the cluster, GRPCRoute is
+	// only in the experimental channel before Gateway API v1.1
+	routeCRDExists := func(gvk schema.GroupVersionKind) bool {
+		_, err := cl.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if meta.IsNoMatchError(err) {
+			setupLog.Info("Gateway API CRD is not found in the cluster", "kind", gvk.Kind)
+			return false
+		} else if err != nil {
+			setupLog.Error(err, "Unable to access Gateway API CRD", "kind", gvk.Kind)
+			return false
+		}
+		return true
+	}
+	httpRouteCRDExists := routeCRDExists(gatewayv1.SchemeGroupVersion.WithKind("HTTPRoute"))
+	grpcRouteCRDExists := routeCRDExists(gatewayv1alpha2.SchemeGroupVersion.WithKind("GRPCRoute"))
+
 	if err = (&controllers.ServiceReconciler{
 		Client:                  mgr.GetClient(),
 		Log:                     ctrl.Log.WithName("controllers").WithName("Service"),
@@ -316,6 +446,11 @@ func main() {
 		ConfigProvider:          cp,
 		ConfigMapName:           types.NamespacedName{Namespace: ControllerNamespace, Name: UserConfigMapName},
 		ServiceMonitorCRDExists: serviceMonitorCRDExists,
+		HTTPRouteCRDExists:      httpRouteCRDExists,
+		GRPCRouteCRDExists:      grpcRouteCRDExists,
+		Recorder:                mgr.GetEventRecorderFor("modelmesh-controller"),
+		EnableSecretWatch:       enableSecretWatch,
+		EnableCSRWatch:          enableCSRWatch,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", "Service")
 		os.Exit(1)
@@ -356,48 +491,6 @@ func main() {
 	enableIsvcWatch := checkEnvVar(EnableInferenceServiceEnvVar, "InferenceService", &v1beta1.InferenceService{},
 		controllers.InferenceServiceCRSourceId, predictor_source.InferenceServiceRegistry{Client: mgr.GetClient()})
 
-	checkCSRVar := func(envVar string, resourceName string, resourceObject client.Object) bool {
-		// default is true
-		envVarVal, _ := os.LookupEnv(envVar)
-		if envVarVal != FalseString {
-			err = cl.Get(context.Background(), client.ObjectKey{Name: "foo", Namespace: ControllerNamespace}, resourceObject)
-			if err == nil || errors.IsNotFound(err) {
-				setupLog.Info(fmt.Sprintf("Reconciliation of %s is enabled", resourceName))
-				return true
-			} else if envVarVal == TrueString {
-				// If env var is explicitly true, require that specified CRD is present
-				setupLog.Error(err, fmt.Sprintf("Unable to access %s Custom Resource", resourceName))
-				os.Exit(1)
-			} else if meta.IsNoMatchError(err) {
-				setupLog.Info(fmt.Sprintf("%s CRD not found, will not reconcile", resourceName))
-			} else {
-				setupLog.Error(err, fmt.Sprintf("%s CRD not accessible, will not reconcile", resourceName))
-			}
-		}
-		return false
-	}
-	enableCSRWatch := checkCSRVar(EnableClusterServingRuntimeEnvVar, "ClusterServingRuntime", &v1alpha1.ClusterServingRuntime{})
-
-	checkSecretVar := func(envVar string, resourceName string, resourceObject client.Object) bool {
-		// default is true
-		envVarVal, _ := os.LookupEnv(envVar)
-		if envVarVal != FalseString {
-			err = cl.Get(context.Background(), client.ObjectKey{Name: "storage-config", Namespace: ControllerNamespace}, resourceObject)
-			if err == nil || errors.IsNotFound(err) {
-				setupLog.Info(fmt.Sprintf("Reconciliation of %s is enabled", resourceName))
-				return true
-			} else if envVarVal == TrueString {
-				// If env var is explicitly true, require that specified CRD is present
-				setupLog.Error(err, fmt.Sprintf("Unable to access %s resource", resourceName))
-				os.Exit(1)
-			} else {
-				setupLog.Error(err, fmt.Sprintf("%s CRD not accessible, will not reconcile", resourceName))
-			}
-		}
-		return false
-	}
-	enableSecretWatch := checkSecretVar(EnableSecretEnvVar, "Secret", &corev1.Secret{})
-
 	var predictorControllerEvents, runtimeControllerEvents chan event.GenericEvent
 	if len(sources) != 0 {
 		predictorControllerEvents = make(chan event.GenericEvent, 256)
@@ -445,11 +538,13 @@ func main() {
 		Client:         mgr.GetClient(),
 		Log:            ctrl.Log.WithName("controllers").WithName("Predictor"),
 		MMServices:     mmServiceMap,
+		ClusterScope:   clusterScopeMode,
 		RegistryLookup: registryMap,
 	}).SetupWithManager(mgr, modelEventStream, enableIsvcWatch, predictorControllerEvents); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Predictor")
 		os.Exit(1)
 	}
+	metrics.Registry.MustRegister(controllers.NewPredictorCollector(registryMap))
 
 	if err = (&controllers.ServingRuntimeReconciler{
 		Client:              mgr.GetClient(),
@@ -483,8 +578,88 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	// export the spans which are still buffered
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if terr := shutdownTracing(shutdownCtx); terr != nil {
+		setupLog.Error(terr, "Error flushing traces")
+	}
+	cancel()
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// runRegistryCommand backs up or restores the model-mesh model and vmodel registries in the KV store
+func runRegistryCommand(command string, args []string, cl client.Client, conf *config2.Config) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	namespaces := fs.String("namespaces", ControllerNamespace, "Comma-separated list of the namespaces to "+command)
+	file := fs.String("file", "", "Backup file to write or read, stdout or stdin if not set")
+	dryRun := fs.Bool("dry-run", false, "Report the records that would be restored without writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	// each namespace's registries are in the KV store it uses, its own or the controller's
+	kvStores := mmesh.NewNamespaceKVStores(ctrl.Log.WithName("KVStore"), cl, ControllerNamespace,
+		conf.GetEtcdSecretName())
+	defer kvStores.Close()
+
+	if command == "backup" {
+		backup, err := mmesh.BackupRegistries(ctx, kvStores, conf.InferenceServiceName, strings.Split(*namespaces, ","))
+		if err != nil {
+			return err
+		}
+		out := os.Stdout
+		if *file != "" {
+			if out, err = os.Create(*file); err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(backup)
+	}
+
+	in := os.Stdin
+	var err error
+	if *file != "" {
+		if in, err = os.Open(*file); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	backup := &mmesh.RegistryBackup{}
+	if err = json.NewDecoder(in).Decode(backup); err != nil {
+		return fmt.Errorf("failed to read the backup: %w", err)
+	}
+	// only restore the namespaces selected, when the flag is set
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "namespaces" {
+			selected := map[string]bool{}
+			for _, ns := range strings.Split(*namespaces, ",") {
+				selected[ns] = true
+			}
+			var filtered []mmesh.NamespaceRegistries
+			for _, nr := range backup.Namespaces {
+				if selected[nr.Namespace] {
+					filtered = append(filtered, nr)
+				}
+			}
+			backup.Namespaces = filtered
+		}
+	})
+	registries := map[string]predictor_source.PredictorRegistry{
+		controllers.PredictorCRSourceId:        predictor_source.PredictorCRRegistry{Client: cl},
+		controllers.InferenceServiceCRSourceId: predictor_source.InferenceServiceRegistry{Client: cl},
+	}
+	results, err := mmesh.RestoreRegistries(ctx, kvStores, backup, registries, *dryRun)
+	for _, r := range results {
+		setupLog.Info("Registry record", "namespace", r.Namespace, "registry", r.Registry, "id", r.Id,
+			"restored", r.Restored, "reason", r.Reason, "dryRun", *dryRun)
+	}
+	return err
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	kserveapi "github.com/kserve/kserve/pkg/apis/serving/v1alpha1"
@@ -59,6 +60,11 @@ type Config struct {
 	EtcdSecretName    string // DEPRECATED - should be removed in the future
 	ModelMeshEndpoint string // For dev use only
 	AllowAnyPVC       bool
+	// one of etcd or grpc
+	ModelEventSource string
+	KVStoreCleanup   KVStoreCleanupConfig
+	ModelMeshClient  ModelMeshClientConfig
+	Tracing          TracingConfig
 
 	// Service config
 	InferenceServiceName    string
@@ -75,15 +81,21 @@ type Config struct {
 	StorageHelperResources ResourceRequirements
 	PodsPerRuntime         uint16
 	StorageSecretName      string
-	EnableAccessLogging    bool
+	EnableAccessLogging    bool // DEPRECATED - use AccessLog.Enabled
+	AccessLog              AccessLogConfig
+	ModelMeshLogFormat     string
 	BuiltInServerTypes     []string
 	PayloadProcessors      []string
+	ModelCache             ModelCacheConfig
 
 	ServiceAccountName string
 
 	Metrics     PrometheusConfig
 	ScaleToZero ScaleToZeroConfig
 
+	ExternalRoute ExternalRouteConfig
+	NetworkPolicy NetworkPolicyConfig
+
 	RuntimePodLabels      map[string]string
 	RuntimePodAnnotations map[string]string
 
@@ -125,11 +137,259 @@ type ScaleToZeroConfig struct {
 	GracePeriodSeconds uint16
 }
 
+// ModelMeshClientConfig configures the controller's gRPC client of the model-mesh
+// management API in each namespace
+type ModelMeshClientConfig struct {
+	// timeout of each management RPC
+	RequestTimeout time.Duration
+	// max attempts of the idempotent RPCs (getVModelStatus and ensureLoaded) which fail
+	// because model-mesh is unavailable, from 1 (no retries) to 5
+	MaxAttempts int
+	// interval of keepalive pings while there are RPCs in flight, disabled if 0. The
+	// model-mesh server rejects pings more frequent than every 5 minutes by default.
+	KeepaliveTime time.Duration
+	// how long to wait for a keepalive ping response before closing the connection
+	KeepaliveTimeout time.Duration
+	CircuitBreaker   CircuitBreakerConfig
+}
+
+// CircuitBreakerConfig configures the per-namespace circuit breaker which fails
+// management RPCs and defers Predictor reconciles while model-mesh is unavailable
+type CircuitBreakerConfig struct {
+	// consecutive unavailable or timed out RPCs which open the circuit, disabled if 0
+	FailureThreshold int
+	// how long the circuit stays open before another RPC is attempted
+	OpenDuration time.Duration
+}
+
+func (mcc ModelMeshClientConfig) validate() error {
+	if mcc.RequestTimeout <= 0 {
+		return fmt.Errorf("'RequestTimeout' must be positive")
+	}
+	if mcc.MaxAttempts < 1 || mcc.MaxAttempts > 5 {
+		return fmt.Errorf("'MaxAttempts' must be between 1 and 5, got %d", mcc.MaxAttempts)
+	}
+	if mcc.KeepaliveTime < 0 || mcc.KeepaliveTimeout < 0 {
+		return fmt.Errorf("'KeepaliveTime' and 'KeepaliveTimeout' must not be negative")
+	}
+	if cb := mcc.CircuitBreaker; cb.FailureThreshold < 0 || (cb.FailureThreshold > 0 && cb.OpenDuration <= 0) {
+		return fmt.Errorf("'CircuitBreaker.FailureThreshold' must not be negative and 'CircuitBreaker.OpenDuration' must be positive")
+	}
+	return nil
+}
+
+// TracingConfig configures the export of OpenTelemetry traces of the controller's
+// reconciles and model-mesh calls. It's only read when the controller starts.
+type TracingConfig struct {
+	// host:port of the OTLP gRPC trace collector, tracing disabled if empty
+	Endpoint string
+	// connect to the collector without TLS
+	Insecure bool
+	// fraction of the reconciles which are traced, from 0 to 1
+	SamplingRatio float64
+}
+
+// formats of ModelMeshLogFormat, which applies to all of model-mesh's logs
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// AccessLogConfig configures model-mesh's logging of each inference request, in the format of
+// its other logs. Whether it's enabled can be overridden per runtime with an annotation.
+type AccessLogConfig struct {
+	Enabled bool
+	// request headers whose values are included in the log lines
+	RequestHeaders []string
+	// fraction of the requests logged by the OpenAI API sidecar, from 0 to 1. It doesn't
+	// apply to model-mesh, which can't sample its access logs and logs every request.
+	OpenAISampleRatio float64
+}
+
+func (alc AccessLogConfig) validate() error {
+	if alc.OpenAISampleRatio < 0 || alc.OpenAISampleRatio > 1 {
+		return fmt.Errorf("'OpenAISampleRatio' must be between 0 and 1, got %v", alc.OpenAISampleRatio)
+	}
+	for _, header := range alc.RequestHeaders {
+		if header == "" || strings.ContainsAny(header, " :") {
+			return fmt.Errorf("'RequestHeaders' must be header names, got %q", header)
+		}
+	}
+	return nil
+}
+
+// KVStoreCleanupConfig configures the deletion of model-mesh's KV store data of
+// namespaces which were deleted or are no longer modelmesh-enabled
+type KVStoreCleanupConfig struct {
+	Enabled bool
+	// only log the namespaces whose data would be deleted
+	DryRun bool
+	// how long a namespace must be disabled before its data is deleted
+	GracePeriod time.Duration
+	// how often to check for the data of disabled namespaces
+	Interval time.Duration
+}
+
+const (
+	// watch model-mesh's etcd registries directly
+	ModelEventSourceEtcd = "etcd"
+	// use the watchModelEvents rpc of model-mesh
+	ModelEventSourceGrpc = "grpc"
+)
+
+const (
+	ModelCacheEmptyDir  = "emptyDir"
+	ModelCacheMemory    = "memory"
+	ModelCacheEphemeral = "ephemeral"
+	ModelCachePVC       = "pvc"
+
+	DefaultModelCacheSizeMultiplier = 1.5
+)
+
+// ModelCacheConfig determines the volume backing the models directory of runtime Pods.
+// Each field can be overridden per runtime via ServingRuntime annotations.
+type ModelCacheConfig struct {
+	// one of emptyDir, memory, ephemeral or pvc
+	Type string
+	// size of the cache as a multiple of the total memory limit of the runtime containers
+	SizeMultiplier float64
+	// storage class of the generic ephemeral volume, cluster default if empty
+	StorageClassName string
+	// name of an existing PVC, required when Type is pvc
+	ClaimName string
+}
+
+func (mc ModelCacheConfig) Validate() error {
+	switch mc.Type {
+	case ModelCacheEmptyDir, ModelCacheMemory, ModelCacheEphemeral:
+	case ModelCachePVC:
+		if mc.ClaimName == "" {
+			return fmt.Errorf("'ClaimName' must be set when 'Type' is %s", ModelCachePVC)
+		}
+	default:
+		return fmt.Errorf("unsupported 'Type' %q, must be one of %s, %s, %s or %s", mc.Type,
+			ModelCacheEmptyDir, ModelCacheMemory, ModelCacheEphemeral, ModelCachePVC)
+	}
+	if mc.SizeMultiplier <= 0 {
+		return fmt.Errorf("'SizeMultiplier' must be positive, got %v", mc.SizeMultiplier)
+	}
+	return nil
+}
+
+const (
+	ExternalRouteGateway = "gateway"
+	ExternalRouteIngress = "ingress"
+)
+
+// ExternalRouteConfig configures the generation of Gateway API routes or Ingresses
+// which expose the inference Service of each namespace outside of the cluster
+type ExternalRouteConfig struct {
+	// one of gateway or ingress, disabled if empty
+	Type string
+	// external hosts are <service-name>-grpc.<namespace>.<domain> and <service-name>.<namespace>.<domain>
+	Domain string
+	// whether the external endpoints are served over TLS
+	TLS bool
+	// the Gateway which the routes are attached to
+	GatewayName      string
+	GatewayNamespace string
+	// used for the Ingresses
+	IngressClassName     string
+	IngressTLSSecretName string
+	// added to all generated routes or Ingresses
+	Annotations map[string]string
+	// added to the gRPC routes or Ingresses only
+	GrpcAnnotations map[string]string
+}
+
+func (erc ExternalRouteConfig) Enabled() bool {
+	return erc.Type != ""
+}
+
+// Hosts returns the external gRPC and HTTP hostnames of the inference Service
+func (erc ExternalRouteConfig) Hosts(serviceName, namespace string) (grpcHost, httpHost string) {
+	return fmt.Sprintf("%s-grpc.%s.%s", serviceName, namespace, erc.Domain),
+		fmt.Sprintf("%s.%s.%s", serviceName, namespace, erc.Domain)
+}
+
+// Endpoints returns the external gRPC and HTTP URLs of the inference Service,
+// the HTTP endpoint is empty if the REST proxy is disabled
+func (erc ExternalRouteConfig) Endpoints(serviceName, namespace string, restEnabled bool) (grpcEndpoint, httpEndpoint string) {
+	grpcHost, httpHost := erc.Hosts(serviceName, namespace)
+	port, scheme := 80, "http"
+	if erc.TLS {
+		port, scheme = 443, "https"
+	}
+	grpcEndpoint = fmt.Sprintf("grpc://%s:%d", grpcHost, port)
+	if restEnabled {
+		httpEndpoint = fmt.Sprintf("%s://%s", scheme, httpHost)
+	}
+	return
+}
+
+func (erc ExternalRouteConfig) validate() error {
+	switch erc.Type {
+	case "":
+		return nil
+	case ExternalRouteGateway:
+		if erc.GatewayName == "" {
+			return fmt.Errorf("'GatewayName' must be set when 'Type' is %s", ExternalRouteGateway)
+		}
+	case ExternalRouteIngress:
+	default:
+		return fmt.Errorf("unsupported 'Type' %q, must be %s or %s",
+			erc.Type, ExternalRouteGateway, ExternalRouteIngress)
+	}
+	if erc.Domain == "" {
+		return fmt.Errorf("'Domain' must be set when 'Type' is %s", erc.Type)
+	}
+	return nil
+}
+
+// NetworkPolicyConfig configures the NetworkPolicy which the controller manages for
+// the runtime Pods in each namespace
+type NetworkPolicyConfig struct {
+	Enabled bool
+	// peers allowed to reach the inference ports, any pod if empty
+	InferenceClients []NetworkPolicyPeer
+	// namespace from which runtime metrics are scraped, any namespace if empty
+	MonitoringNamespace string
+}
+
+// NetworkPolicyPeer selects pods by their labels, in the runtime namespace unless
+// NamespaceLabels is set
+type NetworkPolicyPeer struct {
+	PodLabels       map[string]string
+	NamespaceLabels map[string]string
+}
+
 type TLSConfig struct {
 	// TLS disabled if omitted
 	SecretName string
 	// Mutual TLS disabled if omitted
 	ClientAuth string
+	// Secret in the controller namespace with the key pair which the controller presents
+	// to model-mesh, the key pair of SecretName is used if omitted
+	ClientCertSecretName string
+	// Have cert-manager issue the SecretName secret in each namespace
+	CertManager CertManagerConfig
+}
+
+// CertManagerConfig configures the cert-manager Certificates which the controller
+// creates for the model-mesh Service of each namespace
+type CertManagerConfig struct {
+	// cert-manager integration disabled if omitted
+	IssuerName string
+	// Issuer or ClusterIssuer
+	IssuerKind string
+	// cert-manager's defaults are used if omitted
+	Duration    time.Duration
+	RenewBefore time.Duration
+}
+
+// Enabled returns whether the controller manages the TLS secrets via cert-manager
+func (c CertManagerConfig) Enabled() bool {
+	return c.IssuerName != ""
 }
 
 type RESTProxyConfig struct {
@@ -138,6 +398,112 @@ type RESTProxyConfig struct {
 	Port       uint16
 	Image      ImageConfig
 	Resources  ResourceRequirements
+	// options of the REST port's HTTP listener, which the REST proxy image doesn't support,
+	// so they are enforced by the Listener sidecar in front of it
+	HTTPListenerConfig `mapstructure:",squash"`
+	Listener           RESTProxyListenerConfig
+	OpenAI             OpenAIProxyConfig
+}
+
+// RESTProxyListenerConfig configures the sidecar which serves the REST port in front of
+// the REST proxy when any of its HTTP listener options are set
+type RESTProxyListenerConfig struct {
+	Image     ImageConfig
+	Resources ResourceRequirements
+	// port of the REST proxy behind the sidecar, which isn't exposed by the Service
+	BackendPort uint16
+}
+
+// OpenAIProxyConfig configures a sidecar next to the REST proxy which serves the OpenAI
+// completions and chat completions API for text generation models
+type OpenAIProxyConfig struct {
+	Enabled            bool
+	Port               uint16
+	Image              ImageConfig
+	Resources          ResourceRequirements
+	HTTPListenerConfig `mapstructure:",squash"`
+}
+
+// HTTPListenerConfig configures the CORS, request size limit, authentication and TLS
+// certificate of an HTTP listener of the runtime pods
+type HTTPListenerConfig struct {
+	// origins allowed to make cross-origin requests, "*" for any, CORS disabled if empty
+	CORSAllowedOrigins []string
+	// maximum size of request bodies in bytes, no limit if 0
+	MaxRequestBodyBytes int
+	Auth                HTTPListenerAuthConfig
+	TLS                 HTTPListenerTLSConfig
+}
+
+const (
+	HTTPListenerAuthBearer = "bearer"
+	HTTPListenerAuthMTLS   = "mtls"
+)
+
+// HTTPListenerAuthConfig configures the authentication of HTTP requests
+type HTTPListenerAuthConfig struct {
+	// one of bearer or mtls, no authentication if empty
+	Type string
+	// Secret whose tokens key holds the accepted bearer tokens, one per line
+	TokenSecretName string
+}
+
+// HTTPListenerTLSConfig configures a certificate for an HTTP listener which is separate
+// from that of model-mesh
+type HTTPListenerTLSConfig struct {
+	// kubernetes.io/tls Secret of the listener, the TLS secret of model-mesh is used if empty.
+	// Its ca.crt key holds the CAs of the client certificates accepted with mtls authentication.
+	SecretName string
+}
+
+// Configured returns whether any of the listener options are set
+func (c HTTPListenerConfig) Configured() bool {
+	return len(c.CORSAllowedOrigins) > 0 || c.MaxRequestBodyBytes > 0 || c.Auth.Type != "" || c.TLS.SecretName != ""
+}
+
+func (c HTTPListenerConfig) validate(prefix string) error {
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fmt.Errorf("'%sCORSAllowedOrigins' must be \"*\" or http(s) origins, got %q", prefix, origin)
+		}
+	}
+	if c.MaxRequestBodyBytes < 0 {
+		return fmt.Errorf("'%sMaxRequestBodyBytes' must not be negative, got %d", prefix, c.MaxRequestBodyBytes)
+	}
+	switch c.Auth.Type {
+	case "":
+	case HTTPListenerAuthBearer:
+		if c.Auth.TokenSecretName == "" {
+			return fmt.Errorf("'%sAuth.TokenSecretName' must be set when '%sAuth.Type' is %s",
+				prefix, prefix, HTTPListenerAuthBearer)
+		}
+	case HTTPListenerAuthMTLS:
+		if c.TLS.SecretName == "" {
+			return fmt.Errorf("'%sTLS.SecretName' must be set when '%sAuth.Type' is %s",
+				prefix, prefix, HTTPListenerAuthMTLS)
+		}
+	default:
+		return fmt.Errorf("unsupported '%sAuth.Type' %q, must be %s or %s", prefix, c.Auth.Type,
+			HTTPListenerAuthBearer, HTTPListenerAuthMTLS)
+	}
+	return nil
+}
+
+func (rpc RESTProxyConfig) validate() error {
+	oc := rpc.OpenAI
+	if oc.Enabled && (oc.Port == 0 || oc.Port == rpc.Port) {
+		return fmt.Errorf("'OpenAI.Port' must be set and differ from 'Port', got %d", oc.Port)
+	}
+	if err := rpc.HTTPListenerConfig.validate(""); err != nil {
+		return err
+	}
+	if rpc.Configured() {
+		bp := rpc.Listener.BackendPort
+		if bp == 0 || bp == rpc.Port || (oc.Enabled && bp == oc.Port) {
+			return fmt.Errorf("'Listener.BackendPort' must be set and differ from 'Port' and 'OpenAI.Port', got %d", bp)
+		}
+	}
+	return oc.HTTPListenerConfig.validate("OpenAI.")
 }
 
 func (c *Config) GetEtcdSecretName() string {
@@ -339,10 +705,25 @@ func defaults(v *viper.Viper) {
 	v.SetDefault("StorageSecretName", "storage-config")
 	v.SetDefault("ServiceAccountName", "")
 	v.SetDefault("PayloadProcessors", []string{})
+	v.SetDefault("ModelEventSource", ModelEventSourceEtcd)
 	v.SetDefault(concatStringsWithDelimiter([]string{"Metrics", "Port"}), 2112)
 	v.SetDefault(concatStringsWithDelimiter([]string{"Metrics", "Scheme"}), "https")
 	v.SetDefault(concatStringsWithDelimiter([]string{"ScaleToZero", "Enabled"}), true)
 	v.SetDefault(concatStringsWithDelimiter([]string{"ScaleToZero", "GracePeriodSeconds"}), 60)
+	v.SetDefault(concatStringsWithDelimiter([]string{"KVStoreCleanup", "GracePeriod"}), "24h")
+	v.SetDefault(concatStringsWithDelimiter([]string{"KVStoreCleanup", "Interval"}), "1h")
+	v.SetDefault(concatStringsWithDelimiter([]string{"TLS", "CertManager", "IssuerKind"}), "ClusterIssuer")
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "RequestTimeout"}), "10s")
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "MaxAttempts"}), 3)
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "KeepaliveTime"}), "5m")
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "KeepaliveTimeout"}), "20s")
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "CircuitBreaker", "FailureThreshold"}), 5)
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelMeshClient", "CircuitBreaker", "OpenDuration"}), "30s")
+	v.SetDefault(concatStringsWithDelimiter([]string{"Tracing", "SamplingRatio"}), 1.0)
+	v.SetDefault(concatStringsWithDelimiter([]string{"AccessLog", "OpenAISampleRatio"}), 1.0)
+	v.SetDefault("ModelMeshLogFormat", LogFormatText)
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelCache", "Type"}), ModelCacheEmptyDir)
+	v.SetDefault(concatStringsWithDelimiter([]string{"ModelCache", "SizeMultiplier"}), DefaultModelCacheSizeMultiplier)
 	// default size 16MiB in bytes
 	v.SetDefault("GrpcMaxMessageSizeBytes", 16777216)
 	v.SetDefault("BuiltInServerTypes", []string{
@@ -415,16 +796,75 @@ func NewMergedConfigFromString(configYaml string) (*Config, error) {
 	configLog.Info("Updated model serving config", "mergedConfig", config)
 
 	// extra validations on parsed config
+	if config.ModelEventSource != ModelEventSourceEtcd && config.ModelEventSource != ModelEventSourceGrpc {
+		return nil, fmt.Errorf("Invalid config for 'ModelEventSource': must be %s or %s, got %q",
+			ModelEventSourceEtcd, ModelEventSourceGrpc, config.ModelEventSource)
+	}
+	if config.KVStoreCleanup.Interval <= 0 || config.KVStoreCleanup.GracePeriod < 0 {
+		return nil, fmt.Errorf("Invalid config for 'KVStoreCleanup': 'Interval' must be positive and 'GracePeriod' must not be negative")
+	}
+	if err = config.ModelMeshClient.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config for 'ModelMeshClient': %s", err)
+	}
+	if config.Tracing.SamplingRatio < 0 || config.Tracing.SamplingRatio > 1 {
+		return nil, fmt.Errorf("Invalid config for 'Tracing': 'SamplingRatio' must be between 0 and 1, got %v",
+			config.Tracing.SamplingRatio)
+	}
+	if config.EnableAccessLogging {
+		config.AccessLog.Enabled = true
+	}
+	if err = config.AccessLog.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config for 'AccessLog': %s", err)
+	}
+	if config.ModelMeshLogFormat != LogFormatText && config.ModelMeshLogFormat != LogFormatJSON {
+		return nil, fmt.Errorf("Invalid config for 'ModelMeshLogFormat': must be %s or %s, got %q",
+			LogFormatText, LogFormatJSON, config.ModelMeshLogFormat)
+	}
+	if config.TLS.ClientCertSecretName != "" && config.TLS.SecretName == "" {
+		return nil, fmt.Errorf("Invalid config for 'TLS': 'ClientCertSecretName' requires 'SecretName' to be set")
+	}
+	if cm := config.TLS.CertManager; cm.Enabled() {
+		if config.TLS.SecretName == "" {
+			return nil, fmt.Errorf("Invalid config for 'TLS.CertManager': 'IssuerName' requires 'TLS.SecretName' to be set")
+		}
+		if cm.IssuerKind != "Issuer" && cm.IssuerKind != "ClusterIssuer" {
+			return nil, fmt.Errorf("Invalid config for 'TLS.CertManager': 'IssuerKind' must be Issuer or ClusterIssuer, got %q",
+				cm.IssuerKind)
+		}
+		if cm.Duration < 0 || cm.RenewBefore < 0 {
+			return nil, fmt.Errorf("Invalid config for 'TLS.CertManager': 'Duration' and 'RenewBefore' must not be negative")
+		}
+	}
 	if err = config.ModelMeshResources.parseAndValidate(); err != nil {
 		return nil, fmt.Errorf("Invalid config for 'ModelMeshResources': %s", err)
 	}
 	if err = config.RESTProxy.Resources.parseAndValidate(); err != nil {
 		return nil, fmt.Errorf("Invalid config for 'RESTProxy.Resources': %s", err)
 	}
+	if config.RESTProxy.Configured() {
+		if err = config.RESTProxy.Listener.Resources.parseAndValidate(); err != nil {
+			return nil, fmt.Errorf("Invalid config for 'RESTProxy.Listener.Resources': %s", err)
+		}
+	}
+	if config.RESTProxy.OpenAI.Enabled {
+		if err = config.RESTProxy.OpenAI.Resources.parseAndValidate(); err != nil {
+			return nil, fmt.Errorf("Invalid config for 'RESTProxy.OpenAI.Resources': %s", err)
+		}
+	}
+	if err = config.RESTProxy.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config for 'RESTProxy': %s", err)
+	}
 	if err = config.StorageHelperResources.parseAndValidate(); err != nil {
 		return nil, fmt.Errorf("Invalid config for 'StorageHelperResources': %s", err)
 	}
 
+	if err = config.ExternalRoute.validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config for 'ExternalRoute': %s", err)
+	}
+	if err = config.ModelCache.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid config for 'ModelCache': %s", err)
+	}
+
 	// check that none of the payload processors contains a space
 	for _, processor := range config.PayloadProcessors {
 		if strings.Contains(processor, " ") {
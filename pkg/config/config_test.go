@@ -17,6 +17,7 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/assert"
@@ -151,6 +152,9 @@ modelMeshResources:
   requests:
     cpu: "30"
     memory: "asdf"`,
+		// unsupported model event source
+		`
+modelEventSource: zookeeper`,
 	}
 
 	for i, yaml := range invalidConfigs {
@@ -312,3 +316,316 @@ imagePullSecrets:
 		t.Fatalf("Expected ImagePullSecrets to have secret with name [%s], but got [%s]", expectedSecretName, secret.Name)
 	}
 }
+
+func TestModelCache(t *testing.T) {
+	conf, err := NewMergedConfigFromString("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ModelCacheEmptyDir, conf.ModelCache.Type)
+	assert.Equal(t, DefaultModelCacheSizeMultiplier, conf.ModelCache.SizeMultiplier)
+
+	yaml := `
+modelCache:
+  type: ephemeral
+  sizeMultiplier: 2.5
+  storageClassName: fast-local`
+
+	conf, err = NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ModelCacheEphemeral, conf.ModelCache.Type)
+	assert.Equal(t, 2.5, conf.ModelCache.SizeMultiplier)
+	assert.Equal(t, "fast-local", conf.ModelCache.StorageClassName)
+
+	invalidConfigs := []string{
+		"modelCache:\n  type: hostPath",
+		"modelCache:\n  type: pvc",
+		"modelCache:\n  sizeMultiplier: 0",
+	}
+	for i, yaml := range invalidConfigs {
+		if _, err = NewMergedConfigFromString(yaml); err == nil {
+			t.Fatalf("Expected error for test case [%d], but did not get one", i)
+		}
+	}
+}
+
+func TestExternalRoute(t *testing.T) {
+	yaml := `
+externalRoute:
+  type: ingress
+  domain: models.example.com
+  tls: true`
+
+	conf, err := NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.ExternalRoute.Enabled())
+
+	grpcEndpoint, httpEndpoint := conf.ExternalRoute.Endpoints("modelmesh-serving", "team-a", true)
+	assert.Equal(t, "grpc://modelmesh-serving-grpc.team-a.models.example.com:443", grpcEndpoint)
+	assert.Equal(t, "https://modelmesh-serving.team-a.models.example.com", httpEndpoint)
+
+	_, httpEndpoint = conf.ExternalRoute.Endpoints("modelmesh-serving", "team-a", false)
+	assert.Equal(t, "", httpEndpoint)
+
+	invalidConfigs := []string{
+		"externalRoute:\n  type: route\n  domain: example.com",
+		"externalRoute:\n  type: ingress",
+		"externalRoute:\n  type: gateway\n  domain: example.com",
+	}
+	for i, yaml := range invalidConfigs {
+		if _, err = NewMergedConfigFromString(yaml); err == nil {
+			t.Fatalf("Expected error for test case [%d], but did not get one", i)
+		}
+	}
+}
+
+func TestKVStoreCleanup(t *testing.T) {
+	conf, err := NewMergedConfigFromString("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(t, conf.KVStoreCleanup.Enabled)
+	assert.Equal(t, 24*time.Hour, conf.KVStoreCleanup.GracePeriod)
+	assert.Equal(t, time.Hour, conf.KVStoreCleanup.Interval)
+
+	yaml := `
+kvStoreCleanup:
+  enabled: true
+  dryRun: true
+  gracePeriod: 168h
+  interval: 30m`
+
+	conf, err = NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.KVStoreCleanup.Enabled)
+	assert.True(t, conf.KVStoreCleanup.DryRun)
+	assert.Equal(t, 168*time.Hour, conf.KVStoreCleanup.GracePeriod)
+	assert.Equal(t, 30*time.Minute, conf.KVStoreCleanup.Interval)
+
+	invalidConfigs := []string{
+		"kvStoreCleanup:\n  interval: 0s",
+		"kvStoreCleanup:\n  gracePeriod: -1h",
+		"kvStoreCleanup:\n  interval: soon",
+	}
+	for i, yaml := range invalidConfigs {
+		if _, err = NewMergedConfigFromString(yaml); err == nil {
+			t.Fatalf("Expected error for test case [%d], but did not get one", i)
+		}
+	}
+}
+
+func TestTLSClientCertSecret(t *testing.T) {
+	yaml := `
+tls:
+  secretName: modelmesh-certificate
+  clientAuth: require
+  clientCertSecretName: controller-client-cert`
+
+	conf, err := NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "controller-client-cert", conf.TLS.ClientCertSecretName)
+
+	if _, err = NewMergedConfigFromString("tls:\n  clientCertSecretName: controller-client-cert"); err == nil {
+		t.Fatal("Expected error when the client cert secret is set without the TLS secret")
+	}
+}
+
+func TestTLSCertManager(t *testing.T) {
+	yaml := `
+tls:
+  secretName: modelmesh-certificate
+  certManager:
+    issuerName: modelmesh-issuer
+    renewBefore: 360h`
+
+	conf, err := NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.TLS.CertManager.Enabled())
+	assert.Equal(t, "ClusterIssuer", conf.TLS.CertManager.IssuerKind)
+	assert.Equal(t, 360*time.Hour, conf.TLS.CertManager.RenewBefore)
+	assert.Zero(t, conf.TLS.CertManager.Duration)
+
+	for _, invalid := range []string{
+		"tls:\n  certManager:\n    issuerName: modelmesh-issuer",
+		"tls:\n  secretName: cert\n  certManager:\n    issuerName: modelmesh-issuer\n    issuerKind: Vault",
+	} {
+		if _, err = NewMergedConfigFromString(invalid); err == nil {
+			t.Fatalf("Expected error for invalid cert-manager config %q", invalid)
+		}
+	}
+}
+
+func TestModelMeshClientConfig(t *testing.T) {
+	conf, err := NewMergedConfigFromString("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 10*time.Second, conf.ModelMeshClient.RequestTimeout)
+	assert.Equal(t, 3, conf.ModelMeshClient.MaxAttempts)
+	assert.Equal(t, 5*time.Minute, conf.ModelMeshClient.KeepaliveTime)
+	assert.Equal(t, 5, conf.ModelMeshClient.CircuitBreaker.FailureThreshold)
+	assert.Equal(t, 30*time.Second, conf.ModelMeshClient.CircuitBreaker.OpenDuration)
+
+	yaml := `
+modelMeshClient:
+  requestTimeout: 30s
+  maxAttempts: 1
+  circuitBreaker:
+    failureThreshold: 0`
+	if conf, err = NewMergedConfigFromString(yaml); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 30*time.Second, conf.ModelMeshClient.RequestTimeout)
+	assert.Equal(t, 1, conf.ModelMeshClient.MaxAttempts)
+	assert.Zero(t, conf.ModelMeshClient.CircuitBreaker.FailureThreshold)
+
+	for _, invalid := range []string{
+		"modelMeshClient:\n  maxAttempts: 6",
+		"modelMeshClient:\n  requestTimeout: 0s",
+		"modelMeshClient:\n  circuitBreaker:\n    openDuration: 0s",
+	} {
+		if _, err = NewMergedConfigFromString(invalid); err == nil {
+			t.Fatalf("Expected error for invalid model-mesh client config %q", invalid)
+		}
+	}
+}
+
+func TestTracingConfig(t *testing.T) {
+	conf, err := NewMergedConfigFromString("tracing:\n  endpoint: otel-collector:4317")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "otel-collector:4317", conf.Tracing.Endpoint)
+	assert.Equal(t, 1.0, conf.Tracing.SamplingRatio)
+
+	if _, err = NewMergedConfigFromString("tracing:\n  samplingRatio: 1.5"); err == nil {
+		t.Fatal("Expected error for a sampling ratio above 1")
+	}
+}
+
+func TestAccessLogConfig(t *testing.T) {
+	conf, err := NewMergedConfigFromString("accessLog:\n  enabled: true\n  requestHeaders: [x-request-id]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.AccessLog.Enabled)
+	assert.Equal(t, LogFormatText, conf.ModelMeshLogFormat)
+	assert.Equal(t, []string{"x-request-id"}, conf.AccessLog.RequestHeaders)
+	assert.Equal(t, 1.0, conf.AccessLog.OpenAISampleRatio)
+
+	conf, err = NewMergedConfigFromString("modelMeshLogFormat: json\naccessLog:\n  enabled: true\n  openAISampleRatio: 0.05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, LogFormatJSON, conf.ModelMeshLogFormat)
+	assert.Equal(t, 0.05, conf.AccessLog.OpenAISampleRatio)
+
+	// the deprecated flag still enables it
+	conf, err = NewMergedConfigFromString("enableAccessLogging: true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.AccessLog.Enabled)
+
+	if _, err = NewMergedConfigFromString("modelMeshLogFormat: xml"); err == nil {
+		t.Fatal("Expected error for an unsupported log format")
+	}
+	for _, ratio := range []string{"-0.1", "1.5"} {
+		_, err = NewMergedConfigFromString("accessLog:\n  openAISampleRatio: " + ratio)
+		assert.ErrorContains(t, err, "'OpenAISampleRatio' must be between 0 and 1", ratio)
+	}
+}
+
+func TestHTTPListenerConfig(t *testing.T) {
+	yaml := `
+restProxy:
+  openAI:
+    corsAllowedOrigins:
+      - https://app.example.com
+      - http://localhost:3000
+    maxRequestBodyBytes: 1048576
+    auth:
+      type: mtls
+    tls:
+      secretName: openai-proxy-tls`
+	conf, err := NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := conf.RESTProxy.OpenAI
+	assert.Equal(t, []string{"https://app.example.com", "http://localhost:3000"}, oc.CORSAllowedOrigins)
+	assert.Equal(t, 1048576, oc.MaxRequestBodyBytes)
+	assert.Equal(t, HTTPListenerAuthMTLS, oc.Auth.Type)
+	assert.Equal(t, "openai-proxy-tls", oc.TLS.SecretName)
+	// the REST port has its own options
+	assert.False(t, conf.RESTProxy.Configured())
+
+	for _, invalid := range []string{
+		"restProxy:\n  openAI:\n    corsAllowedOrigins: [app.example.com]",
+		"restProxy:\n  openAI:\n    maxRequestBodyBytes: -1",
+		"restProxy:\n  openAI:\n    auth:\n      type: basic",
+		"restProxy:\n  openAI:\n    auth:\n      type: bearer",
+		"restProxy:\n  openAI:\n    auth:\n      type: mtls",
+		"restProxy:\n  corsAllowedOrigins: [app.example.com]",
+		"restProxy:\n  auth:\n    type: bearer",
+	} {
+		if _, err = NewMergedConfigFromString(invalid); err == nil {
+			t.Fatalf("Expected error for invalid HTTP listener config %q", invalid)
+		}
+	}
+}
+
+func TestRESTProxyListenerConfig(t *testing.T) {
+	yaml := `
+restProxy:
+  maxRequestBodyBytes: 1048576
+  auth:
+    type: bearer
+    tokenSecretName: rest-proxy-tokens
+  tls:
+    secretName: rest-proxy-tls`
+	conf, err := NewMergedConfigFromString(yaml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc := conf.RESTProxy
+	assert.True(t, rc.Configured())
+	assert.Equal(t, 1048576, rc.MaxRequestBodyBytes)
+	assert.Equal(t, HTTPListenerAuthConfig{Type: HTTPListenerAuthBearer, TokenSecretName: "rest-proxy-tokens"}, rc.Auth)
+	assert.Equal(t, "rest-proxy-tls", rc.TLS.SecretName)
+	assert.Equal(t, uint16(8018), rc.Listener.BackendPort)
+	assert.Equal(t, "kserve/modelmesh-controller:latest", rc.Listener.Image.TaggedImage())
+	assert.Equal(t, []string{"/manager", "rest-proxy-listener"}, rc.Listener.Image.Command)
+	assert.NotNil(t, rc.Listener.Resources.ToKubernetesType())
+	// they don't apply to the OpenAI API sidecar
+	assert.False(t, rc.OpenAI.Configured())
+
+	_, err = NewMergedConfigFromString(yaml + "\n  listener:\n    backendPort: 8008")
+	assert.ErrorContains(t, err, "'Listener.BackendPort' must be set and differ")
+}
+
+func TestOpenAIProxyConfig(t *testing.T) {
+	conf, err := NewMergedConfigFromString("restProxy:\n  openAI:\n    enabled: true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, conf.RESTProxy.OpenAI.Enabled)
+	assert.Equal(t, uint16(8009), conf.RESTProxy.OpenAI.Port)
+	assert.Equal(t, "kserve/modelmesh-controller:latest", conf.RESTProxy.OpenAI.Image.TaggedImage())
+	assert.Equal(t, []string{"/manager", "openai-proxy"}, conf.RESTProxy.OpenAI.Image.Command)
+	assert.NotNil(t, conf.RESTProxy.OpenAI.Resources.ToKubernetesType())
+
+	if _, err = NewMergedConfigFromString("restProxy:\n  openAI:\n    enabled: true\n    port: 8008"); err == nil {
+		t.Fatal("Expected error for an OpenAI proxy port conflicting with the REST proxy port")
+	}
+}
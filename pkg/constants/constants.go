@@ -19,4 +19,29 @@ import "github.com/kserve/kserve/pkg/constants"
 var (
 	MinScaleAnnotationKey = constants.KServeAPIGroupName + "/min-scale"
 	MaxScaleAnnotationKey = constants.KServeAPIGroupName + "/max-scale"
+
+	// runtime-level overrides of the ModelCache config
+	ModelCacheTypeAnnotationKey             = constants.KServeAPIGroupName + "/model-cache-type"
+	ModelCacheSizeMultiplierAnnotationKey   = constants.KServeAPIGroupName + "/model-cache-size-multiplier"
+	ModelCacheStorageClassNameAnnotationKey = constants.KServeAPIGroupName + "/model-cache-storage-class"
+	ModelCacheClaimNameAnnotationKey        = constants.KServeAPIGroupName + "/model-cache-claim-name"
+
+	// runtime-level override of whether the REST proxy is injected into the runtime's pods
+	RESTProxyAnnotationKey = constants.KServeAPIGroupName + "/rest-proxy"
+	// runtime-level override of whether model-mesh logs each inference request
+	AccessLoggingAnnotationKey = constants.KServeAPIGroupName + "/access-logging"
+	// runtime-level override of the fraction of the requests logged by the OpenAI API sidecar
+	OpenAIAccessLogSampleRatioAnnotationKey = constants.KServeAPIGroupName + "/openai-access-log-sample-ratio"
+
+	// namespace annotation naming the secret in the namespace with the config of its own KV store
+	KVStoreSecretAnnotationKey = constants.KServeAPIGroupName + "/etcd-secret"
+
+	// namespace annotation defining the payload processor destinations which its Predictors can select
+	PayloadProcessorDestinationsAnnotationKey = constants.KServeAPIGroupName + "/payload-processor-destinations"
+	// Predictor annotation with the comma-separated names of the selected payload processor destinations
+	PayloadProcessorsAnnotationKey = constants.KServeAPIGroupName + "/payload-processors"
+
+	// runtime pod annotation with the hash of the cert-manager issued TLS secret, so that
+	// the pods are only restarted when the certificate is renewed
+	TLSSecretHashAnnotationKey = constants.KServeAPIGroupName + "/tls-secret-hash"
 )
@@ -0,0 +1,141 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// circuitOpenMessage is the message of the UNAVAILABLE errors of RPCs rejected by the circuit breaker
+const circuitOpenMessage = "model-mesh circuit breaker is open"
+
+// IsCircuitOpen returns whether the RPC was rejected by the circuit breaker
+func IsCircuitOpen(err error) bool {
+	s := status.Convert(err)
+	return s.Code() == codes.Unavailable && s.Message() == circuitOpenMessage
+}
+
+// circuitBreaker fails the unary RPCs of a model-mesh client fast once a number of
+// consecutive RPCs failed because model-mesh was unavailable or didn't respond in time.
+// After the open duration a single RPC is let through, which closes the circuit if
+// it succeeds or opens it again otherwise.
+type circuitBreaker struct {
+	now func() time.Time
+
+	mutex        sync.Mutex
+	threshold    int // disabled if 0
+	openDuration time.Duration
+	failures     int
+	openUntil    time.Time
+	probing      bool
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{now: time.Now}
+}
+
+// configure sets the thresholds and closes the circuit
+func (cb *circuitBreaker) configure(threshold int, openDuration time.Duration) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	cb.threshold, cb.openDuration = threshold, openDuration
+	cb.failures, cb.openUntil, cb.probing = 0, time.Time{}, false
+}
+
+// retryAfter returns how long the circuit remains open, 0 if RPCs can be attempted
+func (cb *circuitBreaker) retryAfter() time.Duration {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.threshold == 0 || cb.failures < cb.threshold {
+		return 0
+	}
+	if wait := cb.openUntil.Sub(cb.now()); wait > 0 {
+		return wait
+	}
+	if cb.probing {
+		// wait for the outcome of the probe
+		return cb.openDuration
+	}
+	return 0
+}
+
+// allow returns whether an RPC can be attempted
+func (cb *circuitBreaker) allow() bool {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.threshold == 0 || cb.failures < cb.threshold {
+		return true
+	}
+	if cb.probing || cb.now().Before(cb.openUntil) {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+// record updates the state of the circuit with the outcome of an attempted RPC
+func (cb *circuitBreaker) record(err error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	if cb.threshold == 0 {
+		return
+	}
+	cb.probing = false
+	if !isUnavailable(err) {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures >= cb.threshold {
+		cb.openUntil = cb.now().Add(cb.openDuration)
+	}
+}
+
+// isUnavailable returns whether the RPC failed because model-mesh was unavailable or didn't
+// respond in time. Having no model-mesh pods isn't counted, it's reported in Predictor statuses.
+func isUnavailable(err error) bool {
+	s := status.Convert(err)
+	switch s.Code() {
+	case codes.Unavailable:
+		return !strings.Contains(s.Message(), "produced zero addresses")
+	case codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+func (cb *circuitBreaker) unaryInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !cb.allow() {
+		return status.Error(codes.Unavailable, circuitOpenMessage)
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if ctx.Err() == context.Canceled {
+		// the caller gave up, which says nothing about model-mesh
+		cb.mutex.Lock()
+		cb.probing = false
+		cb.mutex.Unlock()
+		return err
+	}
+	cb.record(err)
+	return err
+}
@@ -0,0 +1,107 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker()
+	cb.now = func() time.Time { return now }
+	cb.configure(2, 30*time.Second)
+
+	var rpcErr error
+	calls := 0
+	invoke := func() error {
+		return cb.unaryInterceptor(context.Background(), "/mmesh.ModelMesh/setVModel", nil, nil, nil,
+			func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				calls++
+				return rpcErr
+			})
+	}
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	// having no model-mesh pods doesn't open the circuit
+	rpcErr = status.Error(codes.Unavailable, "name resolver produced zero addresses")
+	invoke()
+	invoke()
+	assert.Zero(t, cb.retryAfter())
+
+	rpcErr = unavailable
+	invoke()
+	rpcErr = status.Error(codes.NotFound, "vmodel not found")
+	invoke()
+	assert.Zero(t, cb.retryAfter(), "successful responses reset the failure count")
+
+	rpcErr = unavailable
+	invoke()
+	rpcErr = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	invoke()
+	assert.Equal(t, 30*time.Second, cb.retryAfter())
+	assert.Equal(t, 6, calls)
+
+	// RPCs fail fast while the circuit is open
+	err := invoke()
+	assert.True(t, IsCircuitOpen(err))
+	assert.Equal(t, 6, calls)
+
+	// a failed probe opens the circuit again
+	now = now.Add(31 * time.Second)
+	assert.Zero(t, cb.retryAfter())
+	rpcErr = unavailable
+	assert.False(t, IsCircuitOpen(invoke()))
+	assert.Equal(t, 30*time.Second, cb.retryAfter())
+	assert.True(t, IsCircuitOpen(invoke()))
+	assert.Equal(t, 7, calls)
+
+	// a successful probe closes it
+	now = now.Add(31 * time.Second)
+	rpcErr = nil
+	assert.NoError(t, invoke())
+	assert.Zero(t, cb.retryAfter())
+	assert.NoError(t, invoke())
+	assert.Equal(t, 9, calls)
+
+	// disabled with a threshold of 0
+	cb.configure(0, 30*time.Second)
+	rpcErr = unavailable
+	for i := 0; i < 5; i++ {
+		assert.False(t, IsCircuitOpen(invoke()))
+	}
+	assert.Zero(t, cb.retryAfter())
+}
+
+func Test_MMServiceConfig(t *testing.T) {
+	for _, maxAttempts := range []int{1, 3} {
+		// dialing fails if the service config is invalid
+		conn, err := grpc.Dial("passthrough:///localhost:8033",
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultServiceConfig(mmServiceConfig(maxAttempts)))
+		assert.NoError(t, err)
+		conn.Close()
+	}
+	assert.NotContains(t, mmServiceConfig(1), "retryPolicy")
+	assert.Contains(t, mmServiceConfig(3), `"maxAttempts":3`)
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	mmClientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "modelmesh_controller_mm_client_requests_total",
+		Help: "Number of model-mesh management API calls made by the controller, by response code",
+	}, []string{"namespace", "method", "code"})
+	mmClientRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "modelmesh_controller_mm_client_request_duration_seconds",
+		Help:    "Latency of the model-mesh management API calls made by the controller",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"namespace", "method"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(mmClientRequests, mmClientRequestDuration)
+}
+
+// DeleteClientMetrics removes the model-mesh client metrics of a namespace which
+// is no longer modelmesh-enabled
+func DeleteClientMetrics(namespace string) {
+	mmClientRequests.DeletePartialMatch(prometheus.Labels{"namespace": namespace})
+	mmClientRequestDuration.DeletePartialMatch(prometheus.Labels{"namespace": namespace})
+}
+
+// clientMetrics records the metrics of the calls made by the model-mesh client of a namespace
+type clientMetrics string
+
+// methodName returns the name of the rpc of a full gRPC method name, e.g. setVModel
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+func (namespace clientMetrics) record(fullMethod string, start time.Time, err error) {
+	method := methodName(fullMethod)
+	mmClientRequests.WithLabelValues(string(namespace), method, status.Code(err).String()).Inc()
+	mmClientRequestDuration.WithLabelValues(string(namespace), method).Observe(time.Since(start).Seconds())
+}
+
+func (namespace clientMetrics) unaryInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	namespace.record(method, start, err)
+	return err
+}
+
+// streamInterceptor records the establishment of streams, their duration is that of the watch
+func (namespace clientMetrics) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	namespace.record(method, start, err)
+	return stream, err
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_ClientMetrics(t *testing.T) {
+	const namespace = "metrics-test"
+	metrics := clientMetrics(namespace)
+	invoke := func(method string, err error) {
+		_ = metrics.unaryInterceptor(context.Background(), method, nil, nil, nil,
+			func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				return err
+			})
+	}
+	invoke("/mmesh.ModelMesh/setVModel", nil)
+	invoke("/mmesh.ModelMesh/setVModel", nil)
+	invoke("/mmesh.ModelMesh/setVModel", status.Error(codes.Unavailable, "connection refused"))
+	invoke("/mmesh.ModelMesh/deleteVModel", nil)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(mmClientRequests.WithLabelValues(namespace, "setVModel", "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mmClientRequests.WithLabelValues(namespace, "setVModel", "Unavailable")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(mmClientRequests.WithLabelValues(namespace, "deleteVModel", "OK")))
+	assert.Equal(t, 2, testutil.CollectAndCount(mmClientRequestDuration, "modelmesh_controller_mm_client_request_duration_seconds"))
+
+	DeleteClientMetrics(namespace)
+	assert.Zero(t, testutil.CollectAndCount(mmClientRequests))
+	assert.Zero(t, testutil.CollectAndCount(mmClientRequestDuration))
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	etcd3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEmbeddedEtcd starts a single member etcd server which is stopped at the end of the test,
+// returning its client URL
+func startEmbeddedEtcd(t *testing.T) string {
+	freeURL := func() url.URL {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		return url.URL{Scheme: "http", Host: l.Addr().String()}
+	}
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.UnsafeNoFsync = true
+	clientURL, peerURL := freeURL(), freeURL()
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	t.Cleanup(e.Close)
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		e.Server.Stop()
+		t.Fatal("Timed out waiting for the embedded etcd server to start")
+	}
+	return clientURL.String()
+}
+
+// connectEmbeddedEtcd returns the KV store of model-mesh's root prefix along with a
+// separate client to make changes as model-mesh would
+func connectEmbeddedEtcd(t *testing.T, rootPrefix string) (KVStore, *etcd3.Client) {
+	endpoint := startEmbeddedEtcd(t)
+	kvStore, err := EtcdConfig{Endpoints: endpoint, RootPrefix: rootPrefix}.Connect(nil, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = kvStore.Close() })
+	client, err := etcd3.New(etcd3.Config{Endpoints: []string{endpoint}, DialTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return kvStore, client
+}
+
+func watchEmbeddedEtcd(t *testing.T, kvStore KVStore, prefix string) <-chan kvEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := make(chan kvEvent, 16)
+	go kvStore.WatchPrefix(ctx, logger, prefix, false, func(eventType KeyEventType, key string, value []byte) {
+		events <- kvEvent{eventType, key, string(value)}
+	})
+	return events
+}
+
+func Test_EmbeddedEtcd_LeaseExpiry(t *testing.T) {
+	kvStore, client := connectEmbeddedEtcd(t, "mm-root")
+	ctx := context.Background()
+	require.NoError(t, kvStore.Check(ctx))
+
+	// model-mesh registers its instances with a lease, which expires when the Pod goes away
+	lease, err := client.Grant(ctx, 1)
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "mm-root/mm/svc/instances/pod-a", "a", etcd3.WithLease(lease.ID))
+	require.NoError(t, err)
+	_, err = client.Put(ctx, "mm-root/mm/svc/instances/pod-b", "b")
+	require.NoError(t, err)
+
+	events := watchEmbeddedEtcd(t, kvStore, "mm-root/mm/svc/instances")
+	expectEvents(t, events,
+		kvEvent{UPDATE, "pod-a", "a"}, kvEvent{UPDATE, "pod-b", "b"}, kvEvent{INITIALIZED, "", ""})
+
+	// the key of the expired lease is deleted without any keepalives, etcd enforces a minimum TTL
+	select {
+	case e := <-events:
+		assert.Equal(t, kvEvent{DELETE, "pod-a", "a"}, e)
+	case <-time.After(15 * time.Second):
+		t.Fatal("Timed out waiting for the lease to expire")
+	}
+	records, err := kvStore.GetRecords(ctx, "mm-root/mm/svc/instances")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"pod-b": []byte("b")}, records)
+}
+
+func Test_EmbeddedEtcd_DeletePrefix(t *testing.T) {
+	kvStore, client := connectEmbeddedEtcd(t, "mm-root")
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		_, err := client.Put(ctx, fmt.Sprintf("mm-root/mm_ns/team-a/mm/svc/vmodels/vm%d", i), fmt.Sprintf("v%d", i))
+		require.NoError(t, err)
+	}
+	// keys sharing the prefix as a string but not as a path are kept
+	_, err := client.Put(ctx, "mm-root/mm_ns/team-ab/mm/svc/vmodels/vm1", "v1")
+	require.NoError(t, err)
+
+	events := watchEmbeddedEtcd(t, kvStore, "mm-root/mm_ns/team-a/mm/svc/vmodels")
+	expectEvents(t, events, kvEvent{UPDATE, "vm1", "v1"}, kvEvent{UPDATE, "vm2", "v2"},
+		kvEvent{UPDATE, "vm3", "v3"}, kvEvent{INITIALIZED, "", ""})
+
+	children, err := kvStore.ListChildren(ctx, "mm-root/mm_ns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-ab"}, children)
+
+	// the whole namespace is deleted at once, and the watch sees each key deleted
+	deleted, err := kvStore.DeletePrefix(ctx, "mm-root/mm_ns/team-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	expectEvents(t, events, kvEvent{DELETE, "vm1", "v1"}, kvEvent{DELETE, "vm2", "v2"}, kvEvent{DELETE, "vm3", "v3"})
+
+	children, err = kvStore.ListChildren(ctx, "mm-root/mm_ns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-ab"}, children)
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -28,6 +29,7 @@ import (
 	etcd3 "go.etcd.io/etcd/client/v3"
 	etcd3mirror "go.etcd.io/etcd/client/v3/mirror"
 	"google.golang.org/grpc"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type EtcdConfig struct {
@@ -49,6 +51,9 @@ type EtcdRangeWatcher struct {
 	logger      logr.Logger
 	etcdClient  *etcd3.Client
 	WatchPrefix string
+	// Revisions is optional, if set the last seen revision is persisted and the
+	// watch is resumed from it on restart rather than replaying every key
+	Revisions RevisionStore
 
 	//cache map[string]cacheEntry
 }
@@ -61,7 +66,11 @@ const (
 	INITIALIZED
 )
 
-const etcdDialTimeout = 10 * time.Second
+const (
+	etcdDialTimeout      = 10 * time.Second
+	revisionSaveInterval = 10 * time.Second
+	revisionSaveTimeout  = 5 * time.Second
+)
 
 var eventTypeNames = []string{
 	"UPDATE", "DELETE", "INITIALIZED",
@@ -96,104 +105,276 @@ func NewEtcdRangeWatcher(logger logr.Logger, etcd *etcd3.Client, prefix string)
 }
 
 func (r *EtcdRangeWatcher) Start(ctx context.Context, keysOnly bool, listener KvListener) {
+	go r.Run(ctx, keysOnly, listener)
+}
+
+// Run is like Start but blocks until ctx is cancelled, after which the listener won't be called
+func (r *EtcdRangeWatcher) Run(ctx context.Context, keysOnly bool, listener KvListener) {
 	log := r.logger.WithValues("WatchPrefix", r.WatchPrefix)
 	prefixBytes := len([]byte(r.WatchPrefix))
 	log.Info("EtcdRangeWatcher starting")
-	go func() {
-		initSent := false
-		cache := make(map[string]*cacheEntry)
-	refresh_loop:
-		for {
-			client := r.etcdClient
-			if keysOnly {
-				rokv := &keysOnlyKvAndWatcher{KV: client.KV, Watcher: client.Watcher}
-				koClient := *client
-				koClient.KV, koClient.Watcher = rokv, rokv
-				client = &koClient
+	initSent := false
+	cache := make(map[string]*cacheEntry)
+	// UPDATEs aren't sent for keys last modified at or before seenRev, since
+	// they were already seen prior to a restart. The keys known at that revision
+	// are cached, so that DELETEs are sent for those which no longer exist even if
+	// the revision has been compacted.
+	seenRev, known := r.loadRevision(ctx, log)
+	for k, v := range known {
+		cache[k] = &cacheEntry{kv: &etcd3kv.KeyValue{Value: v}}
+	}
+	startRev := seenRev
+	backoff := newEtcdRetryBackoff()
+	var snapshot *watchSnapshot
+	if r.Revisions != nil {
+		snapshot = &watchSnapshot{}
+		saved := make(chan struct{})
+		go func() {
+			defer close(saved)
+			r.saveRevisions(ctx, log, snapshot)
+		}()
+		defer func() { <-saved }()
+	}
+refresh_loop:
+	for {
+		client := r.etcdClient
+		if keysOnly {
+			rokv := &keysOnlyKvAndWatcher{KV: client.KV, Watcher: client.Watcher}
+			koClient := *client
+			koClient.KV, koClient.Watcher = rokv, rokv
+			client = &koClient
+		}
+		if startRev == 0 {
+			// determine the base revision here so that it can be persisted
+			resp, err := client.Get(ctx, r.WatchPrefix, etcd3.WithPrefix(), etcd3.WithCountOnly())
+			if err != nil {
+				if ctx.Err() != nil {
+					break // our context was cancelled
+				}
+				retryAfter := backoff.Step()
+				log.Error(etcdError(err), "Error refreshing key range, retrying", "retryAfter", retryAfter)
+				if !sleep(ctx, retryAfter) {
+					break
+				}
+				continue
 			}
-			syncer := etcd3mirror.NewSyncer(client, r.WatchPrefix, 0)
-			getChan, errChan := syncer.SyncBase(ctx)
-			for {
-				select {
-				case err, ok := <-errChan:
-					if !ok {
-						errChan = nil
-					} else {
-						if ctx.Err() != nil {
-							break // our context was cancelled
-						}
-						log.Error(err, "Error refreshing key range, retrying after 3sec")
-						//TODO handle this better and identify fatal case
-						time.Sleep(3 * time.Second)
+			startRev = resp.Header.Revision
+		}
+		// entries found by an interrupted sync must be found again
+		for _, entry := range cache {
+			entry.found = false
+		}
+		syncer := etcd3mirror.NewSyncer(client, r.WatchPrefix, startRev)
+		getChan, errChan := syncer.SyncBase(ctx)
+		for {
+			select {
+			case err, ok := <-errChan:
+				if !ok {
+					errChan = nil
+				} else {
+					if ctx.Err() != nil {
+						break refresh_loop // our context was cancelled
+					}
+					if err == etcd3rpc.ErrCompacted {
+						// the resumed revision is no longer available, resync from the
+						// latest and send UPDATEs only for keys modified since then
+						log.Info("Saved revision has been compacted, resyncing", "revision", startRev)
+						startRev = 0
 						continue refresh_loop
 					}
-				//TODO determine action based on err
-				case gr, ok := <-getChan:
-					if !ok {
-						getChan = nil
-					} else {
-						for _, kv := range gr.Kvs {
-							k := key(kv, prefixBytes)
-							if current, ok := cache[k]; !ok || kv.ModRevision > current.kv.ModRevision {
-								cache[k] = &cacheEntry{kv: kv, found: true}
+					retryAfter := backoff.Step()
+					log.Error(etcdError(err), "Error refreshing key range, retrying", "retryAfter", retryAfter)
+					//TODO handle this better and identify fatal case
+					if !sleep(ctx, retryAfter) {
+						break refresh_loop
+					}
+					startRev = 0
+					continue refresh_loop
+				}
+			//TODO determine action based on err
+			case gr, ok := <-getChan:
+				if !ok {
+					getChan = nil
+				} else {
+					for _, kv := range gr.Kvs {
+						k := key(kv, prefixBytes)
+						if current, ok := cache[k]; !ok || kv.ModRevision > current.kv.ModRevision {
+							cache[k] = &cacheEntry{kv: kv, found: true}
+							if kv.ModRevision > seenRev {
 								listener(UPDATE, k, kv.Value)
-							} else {
-								current.found = true
 							}
+						} else {
+							current.found = true
 						}
 					}
 				}
-				if errChan == nil && getChan == nil {
-					break
-				}
 			}
-			if !initSent {
-				listener(INITIALIZED, "", nil)
-				initSent = true
-			} else {
-				for k, entry := range cache {
-					if !entry.found {
+			if errChan == nil && getChan == nil {
+				break
+			}
+		}
+		backoff = newEtcdRetryBackoff()
+		seenRev = 0
+		if !initSent {
+			listener(INITIALIZED, "", nil)
+			initSent = true
+		}
+		for k, entry := range cache {
+			if !entry.found {
+				delete(cache, k)
+				listener(DELETE, k, entry.kv.Value)
+			}
+		}
+		snapshot.reset(startRev, cache)
+		// Watch phase
+		for wr := range syncer.SyncUpdates(ctx) {
+			for _, event := range wr.Events {
+				kv := event.Kv
+				k := key(kv, prefixBytes)
+				switch event.Type {
+				case etcd3kv.PUT:
+					cache[k] = &cacheEntry{kv: kv}
+					snapshot.put(k, kv.Value)
+					listener(UPDATE, k, kv.Value)
+				case etcd3kv.DELETE:
+					snapshot.delete(k)
+					if prev, ok := cache[k]; ok {
 						delete(cache, k)
-						listener(DELETE, k, entry.kv.Value)
+						listener(DELETE, k, prev.kv.Value)
 					} else {
-						entry.found = false
+						listener(DELETE, k, nil) // unexpected
 					}
 				}
 			}
-			// Watch phase
-			for wr := range syncer.SyncUpdates(ctx) {
-				for _, event := range wr.Events {
-					kv := event.Kv
-					k := key(kv, prefixBytes)
-					switch event.Type {
-					case etcd3kv.PUT:
-						cache[k] = &cacheEntry{kv: kv}
-						listener(UPDATE, k, kv.Value)
-					case etcd3kv.DELETE:
-						if prev, ok := cache[k]; ok {
-							delete(cache, k)
-							listener(DELETE, k, prev.kv.Value)
-						} else {
-							listener(DELETE, k, nil) // unexpected
-						}
-					}
-				}
-				err := wr.Err()
-				if err != nil {
-					if err == etcd3rpc.ErrCompacted {
-						// need to resync
-						log.Info("Received compacted error")
-						break
-					}
-					log.Error(err, "Watch failure")
-					//TODO handle
+			err := wr.Err()
+			if err != nil {
+				if err == etcd3rpc.ErrCompacted {
+					// need to resync
+					log.Info("Received compacted error")
+					break
 				}
+				log.Error(err, "Watch failure")
+				//TODO handle
+			} else if len(wr.Events) != 0 {
+				snapshot.setRevision(wr.Header.Revision)
 			}
-			if ctx.Err() != nil {
-				break // our context was cancelled
+		}
+		startRev = 0
+		if ctx.Err() != nil {
+			break // our context was cancelled
+		}
+	}
+}
+
+// newEtcdRetryBackoff returns the backoff between attempts to sync the key range,
+// from 1 second up to a minute
+func newEtcdRetryBackoff() wait.Backoff {
+	return wait.Backoff{Duration: time.Second, Factor: 2, Jitter: 0.1, Steps: 10, Cap: time.Minute}
+}
+
+func (r *EtcdRangeWatcher) loadRevision(ctx context.Context, log logr.Logger) (int64, map[string][]byte) {
+	if r.Revisions == nil {
+		return 0, nil
+	}
+	rev, known, err := r.Revisions.Load(ctx, r.WatchPrefix)
+	if err != nil {
+		log.Error(err, "Could not load saved watch revision, doing a full resync")
+		return 0, nil
+	}
+	if rev != 0 {
+		log.Info("Resuming watch from saved revision", "revision", rev, "keys", len(known))
+	}
+	return rev, known
+}
+
+// watchSnapshot holds the keys under the watched prefix at the last seen revision, to be
+// persisted by saveRevisions. Its methods do nothing on a nil snapshot.
+type watchSnapshot struct {
+	sync.Mutex
+	rev int64
+	kvs map[string][]byte
+}
+
+func (s *watchSnapshot) reset(rev int64, cache map[string]*cacheEntry) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.rev = rev
+	s.kvs = make(map[string][]byte, len(cache))
+	for k, entry := range cache {
+		s.kvs[k] = entry.kv.Value
+	}
+}
+
+func (s *watchSnapshot) put(key string, value []byte) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.kvs[key] = value
+}
+
+func (s *watchSnapshot) delete(key string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	delete(s.kvs, key)
+}
+
+func (s *watchSnapshot) setRevision(rev int64) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.rev = rev
+}
+
+// get returns the revision and a copy of the keys if the revision isn't the given one
+func (s *watchSnapshot) get(saved int64) (int64, map[string][]byte) {
+	s.Lock()
+	defer s.Unlock()
+	if s.rev == saved {
+		return saved, nil
+	}
+	kvs := make(map[string][]byte, len(s.kvs))
+	for k, v := range s.kvs {
+		kvs[k] = v
+	}
+	return s.rev, kvs
+}
+
+// saveRevisions periodically persists the last seen revision and keys until ctx is cancelled
+func (r *EtcdRangeWatcher) saveRevisions(ctx context.Context, log logr.Logger, snapshot *watchSnapshot) {
+	var saved int64
+	save := func() {
+		if rev, kvs := snapshot.get(saved); rev != saved {
+			// not using ctx since the last save happens after it's cancelled
+			saveCtx, cancel := context.WithTimeout(context.Background(), revisionSaveTimeout)
+			defer cancel()
+			if err := r.Revisions.Save(saveCtx, r.WatchPrefix, rev, kvs); err != nil {
+				log.Error(err, "Could not save watch revision", "revision", rev)
+			} else {
+				saved = rev
 			}
 		}
-	}()
+	}
+	ticker := time.NewTicker(revisionSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			save()
+			return
+		case <-ticker.C:
+			save()
+		}
+	}
 }
 
 func key(kv *etcd3kv.KeyValue, prefixBytes int) string {
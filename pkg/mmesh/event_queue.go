@@ -0,0 +1,185 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// maxPendingEvents is the number of distinct Predictors with pending events,
+// beyond which events are dropped and a resync of the namespace is sent instead
+const maxPendingEvents = 1024
+
+var (
+	modelEventsWatched = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "modelmesh_controller_model_events_watched_total",
+		Help: "Number of model-mesh registry events received from the KV store watches",
+	}, []string{"registry", "event"})
+	modelEventsCoalesced = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "modelmesh_controller_model_events_coalesced_total",
+		Help: "Number of model-mesh model events merged into an already pending Predictor event",
+	})
+	modelEventsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "modelmesh_controller_model_events_dropped_total",
+		Help: "Number of model-mesh model events dropped because too many Predictor events were pending",
+	})
+	modelEventsResyncs = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "modelmesh_controller_model_events_resyncs_total",
+		Help: "Number of namespace resyncs sent to the Predictor controller after events were dropped",
+	})
+	modelEventsPending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "modelmesh_controller_model_events_pending",
+		Help: "Number of Predictor events pending delivery to the Predictor controller",
+	})
+	modelEventsLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "modelmesh_controller_model_events_lag_seconds",
+		Help:    "Time from queueing a Predictor event or resync until its delivery to the Predictor controller",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60},
+	})
+	kvStoreAccessible = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "modelmesh_controller_kvstore_accessible",
+		Help: "Whether the KV store of each watched KV store secret could be accessed by its last check",
+	}, []string{"secret"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(modelEventsWatched, modelEventsCoalesced, modelEventsDropped, modelEventsResyncs,
+		modelEventsPending, modelEventsLag, kvStoreAccessible)
+}
+
+// ResyncEvent returns the event which signals that all Predictors in the given
+// model-mesh namespace must be reconciled, because some of their events were dropped
+func ResyncEvent(namespace string) event.GenericEvent {
+	return event.GenericEvent{Object: &v1.PartialObjectMetadata{
+		ObjectMeta: v1.ObjectMeta{Namespace: namespace},
+	}}
+}
+
+// IsResyncEvent returns the namespace to resync if the object is that of a ResyncEvent,
+// Predictor events always have a name
+func IsResyncEvent(o client.Object) (string, bool) {
+	return o.GetNamespace(), o.GetName() == ""
+}
+
+// eventQueue decouples the watches of model-mesh from the Predictor controller.
+// Adding an event never blocks, events for the same Predictor are coalesced while
+// pending delivery and when too many are pending a resync event is sent instead.
+type eventQueue struct {
+	mutex   sync.Mutex
+	pending []types.NamespacedName
+	// when each pending event was queued
+	queued map[types.NamespacedName]time.Time
+	// model-mesh namespaces in which events were dropped, and when the first was dropped
+	resyncs map[string]time.Time
+	// signalled when the queue becomes non-empty
+	notify chan struct{}
+}
+
+func newEventQueue() *eventQueue {
+	return &eventQueue{
+		queued:  map[types.NamespacedName]time.Time{},
+		resyncs: map[string]time.Time{},
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// add queues the event of the Predictor, namespace is that of model-mesh which
+// differs from the Predictor event's namespace when prefixed with a source id
+func (q *eventQueue) add(namespace string, e event.GenericEvent) {
+	nn := types.NamespacedName{Namespace: e.Object.GetNamespace(), Name: e.Object.GetName()}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if _, ok := q.queued[nn]; ok {
+		modelEventsCoalesced.Inc()
+		return
+	}
+	if len(q.queued) >= maxPendingEvents {
+		modelEventsDropped.Inc()
+		if _, ok := q.resyncs[namespace]; !ok {
+			q.resyncs[namespace] = time.Now()
+		}
+	} else {
+		q.queued[nn] = time.Now()
+		q.pending = append(q.pending, nn)
+		modelEventsPending.Inc()
+	}
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// next removes and returns the next event, resyncs are returned first
+func (q *eventQueue) next() (event.GenericEvent, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for namespace, queued := range q.resyncs {
+		delete(q.resyncs, namespace)
+		modelEventsResyncs.Inc()
+		modelEventsLag.Observe(time.Since(queued).Seconds())
+		return ResyncEvent(namespace), true
+	}
+	if len(q.pending) == 0 {
+		return event.GenericEvent{}, false
+	}
+	nn := q.pending[0]
+	q.pending[0] = types.NamespacedName{}
+	q.pending = q.pending[1:]
+	modelEventsLag.Observe(time.Since(q.queued[nn]).Seconds())
+	delete(q.queued, nn)
+	modelEventsPending.Dec()
+	return event.GenericEvent{Object: &v1.PartialObjectMetadata{
+		ObjectMeta: v1.ObjectMeta{Name: nn.Name, Namespace: nn.Namespace},
+	}}, true
+}
+
+// clear discards all pending events
+func (q *eventQueue) clear() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	modelEventsPending.Sub(float64(len(q.pending)))
+	q.pending = nil
+	q.queued = map[types.NamespacedName]time.Time{}
+	q.resyncs = map[string]time.Time{}
+}
+
+// deliver sends the queued events to the given channel until ctx is cancelled
+func (q *eventQueue) deliver(ctx context.Context, events chan<- event.GenericEvent) {
+	for {
+		e, ok := q.next()
+		if !ok {
+			select {
+			case <-q.notify:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case events <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
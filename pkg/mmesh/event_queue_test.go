@@ -0,0 +1,108 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func Test_EventQueue_Coalesce(t *testing.T) {
+	q := newEventQueue()
+	coalesced := testutil.ToFloat64(modelEventsCoalesced)
+
+	q.add("ns", vModelEvent("ns", "p1", "ksp"))
+	q.add("ns", vModelEvent("ns", "p2", "ksp"))
+	q.add("ns", vModelEvent("ns", "p1", "ksp"))
+	assert.Equal(t, coalesced+1, testutil.ToFloat64(modelEventsCoalesced))
+
+	e, ok := q.next()
+	require.True(t, ok)
+	assert.Equal(t, "p1", e.Object.GetName())
+	assert.Equal(t, "ksp_ns", e.Object.GetNamespace())
+
+	// no longer pending so not coalesced
+	q.add("ns", vModelEvent("ns", "p1", "ksp"))
+	e, _ = q.next()
+	assert.Equal(t, "p2", e.Object.GetName())
+	e, _ = q.next()
+	assert.Equal(t, "p1", e.Object.GetName())
+	_, ok = q.next()
+	assert.False(t, ok)
+}
+
+func Test_EventQueue_Overflow(t *testing.T) {
+	q := newEventQueue()
+	dropped := testutil.ToFloat64(modelEventsDropped)
+	resyncs := testutil.ToFloat64(modelEventsResyncs)
+
+	// nothing is receiving but adding never blocks
+	for i := 0; i < maxPendingEvents+10; i++ {
+		q.add("ns", vModelEvent("ns", fmt.Sprintf("p%d", i), ""))
+	}
+	assert.Equal(t, dropped+10, testutil.ToFloat64(modelEventsDropped))
+
+	// the resync is sent first, only once per namespace
+	e, ok := q.next()
+	require.True(t, ok)
+	namespace, resync := IsResyncEvent(e.Object)
+	assert.True(t, resync)
+	assert.Equal(t, "ns", namespace)
+	assert.Equal(t, resyncs+1, testutil.ToFloat64(modelEventsResyncs))
+
+	e, _ = q.next()
+	_, resync = IsResyncEvent(e.Object)
+	assert.False(t, resync)
+	assert.Equal(t, "p0", e.Object.GetName())
+	q.clear()
+}
+
+func Test_EventQueue_Deliver(t *testing.T) {
+	q := newEventQueue()
+	pending := testutil.ToFloat64(modelEventsPending)
+	events := make(chan event.GenericEvent)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		q.deliver(ctx, events)
+	}()
+
+	q.add("ns", vModelEvent("ns", "p1", ""))
+	select {
+	case e := <-events:
+		assert.Equal(t, "p1", e.Object.GetName())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+
+	// delivery stops even while blocked on a slow receiver
+	q.add("ns", vModelEvent("ns", "p2", ""))
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery to stop")
+	}
+	q.clear()
+	assert.Equal(t, pending, testutil.ToFloat64(modelEventsPending))
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	mmeshapi "github.com/kserve/modelmesh-serving/generated/mmesh"
+)
+
+const (
+	grpcWatchRetryInterval = 3 * time.Second
+	// used when model-mesh doesn't implement the watch rpc, it might be upgraded later
+	grpcWatchUnimplementedRetryInterval = 1 * time.Minute
+)
+
+// GrpcModelEventStream is a ModelEventSource which generates events for
+// Predictors from the watchModelEvents streaming rpc of model-mesh, using
+// the gRPC connection of the MMService in each watched namespace.
+type GrpcModelEventStream struct {
+	mmServices MMServiceLookup
+
+	// guards the fields below, since UpdateWatchedService is called from the
+	// service reconcile func while Start is called by the manager
+	mutex sync.Mutex
+
+	// watched services are retained while stopped and the watches are
+	// re-established when the stream is started again
+	watchedServices map[string]*namespaceWatch
+
+	// events are queued by the watches and delivered to mmEvents
+	queue    *eventQueue
+	mmEvents chan event.GenericEvent
+	// ctx is nil while the stream isn't running
+	ctx     context.Context
+	watches sync.WaitGroup
+
+	logger logr.Logger
+}
+
+func NewGrpcModelEventStream(logger logr.Logger, mmServices MMServiceLookup) (*GrpcModelEventStream, error) {
+	if mmServices == nil {
+		return nil, fmt.Errorf("mmServices must not be nil")
+	}
+	return &GrpcModelEventStream{
+		mmServices:      mmServices,
+		watchedServices: map[string]*namespaceWatch{},
+		queue:           newEventQueue(),
+		mmEvents:        make(chan event.GenericEvent),
+		logger:          logger,
+	}, nil
+}
+
+// Events returns the channel of the current or next run of the stream, it's
+// closed when the stream is stopped
+func (gs *GrpcModelEventStream) Events() <-chan event.GenericEvent {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	return gs.mmEvents
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (gs *GrpcModelEventStream) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable, it (re-)establishes the watches of the
+// watched services and blocks until ctx is cancelled
+func (gs *GrpcModelEventStream) Start(ctx context.Context) error {
+	gs.mutex.Lock()
+	if gs.ctx != nil {
+		gs.mutex.Unlock()
+		return fmt.Errorf("GrpcModelEventStream is already started")
+	}
+	gs.logger.Info("Starting ModelMesh event stream")
+	gs.ctx = ctx
+	events := gs.mmEvents
+	gs.watches.Add(1)
+	go func() {
+		defer gs.watches.Done()
+		gs.queue.deliver(ctx, events)
+	}()
+	for namespace, nw := range gs.watchedServices {
+		gs.startWatch(nw, namespace, nw.watchedServiceName)
+	}
+	gs.mutex.Unlock()
+
+	<-ctx.Done()
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.logger.Info("Stopping ModelMesh event stream")
+	gs.ctx = nil
+	for _, nw := range gs.watchedServices {
+		nw.cancelWatch()
+	}
+	gs.watches.Wait()
+	gs.queue.clear()
+	drainAndClose(gs.mmEvents)
+	gs.mmEvents = make(chan event.GenericEvent)
+	return nil
+}
+
+// UpdateWatchedService is called from service reconciler, the KV store secret isn't used
+func (gs *GrpcModelEventStream) UpdateWatchedService(_ context.Context,
+	_ types.NamespacedName, serviceName, namespace string) error {
+
+	if serviceName == "" {
+		return fmt.Errorf("serviceName must not be an empty string")
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	nw, ok := gs.watchedServices[namespace]
+	if !ok {
+		nw = &namespaceWatch{}
+		gs.watchedServices[namespace] = nw
+	}
+	if serviceName != nw.watchedServiceName {
+		nw.cancelWatch()
+		if gs.ctx != nil {
+			gs.startWatch(nw, namespace, serviceName)
+		}
+		nw.watchedServiceName = serviceName
+	}
+	return nil
+}
+
+func (gs *GrpcModelEventStream) startWatch(nw *namespaceWatch, namespace, serviceName string) {
+	var watchCtx context.Context
+	watchCtx, nw.cancelFunc = context.WithCancel(gs.ctx)
+	logger := gs.logger.WithValues("namespace", namespace, "service", serviceName)
+	logger.Info("Initialize Model Event Stream")
+	gs.watches.Add(1)
+	go func() {
+		defer gs.watches.Done()
+		gs.watch(watchCtx, namespace, logger)
+	}()
+}
+
+// RemoveWatchedService is called from service reconciler
+func (gs *GrpcModelEventStream) RemoveWatchedService(serviceName, namespace string) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	nw, ok := gs.watchedServices[namespace]
+	if ok && nw.watchedServiceName == serviceName {
+		delete(gs.watchedServices, namespace)
+		nw.cancelWatch()
+	}
+}
+
+// watch (re-)establishes the event stream until the context is cancelled. The
+// MMService client is looked up each time since it's replaced on reconnection.
+func (gs *GrpcModelEventStream) watch(ctx context.Context, namespace string, logger logr.Logger) {
+	for {
+		retryInterval := grpcWatchRetryInterval
+		var client mmeshapi.ModelMeshClient
+		if mms := gs.mmServices(namespace); mms != nil {
+			client = mms.MMClient()
+		}
+		if client == nil {
+			logger.V(1).Info("Waiting for model-mesh gRPC connection")
+		} else if err := gs.receive(ctx, client, namespace, logger); ctx.Err() != nil {
+			return
+		} else if status.Code(err) == codes.Unimplemented {
+			logger.Error(err, "model-mesh does not support the watchModelEvents rpc, the etcd model event source must be used")
+			retryInterval = grpcWatchUnimplementedRetryInterval
+		} else {
+			logger.Info("Model event stream ended, reconnecting", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (gs *GrpcModelEventStream) receive(ctx context.Context, client mmeshapi.ModelMeshClient,
+	namespace string, logger logr.Logger) error {
+	stream, err := client.WatchModelEvents(ctx, &mmeshapi.WatchModelEventsRequest{
+		Models:          true,
+		VModels:         true,
+		IncludeExisting: true,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		me, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if e, ok := grpcModelEvent(namespace, me, logger); ok {
+			gs.queue.add(namespace, e)
+		}
+	}
+}
+
+// grpcModelEvent returns the Predictor event for the given model-mesh event, if any
+func grpcModelEvent(namespace string, me *mmeshapi.ModelEvent, logger logr.Logger) (event.GenericEvent, bool) {
+	switch me.Kind {
+	case mmeshapi.ModelEvent_VMODEL:
+		logger.V(1).Info("ModelMesh VModel Event",
+			"vModelId", me.Id, "owner", me.Owner, "event", me.Type)
+		return vModelEvent(namespace, me.Id, me.Owner), true
+	case mmeshapi.ModelEvent_MODEL:
+		logger.V(1).Info("ModelMesh Model Event", "modelId", me.Id, "event", me.Type)
+		if me.Type == mmeshapi.ModelEvent_DELETED {
+			return event.GenericEvent{}, false
+		}
+		if e, ok := modelEvent(namespace, me.Id); ok {
+			return e, true
+		}
+		logger.Info("Ignoring event for unrecognized ModelMesh model",
+			"modelId", me.Id, "eventType", me.Type)
+	}
+	return event.GenericEvent{}, false
+}
@@ -17,6 +17,7 @@ package mmesh
 import (
 	"context"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
@@ -25,11 +26,11 @@ import (
 	"github.com/go-logr/logr"
 
 	"google.golang.org/grpc/resolver"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 )
 
 const KUBE_SCHEME = "kube"
@@ -69,7 +70,17 @@ func (sr *serviceResolver) Close() {
 // InitGrpcResolver should only be called once
 func InitGrpcResolver(defaultNamespace string, mgr ctrl.Manager) (*KubeResolver, error) {
 	kr := makeKubeResolver(defaultNamespace, mgr.GetClient())
-	err := ctrl.NewControllerManagedBy(mgr).For(&corev1.Endpoints{}).Complete(kr)
+	// a Service may have multiple EndpointSlices, reconcile per Service
+	err := ctrl.NewControllerManagedBy(mgr).Named("KubeResolver").
+		Watches(&discoveryv1.EndpointSlice{}, handler.EnqueueRequestsFromMapFunc(
+			func(_ context.Context, o client.Object) []ctrl.Request {
+				if svcName := o.GetLabels()[discoveryv1.LabelServiceName]; svcName != "" {
+					return []ctrl.Request{{NamespacedName: types.NamespacedName{
+						Name: svcName, Namespace: o.GetNamespace()}}}
+				}
+				return []ctrl.Request{}
+			})).
+		Complete(kr)
 	if err != nil {
 		return nil, err
 	}
@@ -149,38 +160,30 @@ func (kr *KubeResolver) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	if list, ok := kr.resolvers[req.NamespacedName]; ok {
 		return kr.reconcile(ctx, req, list, log)
 	}
-	log.Info("Ignoring event for Endpoints with no resolver", "endpoints", req.NamespacedName)
+	log.Info("Ignoring event for EndpointSlices with no resolver", "service", req.NamespacedName)
 	return ctrl.Result{}, nil
 }
 
 // called under lock
 func (kr *KubeResolver) reconcile(ctx context.Context, req ctrl.Request,
 	list []*serviceResolver, log logr.Logger) (ctrl.Result, error) {
-	endpoints := &corev1.Endpoints{}
-	err := kr.Get(ctx, req.NamespacedName, endpoints)
-	if err != nil {
-		if !errors.IsNotFound(err) {
-			return ctrl.Result{}, fmt.Errorf("error obtaining endpoints for service %s: %w",
-				req.NamespacedName, err)
-		} else {
-			log.Info("Endpoints not found", "endpoints", req.NamespacedName)
-		}
+	slices := &discoveryv1.EndpointSliceList{}
+	if err := kr.List(ctx, slices, client.InNamespace(req.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: req.Name}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("error obtaining endpoint slices for service %s: %w",
+			req.NamespacedName, err)
+	}
+	if len(slices.Items) == 0 {
+		log.Info("EndpointSlices not found", "service", req.NamespacedName)
 	}
 	result := ctrl.Result{}
 	var updateError error
 	for _, r := range list {
-		if errors.IsNotFound(err) {
-			r.cc.ReportError(fmt.Errorf("kube Service %s not found", req.NamespacedName))
+		if len(slices.Items) == 0 {
+			r.cc.ReportError(fmt.Errorf("no EndpointSlices found for kube Service %s", req.NamespacedName))
 			continue // not an error from reconciler pov
 		}
-		var addrs []resolver.Address
-		for _, s := range endpoints.Subsets {
-			if p := hasTargetPort(&s, r.port); p > 0 {
-				for _, ea := range s.Addresses {
-					addrs = append(addrs, resolver.Address{Addr: fmt.Sprintf("%s:%d", ea.IP, p)})
-				}
-			}
-		}
+		addrs := resolverAddresses(slices.Items, r.port)
 		if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
 			if err.Error() == "bad resolver state" {
 				// This is possible/expected when we are reconfiguring the client
@@ -191,17 +194,87 @@ func (kr *KubeResolver) reconcile(ctx context.Context, req ctrl.Request,
 			}
 		} else {
 			log.Info("Updated resolver state with new endpoints",
-				"endpoints", req.NamespacedName, "count", len(addrs))
+				"service", req.NamespacedName, "count", len(addrs))
 		}
 	}
 	return result, updateError
 }
 
-// returns int32 port number if port string matches name or number of port in EndpointSubset
-func hasTargetPort(s *corev1.EndpointSubset, port string) int32 {
-	for _, p := range s.Ports {
-		if p.Name == port || strconv.Itoa(int(p.Port)) == port {
-			return p.Port
+// resolverAddresses returns the addresses of the ready endpoints in the given slices for the given port.
+// Endpoints which are terminating but still serving are only used if there are no ready endpoints.
+// For dual-stack Services only the IPv4 addresses are used if there are any, so that each pod is
+// included once.
+func resolverAddresses(slices []discoveryv1.EndpointSlice, port string) []resolver.Address {
+	type familyAddrs struct{ ready, serving []string }
+	byFamily := map[discoveryv1.AddressType]*familyAddrs{
+		discoveryv1.AddressTypeIPv4: {},
+		discoveryv1.AddressTypeIPv6: {},
+	}
+	// the same endpoint can transiently appear in more than one slice
+	seen := make(map[string]struct{})
+	for i := range slices {
+		s := &slices[i]
+		fa, ok := byFamily[s.AddressType]
+		if !ok {
+			continue // FQDN slices are not supported
+		}
+		p := hasTargetPort(s.Ports, port)
+		if p <= 0 {
+			continue
+		}
+		for _, ep := range s.Endpoints {
+			// nil conditions are to be interpreted as ready / serving
+			ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+			serving := ep.Conditions.Serving == nil || *ep.Conditions.Serving
+			if !ready && !serving {
+				continue
+			}
+			for _, ip := range ep.Addresses {
+				addr := net.JoinHostPort(ip, strconv.Itoa(int(p)))
+				if _, dup := seen[addr]; dup {
+					continue
+				}
+				seen[addr] = struct{}{}
+				if ready {
+					fa.ready = append(fa.ready, addr)
+				} else {
+					fa.serving = append(fa.serving, addr)
+				}
+			}
+		}
+	}
+
+	var selected []string
+	for _, family := range []discoveryv1.AddressType{discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6} {
+		if fa := byFamily[family]; len(fa.ready) > 0 {
+			selected = fa.ready
+			break
+		}
+	}
+	if selected == nil {
+		for _, family := range []discoveryv1.AddressType{discoveryv1.AddressTypeIPv4, discoveryv1.AddressTypeIPv6} {
+			if fa := byFamily[family]; len(fa.serving) > 0 {
+				selected = fa.serving
+				break
+			}
+		}
+	}
+
+	var addrs []resolver.Address
+	for _, addr := range selected {
+		addrs = append(addrs, resolver.Address{Addr: addr})
+	}
+	return addrs
+}
+
+// returns int32 port number if port string matches name or number of port in EndpointSlice
+func hasTargetPort(ports []discoveryv1.EndpointPort, port string) int32 {
+	for _, p := range ports {
+		if p.Port == nil {
+			continue
+		}
+		if (p.Name != nil && *p.Name == port) || strconv.Itoa(int(*p.Port)) == port {
+			return *p.Port
 		}
 	}
 	return -1
@@ -23,7 +23,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/resolver"
 	"google.golang.org/grpc/serviceconfig"
-	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -33,16 +33,17 @@ import (
 
 type mockClient struct {
 	client.Client
-	t       *testing.T
-	getfunc func(context.Context, client.ObjectKey, *v1.Endpoints, []client.GetOption) error
+	t        *testing.T
+	listfunc func(context.Context, *discoveryv1.EndpointSliceList) error
 }
 
-func (m mockClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+func (m mockClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
 	assert.NotNil(m.t, ctx)
-	assert.IsType(m.t, &v1.Endpoints{}, obj)
-	assert.Equal(m.t, "modelmesh-serving", key.Name)
-	assert.Equal(m.t, "namespace", key.Namespace)
-	return m.getfunc(ctx, key, obj.(*v1.Endpoints), opts)
+	assert.IsType(m.t, &discoveryv1.EndpointSliceList{}, list)
+	lo := (&client.ListOptions{}).ApplyOptions(opts)
+	assert.Equal(m.t, "namespace", lo.Namespace)
+	assert.Equal(m.t, discoveryv1.LabelServiceName+"=modelmesh-serving", lo.LabelSelector.String())
+	return m.listfunc(ctx, list.(*discoveryv1.EndpointSliceList))
 }
 
 type mockCC struct {
@@ -59,16 +60,16 @@ func (m mockCC) UpdateState(state resolver.State) error {
 // Test for basic functionality
 func Test_KubeResolver_AddRemove(t *testing.T) {
 	mClient := mockClient{t: t}
-	mClient.getfunc = func(ctx context.Context, key client.ObjectKey, ep *v1.Endpoints, opts []client.GetOption) error {
-		ep.Subsets = []v1.EndpointSubset{
+	mClient.listfunc = func(ctx context.Context, list *discoveryv1.EndpointSliceList) error {
+		list.Items = []discoveryv1.EndpointSlice{
 			{
-				Addresses: []v1.EndpointAddress{
-					{IP: "1.2.3.4"},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints: []discoveryv1.Endpoint{
+					{Addresses: []string{"1.2.3.4"}},
 				},
-				NotReadyAddresses: []v1.EndpointAddress{},
-				Ports: []v1.EndpointPort{
-					{Name: "grpc", Port: 8033},
-					{Name: "prometheus", Port: 2112},
+				Ports: []discoveryv1.EndpointPort{
+					endpointPort("grpc", 8033),
+					endpointPort("prometheus", 2112),
 				},
 			},
 		}
@@ -136,6 +137,93 @@ func Test_KubeResolver_AddRemove(t *testing.T) {
 	updateStateCalled, updateState2Called = false, false
 }
 
+func Test_ResolverAddresses(t *testing.T) {
+	ready, notReady := true, false
+	ports := []discoveryv1.EndpointPort{endpointPort("grpc", 8033), endpointPort("prometheus", 2112)}
+	readyEp := func(ip string) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Addresses: []string{ip}, Conditions: discoveryv1.EndpointConditions{Ready: &ready}}
+	}
+	terminatingEp := func(ip string, serving bool) discoveryv1.Endpoint {
+		return discoveryv1.Endpoint{Addresses: []string{ip}, Conditions: discoveryv1.EndpointConditions{
+			Ready: &notReady, Serving: &serving, Terminating: &ready,
+		}}
+	}
+	slice := func(at discoveryv1.AddressType, eps ...discoveryv1.Endpoint) discoveryv1.EndpointSlice {
+		return discoveryv1.EndpointSlice{AddressType: at, Endpoints: eps, Ports: ports}
+	}
+
+	tests := []struct {
+		name     string
+		slices   []discoveryv1.EndpointSlice
+		port     string
+		expected []string
+	}{
+		{
+			name: "multiple slices",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv4, readyEp("1.2.3.4"), terminatingEp("1.2.3.5", true)),
+				slice(discoveryv1.AddressTypeIPv4, readyEp("1.2.3.6"), readyEp("1.2.3.4")),
+			},
+			port:     "grpc",
+			expected: []string{"1.2.3.4:8033", "1.2.3.6:8033"},
+		},
+		{
+			name: "port by number",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv4, readyEp("1.2.3.4")),
+			},
+			port:     "2112",
+			expected: []string{"1.2.3.4:2112"},
+		},
+		{
+			name: "port not found",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv4, readyEp("1.2.3.4")),
+			},
+			port: "http",
+		},
+		{
+			name: "only serving terminating endpoints",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv4, terminatingEp("1.2.3.4", true), terminatingEp("1.2.3.5", false)),
+			},
+			port:     "grpc",
+			expected: []string{"1.2.3.4:8033"},
+		},
+		{
+			name: "dual-stack",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv6, readyEp("fd00::1")),
+				slice(discoveryv1.AddressTypeIPv4, readyEp("1.2.3.4")),
+			},
+			port:     "grpc",
+			expected: []string{"1.2.3.4:8033"},
+		},
+		{
+			name: "ipv6 only",
+			slices: []discoveryv1.EndpointSlice{
+				slice(discoveryv1.AddressTypeIPv6, readyEp("fd00::1")),
+				slice(discoveryv1.AddressTypeIPv4, terminatingEp("1.2.3.4", true)),
+			},
+			port:     "grpc",
+			expected: []string{"[fd00::1]:8033"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var addrs []string
+			for _, a := range resolverAddresses(tt.slices, tt.port) {
+				addrs = append(addrs, a.Addr)
+			}
+			assert.Equal(t, tt.expected, addrs)
+		})
+	}
+}
+
+func endpointPort(name string, port int32) discoveryv1.EndpointPort {
+	return discoveryv1.EndpointPort{Name: &name, Port: &port}
+}
+
 func reconcile(t *testing.T, kr *KubeResolver) {
 	fmt.Println("Reconcile")
 	_, err := kr.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{
@@ -146,7 +234,7 @@ func reconcile(t *testing.T, kr *KubeResolver) {
 
 // Unused mock funcs
 
-func (m mockClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+func (m mockClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
 	m.t.Error("should not be called")
 	return nil
 }
@@ -0,0 +1,285 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	etcd3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcd3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+)
+
+// KVStoreConfig is the configuration of the KV store used by model-mesh for its
+// registries. It's held as json in the KV store secret, under a key which
+// determines the type of the store.
+type KVStoreConfig interface {
+	// Type is one of etcd or zookeeper
+	Type() string
+	// SecretKey is the key of the KV store secret which holds the json config
+	SecretKey() string
+	// Prefix is the root prefix under which model-mesh stores its data
+	Prefix() string
+	// ForNamespace returns a copy of the config with the root prefix of the given user namespace
+	ForNamespace(namespace string) KVStoreConfig
+	// Connect creates a client of the KV store, any files referenced by the
+	// config are looked up in the secret data
+	Connect(secretData map[string][]byte, logger logr.Logger) (KVStore, error)
+}
+
+// KVStore is a client of the KV store used by model-mesh
+type KVStore interface {
+	// WatchPrefix calls the listener with each key under the given prefix, relative to the
+	// prefix, followed by INITIALIZED and then each subsequent change. It blocks until ctx
+	// is cancelled and the listener won't be called after it returns.
+	WatchPrefix(ctx context.Context, logger logr.Logger, prefix string, keysOnly bool, listener KvListener)
+	// Check verifies that the KV store can be accessed, returning a *KVStoreError if not
+	Check(ctx context.Context) error
+	// ListChildren returns the distinct names of the keys directly beneath the given prefix,
+	// i.e. the first segments of their paths relative to the prefix
+	ListChildren(ctx context.Context, prefix string) ([]string, error)
+	// DeletePrefix deletes all keys beneath the given prefix, returning how many were deleted
+	DeletePrefix(ctx context.Context, prefix string) (int64, error)
+	// GetRecords returns the values of the keys directly beneath the given prefix, keyed by
+	// their names relative to it
+	GetRecords(ctx context.Context, prefix string) (map[string][]byte, error)
+	// CreateRecord sets the value of the key beneath the given prefix unless it already
+	// exists, returning whether it was created
+	CreateRecord(ctx context.Context, prefix, name string, value []byte) (bool, error)
+	Close() error
+}
+
+// KVStoreErrorReason categorizes failures to access the KV store
+type KVStoreErrorReason string
+
+const (
+	KVStoreUnreachable  KVStoreErrorReason = "Unreachable"
+	KVStoreAuthFailed   KVStoreErrorReason = "AuthenticationFailed"
+	KVStoreTLSFailed    KVStoreErrorReason = "TLSFailed"
+	KVStoreUnknownError KVStoreErrorReason = "Unknown"
+)
+
+// KVStoreError is a failure to access the KV store
+type KVStoreError struct {
+	// Type is one of etcd or zookeeper
+	Type   string
+	Reason KVStoreErrorReason
+	Err    error
+}
+
+func (e *KVStoreError) Error() string {
+	return fmt.Sprintf("%s access failed (%s): %v", e.Type, e.Reason, e.Err)
+}
+
+func (e *KVStoreError) Unwrap() error {
+	return e.Err
+}
+
+// KVStoreConfigFromSecret parses the KV store config from the data of the named KV store secret
+func KVStoreConfigFromSecret(secretName string, data map[string][]byte) (KVStoreConfig, error) {
+	if b, ok := data[modelmesh.EtcdSecretKey]; ok {
+		var etcdConfig EtcdConfig
+		if err := json.Unmarshal(b, &etcdConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse etcd config json: %w", err)
+		}
+		return etcdConfig, nil
+	}
+	if b, ok := data[modelmesh.ZookeeperSecretKey]; ok {
+		var zkConfig ZookeeperConfig
+		if err := json.Unmarshal(b, &zkConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse zookeeper config json: %w", err)
+		}
+		if zkConfig.ConnectString == "" {
+			return nil, fmt.Errorf("connect_string must be set in zookeeper config of secret '%s'", secretName)
+		}
+		return zkConfig, nil
+	}
+	return nil, fmt.Errorf("Key '%s' or '%s' was not found in KV store secret '%s'",
+		modelmesh.EtcdSecretKey, modelmesh.ZookeeperSecretKey, secretName)
+}
+
+func namespaceRootPrefix(rootPrefix, namespace string) string {
+	return fmt.Sprintf("%s/%s", namespacesPrefix(rootPrefix), namespace) //TODO double check root prefix restrictions
+}
+
+// namespacesPrefix is the prefix beneath which each user namespace has its own root prefix
+func namespacesPrefix(rootPrefix string) string {
+	return rootPrefix + "/mm_ns"
+}
+
+func (ec EtcdConfig) Type() string {
+	return modelmesh.KVStoreEtcd
+}
+
+func (ec EtcdConfig) SecretKey() string {
+	return modelmesh.EtcdSecretKey
+}
+
+func (ec EtcdConfig) Prefix() string {
+	return ec.RootPrefix
+}
+
+func (ec EtcdConfig) ForNamespace(namespace string) KVStoreConfig {
+	ec.RootPrefix = namespaceRootPrefix(ec.RootPrefix, namespace)
+	return ec
+}
+
+func (ec EtcdConfig) Connect(secretData map[string][]byte, logger logr.Logger) (KVStore, error) {
+	client, err := CreateEtcdClient(ec, secretData, logger)
+	if err != nil {
+		return nil, err
+	}
+	return etcdKVStore{client: client, rootPrefix: ec.RootPrefix}, nil
+}
+
+// WithRevisionStore returns the given KV store with its watches resumed from the
+// revisions persisted in the RevisionStore, if the KV store supports it
+func WithRevisionStore(kvStore KVStore, revisions RevisionStore) KVStore {
+	if es, ok := kvStore.(etcdKVStore); ok {
+		es.revisions = revisions
+		return es
+	}
+	return kvStore
+}
+
+type etcdKVStore struct {
+	client     *etcd3.Client
+	rootPrefix string
+	revisions  RevisionStore
+}
+
+func (es etcdKVStore) WatchPrefix(ctx context.Context, logger logr.Logger, prefix string,
+	keysOnly bool, listener KvListener) {
+	w := NewEtcdRangeWatcher(logger, es.client, prefix+"/")
+	w.Revisions = es.revisions
+	w.Run(ctx, keysOnly, listener)
+}
+
+func (es etcdKVStore) Check(ctx context.Context) error {
+	// a minimal read of the keys under the root prefix verifies connectivity, as well as the
+	// credentials for users whose role only grants access to the prefix
+	if _, err := es.client.Get(ctx, es.rootPrefix+"/", etcd3.WithPrefix(), etcd3.WithCountOnly(),
+		etcd3.WithLimit(1)); err != nil {
+		return etcdError(err)
+	}
+	return nil
+}
+
+func (es etcdKVStore) ListChildren(ctx context.Context, prefix string) ([]string, error) {
+	dir := prefix + "/"
+	end := etcd3.GetPrefixRangeEnd(dir)
+	var children []string
+	// fetch a single key per child, skipping past the rest of its keys each time
+	for key := dir; ; {
+		resp, err := es.client.Get(ctx, key, etcd3.WithRange(end), etcd3.WithLimit(1),
+			etcd3.WithKeysOnly(), etcd3.WithSort(etcd3.SortByKey, etcd3.SortAscend))
+		if err != nil {
+			return nil, etcdError(err)
+		}
+		if len(resp.Kvs) == 0 {
+			return children, nil
+		}
+		child, _, isDir := strings.Cut(strings.TrimPrefix(string(resp.Kvs[0].Key), dir), "/")
+		if len(children) == 0 || children[len(children)-1] != child {
+			children = append(children, child)
+		}
+		if isDir {
+			// '0' is the character after '/', so this is the end of the child's range
+			key = dir + child + "0"
+		} else {
+			key = dir + child + "\x00"
+		}
+	}
+}
+
+func (es etcdKVStore) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	resp, err := es.client.Delete(ctx, prefix+"/", etcd3.WithPrefix())
+	if err != nil {
+		return 0, etcdError(err)
+	}
+	return resp.Deleted, nil
+}
+
+func (es etcdKVStore) GetRecords(ctx context.Context, prefix string) (map[string][]byte, error) {
+	dir := prefix + "/"
+	resp, err := es.client.Get(ctx, dir, etcd3.WithPrefix())
+	if err != nil {
+		return nil, etcdError(err)
+	}
+	records := make(map[string][]byte, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if name := strings.TrimPrefix(string(kv.Key), dir); !strings.Contains(name, "/") {
+			records[name] = kv.Value
+		}
+	}
+	return records, nil
+}
+
+func (es etcdKVStore) CreateRecord(ctx context.Context, prefix, name string, value []byte) (bool, error) {
+	key := prefix + "/" + name
+	resp, err := es.client.Txn(ctx).
+		If(etcd3.Compare(etcd3.CreateRevision(key), "=", 0)).
+		Then(etcd3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return false, etcdError(err)
+	}
+	return resp.Succeeded, nil
+}
+
+// etcdError wraps the error of an etcd request with the reason of the failure
+func etcdError(err error) *KVStoreError {
+	return &KVStoreError{Type: modelmesh.KVStoreEtcd, Reason: etcdErrorReason(err), Err: err}
+}
+
+func etcdErrorReason(err error) KVStoreErrorReason {
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var certVerificationErr *tls.CertificateVerificationError
+	code := status.Code(err)
+	switch {
+	case errors.Is(err, etcd3rpc.ErrAuthFailed), errors.Is(err, etcd3rpc.ErrInvalidAuthToken),
+		errors.Is(err, etcd3rpc.ErrPermissionDenied), errors.Is(err, etcd3rpc.ErrUserEmpty),
+		code == codes.Unauthenticated, code == codes.PermissionDenied:
+		return KVStoreAuthFailed
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &certInvalidErr), errors.As(err, &certVerificationErr):
+		return KVStoreTLSFailed
+	}
+	// gRPC only reports TLS handshake failures as part of the message
+	if msg := err.Error(); strings.Contains(msg, "x509: ") || strings.Contains(msg, "tls: ") ||
+		strings.Contains(msg, "authentication handshake failed") {
+		return KVStoreTLSFailed
+	}
+	if errors.Is(err, context.DeadlineExceeded) || code == codes.Unavailable || code == codes.DeadlineExceeded {
+		return KVStoreUnreachable
+	}
+	return KVStoreUnknownError
+}
+
+func (es etcdKVStore) Close() error {
+	return es.client.Close()
+}
@@ -0,0 +1,255 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+var (
+	kvStoreCleanupNamespaces = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "modelmesh_controller_kvstore_cleanup_namespaces_total",
+		Help: "Number of disabled namespaces whose model-mesh KV store data was deleted",
+	})
+	kvStoreCleanupKeys = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "modelmesh_controller_kvstore_cleanup_keys_total",
+		Help: "Number of model-mesh KV store keys deleted for disabled namespaces",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(kvStoreCleanupNamespaces, kvStoreCleanupKeys)
+}
+
+// KVStoreCleanup deletes the model-mesh KV store data of user namespaces which were deleted
+// or are no longer modelmesh-enabled, once they've been disabled for the configured grace
+// period. That's the data beneath their root prefix in the controller's KV store, and the
+// model-mesh data in the own KV store of disabled namespaces which still reference one. The
+// data in the controller's KV store of namespaces which have since moved to their own is
+// also deleted. It's a manager.Runnable which only runs in the leader, the grace period
+// starts over when leadership changes.
+type KVStoreCleanup struct {
+	logger              logr.Logger
+	k8sClient           client.Client
+	configProvider      *config.ConfigProvider
+	controllerNamespace string
+	// determines whether model-mesh is enabled in the namespace, false if it doesn't exist
+	namespaceEnabled func(ctx context.Context, namespace string) (bool, error)
+	// creates the KV store client, replaced in tests
+	connect func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error)
+	now     func() time.Time
+
+	// when the data of each namespace in each KV store was first found to be unused
+	disabledSince map[kvStoreCleanupTarget]time.Time
+}
+
+// kvStoreCleanupTarget is the data of a namespace in the KV store of a secret
+type kvStoreCleanupTarget struct {
+	namespace string
+	secret    types.NamespacedName
+}
+
+// KVStoreCleanupResult reports the deletion of a disabled namespace's data
+type KVStoreCleanupResult struct {
+	Namespace string
+	// the secret of the KV store which the data was deleted from
+	KVStoreSecret types.NamespacedName
+	// number of keys deleted, zero in dry-run mode
+	DeletedKeys int64
+	DryRun      bool
+}
+
+func NewKVStoreCleanup(logger logr.Logger, k8sClient client.Client, cp *config.ConfigProvider,
+	controllerNamespace string, namespaceEnabled func(ctx context.Context, namespace string) (bool, error)) *KVStoreCleanup {
+	return &KVStoreCleanup{
+		logger:              logger,
+		k8sClient:           k8sClient,
+		configProvider:      cp,
+		controllerNamespace: controllerNamespace,
+		namespaceEnabled:    namespaceEnabled,
+		connect: func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error) {
+			return config.Connect(secretData, logger)
+		},
+		now:           time.Now,
+		disabledSince: map[kvStoreCleanupTarget]time.Time{},
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable
+func (c *KVStoreCleanup) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable, it runs the cleanup at the configured interval
+// while enabled until ctx is cancelled
+func (c *KVStoreCleanup) Start(ctx context.Context) error {
+	c.disabledSince = map[kvStoreCleanupTarget]time.Time{}
+	for {
+		cfg := c.configProvider.GetConfig().KVStoreCleanup
+		if cfg.Enabled {
+			if _, err := c.Run(ctx, cfg); err != nil {
+				c.logger.Error(err, "KV store cleanup failed, retrying at the next interval")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(cfg.Interval):
+		}
+	}
+}
+
+// Run deletes the data of the namespaces which have been disabled for longer than the
+// grace period and returns what was deleted, or would have been in dry-run mode
+func (c *KVStoreCleanup) Run(ctx context.Context, cfg config.KVStoreCleanupConfig) ([]KVStoreCleanupResult, error) {
+	secretName := c.configProvider.GetConfig().GetEtcdSecretName()
+	kvStores := newNamespaceKVStores(c.k8sClient, c.controllerNamespace, secretName, c.connect)
+	defer kvStores.Close()
+	controllerSecret := kvStores.ControllerSecret()
+	kvStore, kvConfig, err := kvStores.Get(ctx, controllerSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := kvStore.ListChildren(ctx, namespacesPrefix(kvConfig.Prefix()))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list the namespaces in %s: %w", kvConfig.Type(), err)
+	}
+
+	now := c.now()
+	found := make(map[kvStoreCleanupTarget]struct{}, len(namespaces))
+	var results []KVStoreCleanupResult
+	cleanup := func(logger logr.Logger, target kvStoreCleanupTarget, used bool, kvStore KVStore, prefix string) {
+		found[target] = struct{}{}
+		if result, ok := c.cleanup(ctx, cfg, now, logger, target, used, kvStore, prefix); ok {
+			results = append(results, result)
+		}
+	}
+	for _, namespace := range namespaces {
+		logger := c.logger.WithValues("namespace", namespace)
+		secret, err := NamespaceKVStoreSecret(ctx, c.k8sClient, namespace, c.controllerNamespace, secretName)
+		if err != nil {
+			logger.Error(err, "Could not determine the KV store secret of the namespace")
+			continue
+		}
+		// the data of a namespace which now has its own KV store is no longer used
+		used := secret == controllerSecret
+		if used {
+			if used, err = c.namespaceEnabled(ctx, namespace); err != nil {
+				logger.Error(err, "Could not determine whether model-mesh is enabled in the namespace")
+				continue
+			}
+		}
+		cleanup(logger, kvStoreCleanupTarget{namespace: namespace, secret: controllerSecret}, used,
+			kvStore, namespaceRootPrefix(kvConfig.Prefix(), namespace))
+	}
+
+	// the namespaces which have their own KV store
+	namespaceList := &corev1.NamespaceList{}
+	if err = c.k8sClient.List(ctx, namespaceList); err != nil {
+		return results, fmt.Errorf("Failed to list the namespaces: %w", err)
+	}
+	for i := range namespaceList.Items {
+		namespace := namespaceList.Items[i].Name
+		secretName := namespaceList.Items[i].Annotations[constants.KVStoreSecretAnnotationKey]
+		if secretName == "" || namespace == c.controllerNamespace {
+			continue
+		}
+		logger := c.logger.WithValues("namespace", namespace, "kvStoreSecret", secretName)
+		target := kvStoreCleanupTarget{namespace: namespace, secret: types.NamespacedName{Name: secretName, Namespace: namespace}}
+		enabled, err := c.namespaceEnabled(ctx, namespace)
+		if err != nil {
+			logger.Error(err, "Could not determine whether model-mesh is enabled in the namespace")
+			continue
+		} else if enabled {
+			found[target] = struct{}{}
+			delete(c.disabledSince, target)
+			continue
+		}
+		nsKVStore, nsKVConfig, err := kvStores.Get(ctx, target.secret)
+		if err != nil {
+			logger.Error(err, "Could not access the KV store of the disabled namespace")
+			continue
+		}
+		// the root prefix of a namespace's own KV store isn't specific to the namespace
+		prefix := fmt.Sprintf("%s/%s", nsKVConfig.Prefix(), modelmesh.ModelMeshEtcdPrefix)
+		if services, err := nsKVStore.ListChildren(ctx, prefix); err != nil {
+			logger.Error(err, "Could not list the model-mesh data in the KV store of the disabled namespace")
+			continue
+		} else if len(services) != 0 {
+			cleanup(logger, target, false, nsKVStore, prefix)
+		}
+	}
+
+	// forget namespaces whose data was deleted by other means
+	for target := range c.disabledSince {
+		if _, ok := found[target]; !ok {
+			delete(c.disabledSince, target)
+		}
+	}
+	return results, nil
+}
+
+// cleanup deletes the data of the target beneath the prefix of the KV store once it's been unused for
+// longer than the grace period, returning the result if it was deleted, or would have been in dry-run mode
+func (c *KVStoreCleanup) cleanup(ctx context.Context, cfg config.KVStoreCleanupConfig, now time.Time, logger logr.Logger,
+	target kvStoreCleanupTarget, used bool, kvStore KVStore, prefix string) (KVStoreCleanupResult, bool) {
+	result := KVStoreCleanupResult{Namespace: target.namespace, KVStoreSecret: target.secret}
+	if used {
+		delete(c.disabledSince, target)
+		return result, false
+	}
+	since, ok := c.disabledSince[target]
+	if !ok {
+		since = now
+		c.disabledSince[target] = since
+		logger.Info("Found unused KV store data of a namespace", "prefix", prefix, "gracePeriod", cfg.GracePeriod)
+	}
+	if now.Sub(since) < cfg.GracePeriod {
+		return result, false
+	}
+	if cfg.DryRun {
+		logger.Info("Dry run, not deleting unused KV store data of the namespace", "prefix", prefix,
+			"disabledSince", since)
+		result.DryRun = true
+		return result, true
+	}
+	deleted, err := kvStore.DeletePrefix(ctx, prefix)
+	kvStoreCleanupKeys.Add(float64(deleted))
+	if err != nil {
+		logger.Error(err, "Failed to delete unused KV store data of the namespace", "prefix", prefix,
+			"deletedKeys", deleted)
+		return result, false
+	}
+	logger.Info("Deleted unused KV store data of the namespace", "prefix", prefix, "deletedKeys", deleted,
+		"disabledSince", since)
+	kvStoreCleanupNamespaces.Inc()
+	delete(c.disabledSince, target)
+	result.DeletedKeys = deleted
+	return result, true
+}
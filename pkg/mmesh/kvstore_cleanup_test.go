@@ -0,0 +1,156 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	etcd3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcd3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+func Test_KVStoreCleanup(t *testing.T) {
+	ctx := context.Background()
+	f, dedicated := newFakeEtcd(), newFakeEtcd()
+	f.put("root/mm/modelmesh-serving/vmodels/vm1", "v")
+	f.put("root/mm_ns/enabled/mm/modelmesh-serving/vmodels/vm1", "v")
+	f.put("root/mm_ns/disabled/mm/modelmesh-serving/registry/m1", "v")
+	f.put("root/mm_ns/disabled/mm/modelmesh-serving/vmodels/vm1", "v")
+	f.put("root/mm_ns/disabled-too/mm/modelmesh-serving/vmodels/vm1", "v")
+	// data left behind by a namespace which has since moved to its own KV store
+	f.put("root/mm_ns/moved/mm/modelmesh-serving/vmodels/vm1", "v")
+	dedicated.put("tenant/mm/modelmesh-serving/vmodels/vm1", "v")
+	dedicated.put("tenant/other/key", "v")
+
+	cp := config.NewConfigProviderForTest()
+	conf, err := config.NewMergedConfigFromString("")
+	require.NoError(t, err)
+	config.SetConfigForTest(cp, conf)
+	// the "tenant" namespace has its own KV store
+	kvStores := newTestNamespaceKVStores(t, f, dedicated)
+	require.NoError(t, kvStores.k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "moved",
+		Annotations: map[string]string{constants.KVStoreSecretAnnotationKey: "moved-etcd"},
+	}}))
+	enabled := map[string]bool{"enabled": true, "moved": true}
+	c := NewKVStoreCleanup(logger, kvStores.k8sClient, cp, testNamespace, func(_ context.Context, namespace string) (bool, error) {
+		return enabled[namespace], nil
+	})
+	c.connect = kvStores.connect
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	cfg := config.KVStoreCleanupConfig{Enabled: true, DryRun: true, GracePeriod: time.Hour}
+	controllerSecret := types.NamespacedName{Name: "model-serving-etcd", Namespace: testNamespace}
+	tenantSecret := types.NamespacedName{Name: "tenant-etcd", Namespace: "tenant"}
+
+	// the grace period starts when the namespace is first found to be disabled
+	results, err := c.Run(ctx, cfg)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// re-enabled before the grace period expired
+	enabled["disabled-too"] = true
+	now = now.Add(2 * time.Hour)
+	results, err = c.Run(ctx, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []KVStoreCleanupResult{
+		{Namespace: "disabled", KVStoreSecret: controllerSecret, DryRun: true},
+		{Namespace: "moved", KVStoreSecret: controllerSecret, DryRun: true},
+		{Namespace: "tenant", KVStoreSecret: tenantSecret, DryRun: true},
+	}, results)
+	assert.Equal(t, int64(2), f.count("root/mm_ns/disabled/"))
+
+	keys := testutil.ToFloat64(kvStoreCleanupKeys)
+	cfg.DryRun = false
+	results, err = c.Run(ctx, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []KVStoreCleanupResult{
+		{Namespace: "disabled", KVStoreSecret: controllerSecret, DeletedKeys: 2},
+		{Namespace: "moved", KVStoreSecret: controllerSecret, DeletedKeys: 1},
+		{Namespace: "tenant", KVStoreSecret: tenantSecret, DeletedKeys: 1},
+	}, results)
+	assert.Equal(t, keys+4, testutil.ToFloat64(kvStoreCleanupKeys))
+	assert.Equal(t, int64(0), f.count("root/mm_ns/disabled/"))
+	assert.Equal(t, int64(0), f.count("root/mm_ns/moved/"))
+	assert.Equal(t, int64(1), f.count("root/mm_ns/disabled-too/"))
+	assert.Equal(t, int64(1), f.count("root/mm_ns/enabled/"))
+	assert.Equal(t, int64(1), f.count("root/mm/"))
+	// only model-mesh's data is deleted from a namespace's own KV store
+	assert.Equal(t, int64(0), dedicated.count("tenant/mm/"))
+	assert.Equal(t, int64(1), dedicated.count("tenant/other/"))
+
+	// disabled again, the grace period starts over
+	enabled["disabled-too"] = false
+	results, err = c.Run(ctx, cfg)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	// the clients are closed after each run
+	assert.Equal(t, 4, f.closed)
+	assert.Equal(t, 4, dedicated.closed)
+}
+
+func Test_EtcdKVStore_ListChildren(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("root/mm_ns/ns/mm/a", "v")
+	f.put("root/mm_ns/ns/mm/b", "v")
+	f.put("root/mm_ns/ns-a", "v")
+	f.put("root/mm_ns/ns-b/mm/a", "v")
+	f.put("root/mm_nsx/other/a", "v")
+	es := etcdKVStore{client: f.client()}
+
+	children, err := es.ListChildren(context.Background(), "root/mm_ns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns-a", "ns-b", "ns"}, children)
+
+	deleted, err := es.DeletePrefix(context.Background(), "root/mm_ns/ns")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.Equal(t, int64(1), f.count("root/mm_ns/ns-b/"))
+}
+
+func Test_EtcdKVStore_Check(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("root/mm_ns/ns/mm/a", "v")
+	es := etcdKVStore{client: f.client(), rootPrefix: "root/mm_ns/ns"}
+
+	require.NoError(t, es.Check(context.Background()))
+	require.Len(t, f.gets, 1)
+	op := f.gets[0]
+	assert.Equal(t, "root/mm_ns/ns/", string(op.KeyBytes()))
+	assert.Equal(t, etcd3.GetPrefixRangeEnd("root/mm_ns/ns/"), string(op.RangeBytes()))
+	assert.True(t, op.IsCountOnly())
+
+	f.getErr = etcd3rpc.ErrPermissionDenied
+	var kvErr *KVStoreError
+	require.ErrorAs(t, es.Check(context.Background()), &kvErr)
+	assert.Equal(t, KVStoreAuthFailed, kvErr.Reason)
+}
+
+func (f *fakeEtcd) count(prefix string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return int64(len(f.state(prefix, etcd3.GetPrefixRangeEnd(prefix), f.rev)))
+}
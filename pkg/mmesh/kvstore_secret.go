@@ -0,0 +1,119 @@
+// Copyright 2021 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/go-logr/logr"
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// A KVStoreSecret represents the KV store (etcd or ZooKeeper) configuation for a user namespace
+type KVStoreSecret struct {
+	Log                 logr.Logger
+	Name                string
+	Namespace           string
+	ControllerNamespace string
+	Config              KVStoreConfig
+	// Data of the controller's KV store secret, other than the config itself it holds
+	// the certificates referenced by the config which are copied to the user namespace
+	Data   map[string][]byte
+	Scheme *runtime.Scheme
+}
+
+func (es KVStoreSecret) Apply(ctx context.Context, cl client.Client) error {
+	s := &corev1.Secret{}
+	err := cl.Get(ctx, types.NamespacedName{Name: es.Name, Namespace: es.Namespace}, s)
+	notfound := errors.IsNotFound(err)
+	if err != nil && !notfound {
+		return err
+	}
+	commonLabelValue := "modelmesh-controller"
+	existing := s.DeepCopy()
+
+	s.ObjectMeta = metav1.ObjectMeta{
+		Name:      es.Name,
+		Namespace: es.Namespace,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": commonLabelValue,
+		},
+	}
+	s.ResourceVersion = existing.ResourceVersion
+	if err = es.addData(s); err != nil {
+		return err
+	}
+
+	if notfound {
+		return cl.Create(ctx, s)
+	}
+	if reflect.DeepEqual(s.Data, existing.Data) && reflect.DeepEqual(s.Labels, existing.Labels) {
+		return nil
+	}
+	es.Log.Info("Updating KV store secret", "namespace", es.Namespace, "name", es.Name)
+	return cl.Update(ctx, s)
+}
+
+// NamespaceKVStoreSecret returns the KV store secret used by the namespace: the secret in the namespace
+// named by its KVStoreSecretAnnotationKey annotation if it has opted into its own KV store, otherwise the
+// controller's secret. The controller namespace always uses the controller's secret.
+func NamespaceKVStoreSecret(ctx context.Context, cl client.Client, namespace, controllerNamespace,
+	secretName string) (types.NamespacedName, error) {
+	controllerSecret := types.NamespacedName{Name: secretName, Namespace: controllerNamespace}
+	if namespace == controllerNamespace {
+		return controllerSecret, nil
+	}
+	n := &corev1.Namespace{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: namespace}, n); err != nil {
+		if errors.IsNotFound(err) {
+			return controllerSecret, nil
+		}
+		return controllerSecret, err
+	}
+	if name := n.Annotations[constants.KVStoreSecretAnnotationKey]; name != "" {
+		return types.NamespacedName{Name: name, Namespace: namespace}, nil
+	}
+	return controllerSecret, nil
+}
+
+// Add data to the provided secret
+func (es KVStoreSecret) addData(s *corev1.Secret) error {
+	b, err := json.Marshal(es.Config.ForNamespace(es.Namespace))
+	if err != nil {
+		return fmt.Errorf("error json-marshalling %s config: %w", es.Config.Type(), err)
+	}
+
+	// replace any previous content so that rotated certificates are propagated
+	s.Data = make(map[string][]byte, len(es.Data))
+	for k, v := range es.Data {
+		// only one of the KV store config keys may be present
+		if k != modelmesh.EtcdSecretKey && k != modelmesh.ZookeeperSecretKey {
+			s.Data[k] = v
+		}
+	}
+	s.Data[es.Config.SecretKey()] = b
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+)
+
+func Test_KVStoreSecret_Apply(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	es := KVStoreSecret{
+		Log:                 logger,
+		Name:                "model-serving-etcd",
+		Namespace:           "user-ns",
+		ControllerNamespace: testNamespace,
+		Config:              EtcdConfig{Endpoints: "https://etcd:2379", RootPrefix: "root", CertificateFile: "ca.crt"},
+		Data: map[string][]byte{
+			modelmesh.EtcdSecretKey: []byte(`{"endpoints": "https://etcd:2379", "root_prefix": "root", "certificate_file": "ca.crt"}`),
+			"ca.crt":                []byte("ca1"),
+		},
+		Scheme: scheme.Scheme,
+	}
+	require.NoError(t, es.Apply(ctx, cl))
+
+	s := &corev1.Secret{}
+	key := types.NamespacedName{Name: "model-serving-etcd", Namespace: "user-ns"}
+	require.NoError(t, cl.Get(ctx, key, s))
+	assert.Equal(t, []byte("ca1"), s.Data["ca.crt"])
+	assert.JSONEq(t, `{"endpoints": "https://etcd:2379", "root_prefix": "root/mm_ns/user-ns", "certificate_file": "ca.crt"}`,
+		string(s.Data[modelmesh.EtcdSecretKey]))
+
+	// unchanged content isn't updated
+	rv := s.ResourceVersion
+	require.NoError(t, es.Apply(ctx, cl))
+	require.NoError(t, cl.Get(ctx, key, s))
+	assert.Equal(t, rv, s.ResourceVersion)
+
+	// the rotated certificate is propagated
+	es.Data = map[string][]byte{modelmesh.EtcdSecretKey: es.Data[modelmesh.EtcdSecretKey], "ca.crt": []byte("ca2")}
+	require.NoError(t, es.Apply(ctx, cl))
+	require.NoError(t, cl.Get(ctx, key, s))
+	assert.Equal(t, []byte("ca2"), s.Data["ca.crt"])
+}
+
+func Test_NamespaceKVStoreSecret(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dedicated",
+			Annotations: map[string]string{constants.KVStoreSecretAnnotationKey: "own-etcd"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNamespace,
+			Annotations: map[string]string{constants.KVStoreSecretAnnotationKey: "own-etcd"}}},
+	).Build()
+	controllerSecret := types.NamespacedName{Name: "model-serving-etcd", Namespace: testNamespace}
+
+	tests := []struct {
+		namespace string
+		expected  types.NamespacedName
+	}{
+		{"shared", controllerSecret},
+		{"dedicated", types.NamespacedName{Name: "own-etcd", Namespace: "dedicated"}},
+		{"missing", controllerSecret},
+		// the annotation is ignored in the controller namespace
+		{testNamespace, controllerSecret},
+	}
+	for _, tt := range tests {
+		secret, err := NamespaceKVStoreSecret(ctx, cl, tt.namespace, testNamespace, "model-serving-etcd")
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, secret, tt.namespace)
+	}
+}
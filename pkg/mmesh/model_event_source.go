@@ -0,0 +1,97 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+// ModelEventSource generates events for Predictors based on changes to
+// the models/vmodels of the model-mesh Service in each watched namespace.
+// It's added to the manager so that the watches only run while leader.
+type ModelEventSource interface {
+	manager.LeaderElectionRunnable
+	manager.Runnable
+	// Events returns the channel on which the Predictor events are sent
+	Events() <-chan event.GenericEvent
+	// UpdateWatchedService is called from service reconciler with the KV store secret used by the namespace
+	UpdateWatchedService(ctx context.Context, kvSecret types.NamespacedName, serviceName, namespace string) error
+	// RemoveWatchedService is called from service reconciler
+	RemoveWatchedService(serviceName, namespace string)
+}
+
+// MMServiceLookup returns the MMService of the given namespace, or nil if there isn't one
+type MMServiceLookup func(namespace string) *MMService
+
+// NewModelEventSource returns the ModelEventSource of the configured type
+func NewModelEventSource(sourceType string, logger logr.Logger, k8sClient k8sClient.Client,
+	namespace string, mmServices MMServiceLookup) (ModelEventSource, error) {
+	switch sourceType {
+	case config.ModelEventSourceEtcd:
+		return NewModelEventStream(logger, k8sClient, namespace)
+	case config.ModelEventSourceGrpc:
+		return NewGrpcModelEventStream(logger, mmServices)
+	default:
+		return nil, fmt.Errorf("unsupported model event source type %q", sourceType)
+	}
+}
+
+// vModelEvent returns the Predictor event for a change to the given vmodel. The owner
+// of a vmodel is the id of the Predictor source, which is encoded in the namespace.
+func vModelEvent(namespace, vModelId, owner string) event.GenericEvent {
+	if owner != "" {
+		namespace = fmt.Sprintf("%s_%s", owner, namespace)
+	}
+	return event.GenericEvent{Object: &v1.PartialObjectMetadata{
+		ObjectMeta: v1.ObjectMeta{Name: vModelId, Namespace: namespace},
+	}}
+}
+
+// modelEvent returns the Predictor event for a change to the given concrete model, provided
+// that its id is of the form "<predictor-name>__<source-id>-<hash>" used for Predictors
+func modelEvent(namespace, modelId string) (event.GenericEvent, bool) {
+	if predictorName, sourceId, ok := modelOwner(modelId); ok {
+		return event.GenericEvent{Object: &v1.PartialObjectMetadata{ObjectMeta: v1.ObjectMeta{
+			Name:      predictorName,
+			Namespace: fmt.Sprintf("%s_%s", sourceId, namespace),
+		}}}, true
+	}
+	return event.GenericEvent{}, false
+}
+
+// modelOwner returns the Predictor name and source id of a concrete model id of
+// the form "<predictor-name>__<source-id>-<hash>"
+func modelOwner(modelId string) (predictorName, sourceId string, ok bool) {
+	ownerIdx := strings.LastIndex(modelId, "__") + 2
+	if ownerIdx > 2 {
+		hashIdx := len(modelId) - 11 // 11 is ('-' plus 10 hash chars)
+		if hashIdx > ownerIdx && modelId[hashIdx] == '-' {
+			// Infer predictor/vmodel and source ids from concrete model id by removing hash suffix
+			return modelId[:ownerIdx-2], modelId[ownerIdx:hashIdx], true
+		}
+	}
+	return "", "", false
+}
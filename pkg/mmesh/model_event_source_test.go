@@ -0,0 +1,95 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	mmeshapi "github.com/kserve/modelmesh-serving/generated/mmesh"
+)
+
+func Test_ModelEvent(t *testing.T) {
+	e, ok := modelEvent("ns", "my-predictor__isvc-0123456789")
+	assert.True(t, ok)
+	assert.Equal(t, types.NamespacedName{Name: "my-predictor", Namespace: "isvc_ns"}, client.ObjectKeyFromObject(e.Object))
+
+	// predictor names can contain double underscores
+	e, ok = modelEvent("ns", "my__predictor__ksp-0123456789")
+	assert.True(t, ok)
+	assert.Equal(t, types.NamespacedName{Name: "my__predictor", Namespace: "ksp_ns"}, client.ObjectKeyFromObject(e.Object))
+
+	for _, id := range []string{"my-model", "my-predictor__isvc", "my-predictor__isvc_0123456789", "__-0123456789"} {
+		_, ok = modelEvent("ns", id)
+		assert.False(t, ok, id)
+	}
+}
+
+func Test_VModelEvent(t *testing.T) {
+	e := vModelEvent("ns", "my-predictor", "isvc")
+	assert.Equal(t, types.NamespacedName{Name: "my-predictor", Namespace: "isvc_ns"}, client.ObjectKeyFromObject(e.Object))
+
+	e = vModelEvent("ns", "my-vmodel", "")
+	assert.Equal(t, types.NamespacedName{Name: "my-vmodel", Namespace: "ns"}, client.ObjectKeyFromObject(e.Object))
+}
+
+func Test_GrpcModelEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *mmeshapi.ModelEvent
+		expected *types.NamespacedName
+	}{
+		{
+			name: "vmodel added",
+			event: &mmeshapi.ModelEvent{Kind: mmeshapi.ModelEvent_VMODEL, Type: mmeshapi.ModelEvent_ADDED,
+				Id: "my-predictor", Owner: "isvc"},
+			expected: &types.NamespacedName{Name: "my-predictor", Namespace: "isvc_ns"},
+		},
+		{
+			name: "vmodel deleted",
+			event: &mmeshapi.ModelEvent{Kind: mmeshapi.ModelEvent_VMODEL, Type: mmeshapi.ModelEvent_DELETED,
+				Id: "my-predictor", Owner: "isvc"},
+			expected: &types.NamespacedName{Name: "my-predictor", Namespace: "isvc_ns"},
+		},
+		{
+			name: "model updated",
+			event: &mmeshapi.ModelEvent{Kind: mmeshapi.ModelEvent_MODEL, Type: mmeshapi.ModelEvent_UPDATED,
+				Id: "my-predictor__isvc-0123456789"},
+			expected: &types.NamespacedName{Name: "my-predictor", Namespace: "isvc_ns"},
+		},
+		{
+			name: "model deleted",
+			event: &mmeshapi.ModelEvent{Kind: mmeshapi.ModelEvent_MODEL, Type: mmeshapi.ModelEvent_DELETED,
+				Id: "my-predictor__isvc-0123456789"},
+		},
+		{
+			name:  "unrecognized model",
+			event: &mmeshapi.ModelEvent{Kind: mmeshapi.ModelEvent_MODEL, Type: mmeshapi.ModelEvent_ADDED, Id: "my-model"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := grpcModelEvent("ns", tt.event, logr.Discard())
+			assert.Equal(t, tt.expected != nil, ok)
+			if tt.expected != nil {
+				assert.Equal(t, *tt.expected, client.ObjectKeyFromObject(e.Object))
+			}
+		})
+	}
+}
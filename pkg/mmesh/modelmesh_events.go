@@ -16,45 +16,69 @@ package mmesh
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
-	etcd3 "go.etcd.io/etcd/client/v3"
 	v12 "k8s.io/api/core/v1"
-	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
-// ModelMeshEventStream generates events for Predictors based on
-// changes to models/vmodels within model-mesh's etcd-based registries.
-// This is unfortunately tightly coupled to certain model-mesh internal
-// details (i.e. etcd layout), and in future we would like to expose
-// streaming gRPC APIs from model-mesh for an "official" way to watch
-// for model events.
+// ModelMeshEventStream is a ModelEventSource which generates events for
+// Predictors based on changes to models/vmodels within model-mesh's
+// KV store (etcd or ZooKeeper) registries. This is unfortunately tightly
+// coupled to certain model-mesh internal details (i.e. KV store layout),
+// the GrpcModelEventStream uses the "official" streaming gRPC API of
+// model-mesh instead.
 type ModelMeshEventStream struct {
 	controllerNamespace string
 	k8sClient           k8sClient.Client
-
-	secretName     string
-	etcdClient     *etcd3.Client
-	etcdRootPrefix string
-
-	// accessed only in UpdateWatchedService func, called only from service reconcile func
+	// persists the last seen etcd revision of each watch across restarts
+	revisions RevisionStore
+	// creates the KV store client, replaced in tests
+	connect func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error)
+
+	// guards the fields below, since UpdateWatchedService is called from the
+	// service reconcile func while Start is called by the manager
+	mutex sync.Mutex
+
+	// the KV store secrets of the watched namespaces, the controller's secret is
+	// shared by all namespaces which don't have their own
+	secrets map[types.NamespacedName]*watchedSecret
+	// KV store clients keyed by kvClientKey, one per distinct set of endpoints and
+	// credentials, shared by the secrets which have the same
+	clients map[string]*sharedKVStore
+
+	// watched services are retained while stopped and the watches are
+	// re-established when the stream is started again
 	watchedServices map[string]*namespaceWatch
 
-	MMEvents chan event.GenericEvent
-	ctx      context.Context
+	// events are queued by the watches and delivered to mmEvents
+	queue    *eventQueue
+	mmEvents chan event.GenericEvent
+	// ctx is nil while the stream isn't running, i.e. before it's started
+	// by the manager upon leader election or after it's been stopped
+	ctx     context.Context
+	watches sync.WaitGroup
 
 	logger logr.Logger
 }
 
 type namespaceWatch struct {
 	watchedServiceName string
-	cancelFunc         context.CancelFunc
+	// the KV store secret of the namespace, empty until the service is reconciled
+	secret     types.NamespacedName
+	cancelFunc context.CancelFunc
 }
 
 func (nw *namespaceWatch) cancelWatch() {
@@ -64,9 +88,34 @@ func (nw *namespaceWatch) cancelWatch() {
 	}
 }
 
+type watchedSecret struct {
+	// hash of the secret's data, the client is recreated when it changes so
+	// that rotated certificates and credentials take effect
+	hash     string
+	kvConfig KVStoreConfig
+	// the shared client with the secret's revision store, nil if it couldn't be created
+	kvStore KVStore
+	client  *sharedKVStore
+	// the last failure to create the KV store client, reported by checkKVStores
+	connectErr error
+	// the result of the last connection check, failures are logged when it changes
+	accessible bool
+}
+
+type sharedKVStore struct {
+	key     string
+	kvStore KVStore
+	// number of secrets using the client, it's closed when there are none
+	refs int
+}
+
 const (
 	ModelRegistryPrefix  = "registry"
 	VModelRegistryPrefix = "vmodels"
+
+	kvStoreCheckTimeout = 5 * time.Second
+	// how often the KV stores of the watched secrets are checked
+	kvStoreCheckInterval = 30 * time.Second
 )
 
 func NewModelEventStream(logger logr.Logger, k8sClient k8sClient.Client,
@@ -76,69 +125,184 @@ func NewModelEventStream(logger logr.Logger, k8sClient k8sClient.Client,
 	this.controllerNamespace = namespace
 
 	this.k8sClient = k8sClient
+	this.revisions = ownerRevisionStore{NewConfigMapRevisionStore(k8sClient, namespace, WatchRevisionsConfigMapPrefix)}
+	this.connect = func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error) {
+		return config.Connect(secretData, logger)
+	}
 
 	// These will get set on service reconciling
-	this.etcdClient = nil
-	this.etcdRootPrefix = ""
+	this.secrets = map[types.NamespacedName]*watchedSecret{}
+	this.clients = map[string]*sharedKVStore{}
 
 	this.watchedServices = map[string]*namespaceWatch{namespace: {}}
 
-	this.MMEvents = make(chan event.GenericEvent, 512) //TODO buffer size TBD
+	this.queue = newEventQueue()
+	this.mmEvents = make(chan event.GenericEvent)
+
+	return this, nil
+}
 
-	this.ctx = context.Background() // context.WithCancel(context.Background()) //TODO cancellation
+// Events returns the channel of the current or next run of the stream, it's
+// closed when the stream is stopped
+func (mes *ModelMeshEventStream) Events() <-chan event.GenericEvent {
+	mes.mutex.Lock()
+	defer mes.mutex.Unlock()
+	return mes.mmEvents
+}
 
+// NeedLeaderElection implements manager.LeaderElectionRunnable, events are only
+// needed by the Predictor controller of the leader
+func (mes *ModelMeshEventStream) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable, it (re-)establishes the watches of the
+// watched services and blocks until ctx is cancelled
+func (mes *ModelMeshEventStream) Start(ctx context.Context) error {
+	if err := mes.start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	mes.stop()
+	return nil
+}
+
+func (mes *ModelMeshEventStream) start(ctx context.Context) error {
+	mes.mutex.Lock()
+	defer mes.mutex.Unlock()
+	if mes.ctx != nil {
+		return fmt.Errorf("ModelMeshEventStream is already started")
+	}
+	mes.logger.Info("Starting ModelMesh event stream")
+	mes.ctx = ctx
+	events := mes.mmEvents
+	mes.watches.Add(1)
 	go func() {
-		<-this.ctx.Done()
-		close(this.MMEvents)
+		defer mes.watches.Done()
+		mes.queue.deliver(ctx, events)
 	}()
+	// not one of the watches, since stop waits for those while holding the mutex
+	go mes.checkKVStoresPeriodically(ctx)
+	for n, w := range mes.watchedServices {
+		if w.secret.Name == "" {
+			continue // nothing to watch until the service is reconciled
+		}
+		if _, ok := mes.secrets[w.secret]; !ok {
+			kvSecret, err := mes.getKVStoreSecret(ctx, w.secret)
+			if err == nil {
+				err = mes.connectToKVStore(ctx, kvSecret)
+			}
+			var kvErr *KVStoreError
+			if err != nil && !errors.As(err, &kvErr) {
+				// retried when the service is next reconciled
+				mes.logger.Error(err, "Could not create KV store client", "secret", w.secret)
+			}
+		}
+		mes.refreshWatches(w, n, w.watchedServiceName)
+	}
+	return nil
+}
 
-	return this, nil
+// stop cancels the watches and waits for them to exit, before closing the KV
+// store clients and the events channel. Any undelivered events are discarded.
+func (mes *ModelMeshEventStream) stop() {
+	mes.mutex.Lock()
+	defer mes.mutex.Unlock()
+	mes.logger.Info("Stopping ModelMesh event stream")
+	mes.ctx = nil
+	for _, w := range mes.watchedServices {
+		w.cancelWatch()
+	}
+	mes.watches.Wait()
+	mes.queue.clear()
+	for name := range mes.secrets {
+		mes.releaseSecret(name)
+	}
+	drainAndClose(mes.mmEvents)
+	mes.mmEvents = make(chan event.GenericEvent)
+}
+
+func drainAndClose(events chan event.GenericEvent) {
+	for {
+		select {
+		case <-events:
+		default:
+			close(events)
+			return
+		}
+	}
 }
 
 // UpdateWatchedService is called from service reconciler
 func (mes *ModelMeshEventStream) UpdateWatchedService(ctx context.Context,
-	etcdSecretName, serviceName, namespace string) error {
+	kvSecret types.NamespacedName, serviceName, namespace string) error {
 
 	if serviceName == "" {
 		return fmt.Errorf("serviceName must not be an empty string")
 	}
-	if etcdSecretName == "" {
-		return fmt.Errorf("etcdSecretName must not be an empty string")
+	if kvSecret.Name == "" {
+		return fmt.Errorf("kvSecret name must not be an empty string")
 	}
 
+	mes.mutex.Lock()
+	defer mes.mutex.Unlock()
+
 	nw, ok := mes.watchedServices[namespace]
 	if !ok {
 		nw = &namespaceWatch{}
 		mes.watchedServices[namespace] = nw
 	}
 
-	if etcdSecretName != mes.secretName {
-		// etcd config secret changed
-		mes.logger.V(1).Info("Etcd config secret changed. Creating a new etcd client and restarting watchers.",
-			"oldSecretName", mes.secretName, "newSecretName", etcdSecretName)
+	if mes.ctx == nil {
+		// the watches are established when the stream is started
+		nw.secret = kvSecret
+		nw.watchedServiceName = serviceName
+		return nil
+	}
+
+	secret, err := mes.getKVStoreSecret(ctx, kvSecret)
+	if err != nil {
+		return err
+	}
+
+	if ws, ok := mes.secrets[kvSecret]; !ok || ws.kvStore == nil || secretDataHash(secret.Data) != ws.hash {
+		// KV store config secret or its content changed
+		mes.logger.Info("KV store config secret changed. Creating a new KV store client and restarting watchers.",
+			"namespace", namespace, "oldSecret", nw.secret, "newSecret", kvSecret)
+		previous := nw.secret
+		nw.cancelWatch()
+		nw.secret = kvSecret
+		mes.releaseSecretIfUnused(previous)
+		// restart the watches of all the namespaces using the secret
 		for _, w := range mes.watchedServices {
-			w.cancelWatch()
-		}
-		if mes.etcdClient != nil {
-			if err := mes.etcdClient.Close(); err != nil {
-				mes.logger.Error(err, "Could not close existing etcd client")
+			if w.secret == kvSecret {
+				w.cancelWatch()
 			}
 		}
-		if err := mes.connectToEtcd(ctx, etcdSecretName); err != nil {
-			return fmt.Errorf("Could not create etcd client: %w", err)
+		// the watches are still started if the connection check fails since they retry
+		var kvErr *KVStoreError
+		err = mes.connectToKVStore(ctx, secret)
+		if err != nil && !errors.As(err, &kvErr) {
+			return fmt.Errorf("Could not create KV store client: %w", err)
 		}
-		mes.secretName = etcdSecretName
-
 		for n, w := range mes.watchedServices {
-			sn := w.watchedServiceName
-			if n == namespace {
-				sn = serviceName
+			if w.secret == kvSecret {
+				sn := w.watchedServiceName
+				if n == namespace {
+					sn = serviceName
+				}
+				mes.refreshWatches(w, n, sn)
 			}
-			mes.refreshWatches(w, n, sn)
 		}
-	} else if serviceName != nw.watchedServiceName {
-		// only service name changed
+		if err != nil {
+			return err
+		}
+	} else if kvSecret != nw.secret || serviceName != nw.watchedServiceName {
+		// only service name or the namespace's secret changed
+		previous := nw.secret
 		nw.cancelWatch()
+		nw.secret = kvSecret
+		mes.releaseSecretIfUnused(previous)
 		mes.refreshWatches(nw, namespace, serviceName)
 	}
 
@@ -147,20 +311,34 @@ func (mes *ModelMeshEventStream) UpdateWatchedService(ctx context.Context,
 
 // RemoveWatchedService is called from service reconciler
 func (mes *ModelMeshEventStream) RemoveWatchedService(serviceName, namespace string) {
+	mes.mutex.Lock()
+	defer mes.mutex.Unlock()
 	nw, ok := mes.watchedServices[namespace]
 	if ok && nw.watchedServiceName == serviceName {
 		delete(mes.watchedServices, namespace)
 		nw.cancelWatch()
+		mes.releaseSecretIfUnused(nw.secret)
 	}
 }
 
-func (mes *ModelMeshEventStream) refreshWatches(nw *namespaceWatch, namespace, serviceName string) {
-	rp := mes.etcdRootPrefix
-	if namespace != mes.controllerNamespace {
-		rp = fmt.Sprintf("%s/mm_ns/%s", rp, namespace) //TODO double check root prefix restrictions
+// servicePrefix is the prefix of the model-mesh service's registries in the KV store
+func servicePrefix(kvConfig KVStoreConfig, controllerNamespace, namespace, serviceName string) string {
+	rp := kvConfig.Prefix()
+	if namespace != controllerNamespace {
+		rp = kvConfig.ForNamespace(namespace).Prefix()
 	}
+	return fmt.Sprintf("%s/%s/%s", rp, modelmesh.ModelMeshEtcdPrefix, serviceName)
+}
 
-	servicePrefix := fmt.Sprintf("%s/%s/%s", rp, modelmesh.ModelMeshEtcdPrefix, serviceName)
+func (mes *ModelMeshEventStream) refreshWatches(nw *namespaceWatch, namespace, serviceName string) {
+	ws := mes.secrets[nw.secret]
+	if ws == nil || ws.kvStore == nil {
+		// retried when the service is next reconciled
+		nw.watchedServiceName = serviceName
+		return
+	}
+	// the root prefix of a namespace's own KV store isn't specific to the namespace
+	servicePrefix := servicePrefix(ws.kvConfig, nw.secret.Namespace, namespace, serviceName)
 
 	logger := mes.logger.WithValues("namespace", namespace)
 	logger.Info("Initialize Model Event Stream", "servicePrefix", servicePrefix)
@@ -168,47 +346,32 @@ func (mes *ModelMeshEventStream) refreshWatches(nw *namespaceWatch, namespace, s
 	var watchCtx context.Context
 	watchCtx, nw.cancelFunc = context.WithCancel(mes.ctx)
 
-	vmodelRegistryPrefix := fmt.Sprintf("%s/%s/", servicePrefix, VModelRegistryPrefix)
-	NewEtcdRangeWatcher(logger, mes.etcdClient, vmodelRegistryPrefix).
-		Start(watchCtx, false, func(eventType KeyEventType, key string, value []byte) {
+	vmodelRegistryPrefix := fmt.Sprintf("%s/%s", servicePrefix, VModelRegistryPrefix)
+	mes.watch(watchCtx, ws.kvStore, logger, VModelRegistryPrefix, vmodelRegistryPrefix, false,
+		func(eventType KeyEventType, key string, value []byte) {
 			if eventType != UPDATE && (eventType != DELETE || value == nil) {
 				logger.V(1).Info("ModelMesh VModel Event", "vModelId", key, "event", eventType)
 				return
 			}
 			if owner, err := ownerIDFromVModelRecord(value); err == nil {
-				encodedNamespace := namespace
-				if owner != "" {
-					encodedNamespace = fmt.Sprintf("%s_%s", owner, namespace)
-				}
 				logger.V(1).Info("ModelMesh VModel Event",
 					"vModelId", key, "owner", owner, "event", eventType)
-				mes.MMEvents <- event.GenericEvent{Object: &v1.PartialObjectMetadata{
-					ObjectMeta: v1.ObjectMeta{Name: key, Namespace: encodedNamespace},
-				}}
+				mes.queue.add(namespace, vModelEvent(namespace, key, owner))
 			} else {
 				logger.Error(err, "Error parsing VModel record to determine owner, ignoring event",
 					"vModelId", key, "event", eventType)
 			}
 		})
 
-	modelRegistryPrefix := fmt.Sprintf("%s/%s/", servicePrefix, ModelRegistryPrefix)
-	NewEtcdRangeWatcher(logger, mes.etcdClient, modelRegistryPrefix).
-		Start(watchCtx, true, func(eventType KeyEventType, key string, _ []byte) {
+	modelRegistryPrefix := fmt.Sprintf("%s/%s", servicePrefix, ModelRegistryPrefix)
+	mes.watch(watchCtx, ws.kvStore, logger, ModelRegistryPrefix, modelRegistryPrefix, true,
+		func(eventType KeyEventType, key string, _ []byte) {
 			logger.V(1).Info("ModelMesh Model Event", "modelId", key, "event", eventType)
 			if eventType == UPDATE {
 				// key is like "vmodelname__owner-0123456789"
-				ownerIdx := strings.LastIndex(key, "__") + 2
-				if ownerIdx > 2 {
-					hashIdx := len(key) - 11 // 11 is ('-' plus 10 hash chars)
-					if hashIdx > ownerIdx && key[hashIdx] == '-' {
-						// Infer predictor/vmodel and source ids from concrete model id by removing hash suffix
-						sourceId, predictorName := key[ownerIdx:hashIdx], key[:ownerIdx-2]
-						mes.MMEvents <- event.GenericEvent{Object: &v1.PartialObjectMetadata{ObjectMeta: v1.ObjectMeta{
-							Name:      predictorName,
-							Namespace: fmt.Sprintf("%s_%s", sourceId, namespace),
-						}}}
-						return
-					}
+				if e, ok := modelEvent(namespace, key); ok {
+					mes.queue.add(namespace, e)
+					return
 				}
 				logger.Info("Ignoring event for unrecognized ModelMesh model",
 					"modelId", key, "eventType", eventType)
@@ -219,43 +382,219 @@ func (mes *ModelMeshEventStream) refreshWatches(nw *namespaceWatch, namespace, s
 	nw.watchedServiceName = serviceName
 }
 
+// watch runs a watch of the KV store, which is waited for when the stream is stopped.
+// The events are counted rather than traced, since they aren't part of a reconcile
+// and a sync of a large registry would otherwise produce a trace per key
+func (mes *ModelMeshEventStream) watch(ctx context.Context, kvStore KVStore, logger logr.Logger, registry, prefix string,
+	keysOnly bool, listener KvListener) {
+	mes.watches.Add(1)
+	go func() {
+		defer mes.watches.Done()
+		kvStore.WatchPrefix(ctx, logger, prefix, keysOnly, func(eventType KeyEventType, key string, value []byte) {
+			modelEventsWatched.WithLabelValues(registry, eventType.String()).Inc()
+			listener(eventType, key, value)
+		})
+	}()
+}
+
 func ownerIDFromVModelRecord(data []byte) (string, error) {
-	type record struct{ O string } // owner field is called "o"; ignore others
-	vmr := record{}
+	vmr := ownerRecord{} // other fields are ignored
 	if err := json.Unmarshal(data, &vmr); err != nil {
 		return "", err
 	}
 	return vmr.O, nil
 }
 
-func (mes *ModelMeshEventStream) connectToEtcd(ctx context.Context, secretName string) error {
-	var err error
-	etcdSecret := v12.Secret{}
-	if err = mes.k8sClient.Get(ctx, k8sClient.ObjectKey{Name: secretName, Namespace: mes.controllerNamespace}, &etcdSecret); err != nil {
-		return fmt.Errorf("Unable to access etcd secret with name '%s': %w", secretName, err)
+func (mes *ModelMeshEventStream) getKVStoreSecret(ctx context.Context, name types.NamespacedName) (*v12.Secret, error) {
+	kvSecret := &v12.Secret{}
+	if err := mes.k8sClient.Get(ctx, name, kvSecret); err != nil {
+		return nil, fmt.Errorf("Unable to access KV store secret %s: %w", name, err)
 	}
-	etcdSecretJsonData, ok := etcdSecret.Data[modelmesh.EtcdSecretKey]
-	if !ok {
-		return fmt.Errorf("Key '%s' was not found in etcd secret '%s'", modelmesh.EtcdSecretKey, secretName)
+	return kvSecret, nil
+}
+
+// secretDataHash is used to detect changes to the content of the KV store secret
+func secretDataHash(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%d:", k, len(data[k]))
+		h.Write(data[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
 
-	var etcdConfig EtcdConfig
-	if err = json.Unmarshal(etcdSecretJsonData, &etcdConfig); err != nil {
-		return fmt.Errorf("failed to parse etcd config json: %w", err)
+// kvClientKey identifies the KV store clients which can be shared by secrets, i.e. those with the
+// same set of endpoints, credentials and certificates. The root prefix doesn't affect the client.
+func kvClientKey(kvConfig KVStoreConfig, data map[string][]byte) string {
+	switch c := kvConfig.(type) {
+	case EtcdConfig:
+		c.Endpoints, c.RootPrefix = sortedList(c.Endpoints), ""
+		kvConfig = c
+	case ZookeeperConfig:
+		c.ConnectString, c.RootPrefix = sortedList(c.ConnectString), ""
+		kvConfig = c
+	}
+	b, _ := json.Marshal(kvConfig)
+	clientData := make(map[string][]byte, len(data))
+	for k, v := range data {
+		clientData[k] = v
 	}
+	clientData[kvConfig.SecretKey()] = b
+	return kvConfig.Type() + ":" + secretDataHash(clientData)
+}
 
-	mes.etcdClient, err = CreateEtcdClient(etcdConfig, etcdSecret.Data, mes.logger)
-	if err != nil {
-		return fmt.Errorf("Failed to connect to Etcd: %w", err)
+func sortedList(list string) string {
+	items := strings.Split(list, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
 	}
-	mes.etcdRootPrefix = etcdConfig.RootPrefix
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
 
-	// TODO should we test the etcd client connection here? Otherwise there's no failure even if the URL is bad
+// connectToKVStore (re-)creates the KV store client of the secret, unless there's already one with
+// the same endpoints and credentials, and checks the connection. A *KVStoreError is returned if the
+// client was created but the KV store couldn't be accessed.
+func (mes *ModelMeshEventStream) connectToKVStore(ctx context.Context, kvSecret *v12.Secret) (err error) {
+	name := types.NamespacedName{Name: kvSecret.Name, Namespace: kvSecret.Namespace}
+	ws := &watchedSecret{}
+	// the previous client is released once the new one is acquired, in case they're the same
+	previous := mes.secrets[name]
+	mes.secrets[name] = ws
+	defer func() {
+		if ws.kvStore == nil {
+			ws.connectErr = err
+		}
+		ws.accessible = err == nil
+		setKVStoreAccessible(name, ws.accessible)
+		if previous != nil && previous.client != nil {
+			mes.releaseClient(previous.client)
+		}
+	}()
+	kvConfig, err := KVStoreConfigFromSecret(name.Name, kvSecret.Data)
+	if err != nil {
+		return err
+	}
+	key := kvClientKey(kvConfig, kvSecret.Data)
+	client, ok := mes.clients[key]
+	if !ok {
+		kvStore, err := mes.connect(kvConfig, kvSecret.Data)
+		if err != nil {
+			return fmt.Errorf("Failed to connect to %s: %w", kvConfig.Type(), err)
+		}
+		client = &sharedKVStore{key: key, kvStore: kvStore}
+		mes.clients[key] = client
+	}
+	client.refs++
+	revisions := mes.revisions
+	if name.Namespace != mes.controllerNamespace {
+		// a namespace's own KV store may have the same prefixes as the controller's
+		revisions = prefixedRevisionStore{RevisionStore: revisions, keyPrefix: name.String() + "/"}
+	}
+	ws.hash, ws.kvConfig, ws.client = secretDataHash(kvSecret.Data), kvConfig, client
+	ws.kvStore = WithRevisionStore(client.kvStore, revisions)
+
+	checkCtx, cancel := context.WithTimeout(ctx, kvStoreCheckTimeout)
+	defer cancel()
+	if err = ws.kvStore.Check(checkCtx); err != nil {
+		var kvErr *KVStoreError
+		if errors.As(err, &kvErr) {
+			mes.logger.Error(err, "KV store connection check failed", "reason", kvErr.Reason, "secret", name)
+		}
+		return err
+	}
 	return nil
 }
 
-//func (mes *ModelMeshEventStream) Stop() {
-//	//TODO cancel mes.ctx here
-//	mes.grpcConn.Close()
-//	mes.etcdClient.Close()
-//}
+// releaseSecretIfUnused releases the secret's client if no watched namespace uses the secret
+func (mes *ModelMeshEventStream) releaseSecretIfUnused(name types.NamespacedName) {
+	for _, w := range mes.watchedServices {
+		if w.secret == name {
+			return
+		}
+	}
+	mes.releaseSecret(name)
+}
+
+func (mes *ModelMeshEventStream) releaseSecret(name types.NamespacedName) {
+	if ws, ok := mes.secrets[name]; ok {
+		delete(mes.secrets, name)
+		kvStoreAccessible.DeleteLabelValues(name.String())
+		if ws.client != nil {
+			mes.releaseClient(ws.client)
+		}
+	}
+}
+
+func (mes *ModelMeshEventStream) releaseClient(client *sharedKVStore) {
+	if client.refs--; client.refs > 0 {
+		return
+	}
+	delete(mes.clients, client.key)
+	if err := client.kvStore.Close(); err != nil {
+		mes.logger.Error(err, "Could not close KV store client")
+	}
+}
+
+func setKVStoreAccessible(name types.NamespacedName, accessible bool) {
+	value := 0.0
+	if accessible {
+		value = 1
+	}
+	kvStoreAccessible.WithLabelValues(name.String()).Set(value)
+}
+
+func (mes *ModelMeshEventStream) checkKVStoresPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(kvStoreCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			mes.checkKVStores(ctx)
+		}
+	}
+}
+
+// checkKVStores checks the connections to the KV stores of the watched secrets and updates the
+// kvstore_accessible metric. It doesn't gate the readiness of the controller, the webhooks must
+// still be served while a KV store is down and the watches retry until it's accessible again.
+func (mes *ModelMeshEventStream) checkKVStores(ctx context.Context) {
+	mes.mutex.Lock()
+	secrets := make(map[types.NamespacedName]*watchedSecret, len(mes.secrets))
+	for name, ws := range mes.secrets {
+		secrets[name] = ws
+	}
+	mes.mutex.Unlock()
+	for name, ws := range secrets {
+		err := ws.connectErr
+		if ws.kvStore != nil {
+			checkCtx, cancel := context.WithTimeout(ctx, kvStoreCheckTimeout)
+			err = ws.kvStore.Check(checkCtx)
+			cancel()
+		}
+		mes.mutex.Lock()
+		// skip the secrets which were reloaded or released meanwhile
+		if mes.ctx == ctx && mes.secrets[name] == ws {
+			if err != nil && ws.accessible {
+				var kvErr *KVStoreError
+				if errors.As(err, &kvErr) {
+					mes.logger.Error(err, "KV store connection check failed", "reason", kvErr.Reason, "secret", name)
+				} else {
+					mes.logger.Error(err, "KV store connection check failed", "secret", name)
+				}
+			} else if err == nil && !ws.accessible {
+				mes.logger.Info("KV store is accessible again", "secret", name)
+			}
+			ws.accessible = err == nil
+			setKVStoreAccessible(name, ws.accessible)
+		}
+		mes.mutex.Unlock()
+	}
+}
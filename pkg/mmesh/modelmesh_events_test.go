@@ -0,0 +1,302 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	etcd3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8sClient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+)
+
+const testNamespace = "modelmesh-serving"
+
+var testSecret = types.NamespacedName{Name: "model-serving-etcd", Namespace: testNamespace}
+
+func newTestEventStream(t *testing.T, f *fakeEtcd) *ModelMeshEventStream {
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "model-serving-etcd", Namespace: testNamespace},
+		Data: map[string][]byte{
+			modelmesh.EtcdSecretKey: []byte(`{"endpoints": "http://etcd:2379", "root_prefix": "root"}`),
+		},
+	}).Build()
+	mes, err := NewModelEventStream(logger, cl, testNamespace)
+	require.NoError(t, err)
+	mes.connect = func(config KVStoreConfig, _ map[string][]byte) (KVStore, error) {
+		return etcdKVStore{client: f.client()}, nil
+	}
+	return mes
+}
+
+func expectPredictorEvent(t *testing.T, events <-chan event.GenericEvent, name, namespace string) {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "events channel was closed")
+		assert.Equal(t, name, e.Object.GetName())
+		assert.Equal(t, namespace, e.Object.GetNamespace())
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for event for %s", name)
+	}
+}
+
+func Test_ModelMeshEventStream_RestartAfterLosingLeadership(t *testing.T) {
+	f := newFakeEtcd()
+	mes := newTestEventStream(t, f)
+
+	// the service is reconciled before the stream is started
+	require.NoError(t, mes.UpdateWatchedService(context.Background(), testSecret, "modelmesh-serving", testNamespace))
+	events := mes.Events()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- mes.Start(ctx) }()
+
+	watched := modelEventsWatched.WithLabelValues(VModelRegistryPrefix, UPDATE.String())
+	updates := testutil.ToFloat64(watched)
+	f.put("root/mm/modelmesh-serving/vmodels/vm1", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm1", "src1_"+testNamespace)
+	f.put("root/mm/modelmesh-serving/vmodels/vm2", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm2", "src1_"+testNamespace)
+	assert.Equal(t, updates+2, testutil.ToFloat64(watched))
+
+	// leadership is lost
+	cancel()
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the event stream to stop")
+	}
+	assert.Equal(t, 1, f.closed)
+	_, ok := <-events
+	assert.False(t, ok, "events channel should be closed")
+
+	f.put("root/mm/modelmesh-serving/vmodels/vm3", `{"o":"src2"}`)
+
+	// leadership is regained, the watches resume from the saved revisions
+	// without the service being reconciled again
+	events = mes.Events()
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() { stopped <- mes.Start(ctx) }()
+
+	expectPredictorEvent(t, events, "vm3", "src2_"+testNamespace)
+	assert.ErrorContains(t, mes.Start(ctx), "already started")
+}
+
+func Test_ModelMeshEventStream_SecretRotation(t *testing.T) {
+	f := newFakeEtcd()
+	mes := newTestEventStream(t, f)
+	var connected []map[string][]byte
+	mes.connect = func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error) {
+		connected = append(connected, secretData)
+		return etcdKVStore{client: f.client()}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = mes.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		mes.mutex.Lock()
+		defer mes.mutex.Unlock()
+		return mes.ctx != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", testNamespace))
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", testNamespace))
+	assert.Len(t, connected, 1, "the client is only recreated when the secret changes")
+
+	// the client certificate is rotated
+	secret := &corev1.Secret{}
+	require.NoError(t, mes.k8sClient.Get(ctx, k8sClient.ObjectKey{Name: "model-serving-etcd", Namespace: testNamespace}, secret))
+	secret.Data["client.crt"] = []byte("rotated")
+	require.NoError(t, mes.k8sClient.Update(ctx, secret))
+
+	events := mes.Events()
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", testNamespace))
+	require.Len(t, connected, 2)
+	assert.Equal(t, []byte("rotated"), connected[1]["client.crt"])
+	f.mu.Lock()
+	assert.Equal(t, 1, f.closed)
+	f.mu.Unlock()
+
+	// the watches were restarted with the new client
+	f.put("root/mm/modelmesh-serving/vmodels/vm1", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm1", "src1_"+testNamespace)
+}
+
+func Test_ModelMeshEventStream_DedicatedKVStores(t *testing.T) {
+	shared, dedicated := newFakeEtcd(), newFakeEtcd()
+	mes := newTestEventStream(t, shared)
+	for _, ns := range []string{"tenant-a", "tenant-b"} {
+		// both namespaces use the same etcd cluster, with different root prefixes
+		require.NoError(t, mes.k8sClient.Create(context.Background(), &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-etcd", Namespace: ns},
+			Data: map[string][]byte{
+				modelmesh.EtcdSecretKey: []byte(`{"endpoints": "http://b:2379,http://a:2379", "root_prefix": "` + ns + `"}`),
+			},
+		}))
+	}
+	var connected []string
+	mes.connect = func(config KVStoreConfig, _ map[string][]byte) (KVStore, error) {
+		endpoints := config.(EtcdConfig).Endpoints
+		connected = append(connected, endpoints)
+		if endpoints == "http://etcd:2379" {
+			return etcdKVStore{client: shared.client()}, nil
+		}
+		return etcdKVStore{client: dedicated.client()}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := mes.Events()
+	go func() { _ = mes.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		mes.mutex.Lock()
+		defer mes.mutex.Unlock()
+		return mes.ctx != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", testNamespace))
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", "user"))
+	for _, ns := range []string{"tenant-a", "tenant-b"} {
+		tenantSecret := types.NamespacedName{Name: "tenant-etcd", Namespace: ns}
+		require.NoError(t, mes.UpdateWatchedService(ctx, tenantSecret, "modelmesh-serving", ns))
+	}
+	assert.Equal(t, []string{"http://etcd:2379", "http://b:2379,http://a:2379"}, connected,
+		"one client per distinct endpoint set")
+
+	// the root prefix of a namespace's own secret is used as is
+	dedicated.put("tenant-a/mm/modelmesh-serving/vmodels/vm1", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm1", "src1_tenant-a")
+	dedicated.put("tenant-b/mm/modelmesh-serving/vmodels/vm2", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm2", "src1_tenant-b")
+	shared.put("root/mm_ns/user/mm/modelmesh-serving/vmodels/vm3", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm3", "src1_user")
+
+	// the dedicated client is closed once no namespace uses it
+	mes.RemoveWatchedService("modelmesh-serving", "tenant-a")
+	require.NoError(t, mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", "tenant-b"))
+	dedicated.mu.Lock()
+	assert.Equal(t, 1, dedicated.closed)
+	dedicated.mu.Unlock()
+	shared.put("root/mm_ns/tenant-b/mm/modelmesh-serving/vmodels/vm4", `{"o":"src1"}`)
+	expectPredictorEvent(t, events, "vm4", "src1_tenant-b")
+	assert.Len(t, connected, 2)
+}
+
+func Test_GrpcModelEventStream_Stop(t *testing.T) {
+	gs, err := NewGrpcModelEventStream(logger, func(string) *MMService { return nil })
+	require.NoError(t, err)
+	require.NoError(t, gs.UpdateWatchedService(context.Background(), types.NamespacedName{}, "modelmesh-serving", testNamespace))
+	events := gs.Events()
+
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error)
+		go func() { stopped <- gs.Start(ctx) }()
+		cancel()
+		select {
+		case err := <-stopped:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the event stream to stop")
+		}
+		_, ok := <-events
+		assert.False(t, ok, "events channel should be closed")
+		events = gs.Events()
+	}
+}
+
+func Test_ModelMeshEventStream_KVStoreAccessible(t *testing.T) {
+	f := newFakeEtcd()
+	mes := newTestEventStream(t, f)
+	f.getErr = status.Error(codes.Unavailable, "connection refused")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = mes.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		mes.mutex.Lock()
+		defer mes.mutex.Unlock()
+		return mes.ctx != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the connection is checked when the secret is loaded, but the watches are still started
+	err := mes.UpdateWatchedService(ctx, testSecret, "modelmesh-serving", testNamespace)
+	var kvErr *KVStoreError
+	require.ErrorAs(t, err, &kvErr)
+	assert.Equal(t, KVStoreUnreachable, kvErr.Reason)
+	assert.Equal(t, "modelmesh-serving", mes.watchedServices[testNamespace].watchedServiceName)
+	accessible := kvStoreAccessible.WithLabelValues(testSecret.String())
+	assert.Equal(t, 0.0, testutil.ToFloat64(accessible))
+
+	f.mu.Lock()
+	f.getErr = nil
+	f.mu.Unlock()
+	mes.checkKVStores(ctx)
+	assert.Equal(t, 1.0, testutil.ToFloat64(accessible))
+
+	f.mu.Lock()
+	f.getErr = status.Error(codes.Unauthenticated, "invalid auth token")
+	f.mu.Unlock()
+	mes.checkKVStores(ctx)
+	assert.Equal(t, 0.0, testutil.ToFloat64(accessible))
+
+	// the secret's series is removed once the stream is stopped
+	cancel()
+	require.Eventually(t, func() bool {
+		mes.mutex.Lock()
+		defer mes.mutex.Unlock()
+		return mes.ctx == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.False(t, kvStoreAccessible.DeleteLabelValues(testSecret.String()))
+}
+
+func Test_EtcdErrorReason(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected KVStoreErrorReason
+	}{
+		{etcd3rpc.ErrAuthFailed, KVStoreAuthFailed},
+		{etcd3rpc.ErrPermissionDenied, KVStoreAuthFailed},
+		{status.Error(codes.Unauthenticated, "invalid token"), KVStoreAuthFailed},
+		{fmt.Errorf("dial: %w", x509.UnknownAuthorityError{}), KVStoreTLSFailed},
+		{status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: `+
+			`x509: certificate signed by unknown authority"`), KVStoreTLSFailed},
+		{status.Error(codes.Unavailable, "connection refused"), KVStoreUnreachable},
+		{context.DeadlineExceeded, KVStoreUnreachable},
+		{errors.New("something else"), KVStoreUnknownError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, etcdErrorReason(tt.err), tt.err.Error())
+	}
+}
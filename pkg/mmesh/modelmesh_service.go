@@ -17,17 +17,22 @@ package mmesh
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 	"unsafe"
 
 	"google.golang.org/grpc/credentials/insecure"
 
 	"github.com/go-logr/logr"
 	"github.com/kserve/modelmesh-serving/pkg/config"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/atomic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -35,7 +40,11 @@ import (
 	mmeshapi "github.com/kserve/modelmesh-serving/generated/mmesh"
 )
 
-type TLSConfigLookup func(context.Context, string) (*tls.Config, error)
+// TLSConfigLookup returns the client TLS config for the TLS secret of model-mesh, and the
+// secret with the client key pair of the controller if it's not the TLS secret's. The
+// namespace of the TLS secret is only set when it's issued per namespace by cert-manager,
+// otherwise the secret is in the controller namespace.
+type TLSConfigLookup func(ctx context.Context, tlsSecretNamespace, tlsSecretName, clientCertSecretName string) (*tls.Config, error)
 
 // Encapsulates ModelMesh gRPC service
 type MMService struct {
@@ -43,17 +52,26 @@ type MMService struct {
 	Log       logr.Logger
 	namespace string
 	tlsConfig TLSConfigLookup
+	breaker   *circuitBreaker
 
 	// these protected by mutex
 	name               string
 	port               uint16
+	restProxy          bool
 	restPort           uint16
+	openAIPort         uint16
 	managementEndpoint string
 	headless           bool
 	tlsSecretName      string
+	tlsCertManaged     bool
+	clientCertSecret   string
+	clientConfig       config.ModelMeshClientConfig
 	metricsPort        uint16
 	reconnect          bool // indicates dirty client
 	serviceSpec        *v1.ServiceSpec
+	// set when the Service is exposed via an external route, advertised in place of the internal endpoints
+	externalEndpoint     string
+	externalRESTEndpoint string
 
 	// updates protected by mutex, read with atomic load
 	mmClient atomic.UnsafePointer // stores type *mmClient
@@ -66,6 +84,7 @@ func NewMMService(namespace string, tlsConfig TLSConfigLookup) *MMService {
 		Log:       ctrl.Log.WithName("MMService").WithValues("namespace", namespace),
 		namespace: namespace,
 		tlsConfig: tlsConfig,
+		breaker:   newCircuitBreaker(),
 	}
 }
 
@@ -79,11 +98,22 @@ func (mms *MMService) GetNameAndSpec() (string, *v1.ServiceSpec) {
 	return mms.name, mms.serviceSpec
 }
 
-func (mms *MMService) UpdateConfig(cp *config.ConfigProvider) (*config.Config, bool) {
+// RESTProxyEnabled returns whether the Service exposes the REST proxy, as last configured
+func (mms *MMService) RESTProxyEnabled() bool {
+	mms.mutex.Lock()
+	defer mms.mutex.Unlock()
+	return mms.restProxy
+}
+
+// UpdateConfig applies the config to the Service. The REST proxy and OpenAI API ports are
+// exposed when restProxyEnabled, i.e. when any of the namespace's runtimes has the REST proxy,
+// which runtimes can opt in or out of regardless of the configured default.
+func (mms *MMService) UpdateConfig(cp *config.ConfigProvider, restProxyEnabled bool) (*config.Config, bool) {
 	mms.mutex.Lock()
 	defer mms.mutex.Unlock()
 
 	cfg := cp.GetConfig()
+	mms.restProxy = restProxyEnabled
 
 	specChange, clientChange := false, false
 	if cfg.InferenceServiceName != mms.name {
@@ -97,13 +127,21 @@ func (mms *MMService) UpdateConfig(cp *config.ConfigProvider) (*config.Config, b
 		clientChange = true
 	}
 	var restPort uint16
-	if cfg.RESTProxy.Enabled {
+	if restProxyEnabled {
 		restPort = cfg.RESTProxy.Port
 	}
 	if restPort != mms.restPort {
 		mms.restPort = restPort
 		specChange = true
 	}
+	var openAIPort uint16
+	if restProxyEnabled && cfg.RESTProxy.OpenAI.Enabled {
+		openAIPort = cfg.RESTProxy.OpenAI.Port
+	}
+	if openAIPort != mms.openAIPort {
+		mms.openAIPort = openAIPort
+		specChange = true
+	}
 	endpoint := cfg.ModelMeshEndpoint
 	if endpoint == "" {
 		endpoint = fmt.Sprintf("%s:///%s:%d", KUBE_SCHEME, mms.dnsName(), mms.port)
@@ -116,6 +154,20 @@ func (mms *MMService) UpdateConfig(cp *config.ConfigProvider) (*config.Config, b
 		mms.tlsSecretName = cfg.TLS.SecretName
 		clientChange = true
 	}
+	if cfg.TLS.CertManager.Enabled() != mms.tlsCertManaged {
+		mms.tlsCertManaged = cfg.TLS.CertManager.Enabled()
+		clientChange = true
+	}
+	if cfg.TLS.ClientCertSecretName != mms.clientCertSecret {
+		mms.clientCertSecret = cfg.TLS.ClientCertSecretName
+		clientChange = true
+	}
+	if cfg.ModelMeshClient != mms.clientConfig {
+		mms.clientConfig = cfg.ModelMeshClient
+		cb := cfg.ModelMeshClient.CircuitBreaker
+		mms.breaker.configure(cb.FailureThreshold, cb.OpenDuration)
+		clientChange = true
+	}
 	if cfg.HeadlessService != mms.headless {
 		mms.headless = cfg.HeadlessService
 		specChange = true
@@ -146,6 +198,13 @@ func (mms *MMService) UpdateConfig(cp *config.ConfigProvider) (*config.Config, b
 				TargetPort: intstr.FromString("http"),
 			})
 		}
+		if openAIPort > 0 {
+			spec.Ports = append(spec.Ports, v1.ServicePort{
+				Name:       "openai",
+				Port:       int32(openAIPort),
+				TargetPort: intstr.FromString("openai"),
+			})
+		}
 		if mms.headless {
 			spec.ClusterIP = "None"
 		}
@@ -177,11 +236,42 @@ func (mms *MMService) mmc() *mmClient {
 	return (*mmClient)(mms.mmClient.Load())
 }
 
+// InferenceEndpoints returns the gRPC and REST inference endpoints to advertise, those of the
+// external routes when they exist and otherwise those of the Service
 func (mms *MMService) InferenceEndpoints() (grpc, rest string) {
 	if mmc := mms.mmc(); mmc != nil {
-		return mmc.endpoint, mmc.restEndpoint
+		grpc, rest = mmc.endpoint, mmc.restEndpoint
+	}
+	mms.mutex.Lock()
+	defer mms.mutex.Unlock()
+	if grpc != "" && mms.externalEndpoint != "" {
+		grpc = mms.externalEndpoint
+	}
+	if rest != "" && mms.externalRESTEndpoint != "" {
+		rest = mms.externalRESTEndpoint
 	}
-	return "", ""
+	return grpc, rest
+}
+
+// SetExternalEndpoints sets the endpoints of the external routes of the Service, empty
+// for those which don't exist
+func (mms *MMService) SetExternalEndpoints(grpc, rest string) {
+	mms.mutex.Lock()
+	defer mms.mutex.Unlock()
+	mms.externalEndpoint, mms.externalRESTEndpoint = grpc, rest
+}
+
+// RequestTimeout is the timeout of each model-mesh management RPC
+func (mms *MMService) RequestTimeout() time.Duration {
+	mms.mutex.Lock()
+	defer mms.mutex.Unlock()
+	return mms.clientConfig.RequestTimeout
+}
+
+// CircuitRetryAfter returns how long the circuit breaker of the client stays open, during
+// which management RPCs fail fast, or 0 if it's closed
+func (mms *MMService) CircuitRetryAfter() time.Duration {
+	return mms.breaker.retryAfter()
 }
 
 // MMClient is called from predictor controller
@@ -201,7 +291,12 @@ func (mms *MMService) ConnectIfNeeded(ctx context.Context) error {
 		return nil
 	}
 
-	tlsSecret := mms.tlsSecretName
+	tlsSecret, clientCertSecret := mms.tlsSecretName, mms.clientCertSecret
+	clientConfig := mms.clientConfig
+	tlsSecretNamespace := ""
+	if mms.tlsCertManaged {
+		tlsSecretNamespace = mms.namespace
+	}
 	endpoint := mms.managementEndpoint
 	dnsName := mms.dnsName()
 	inferenceEndpoint := fmt.Sprintf("grpc://%s:%d", dnsName, mms.port)
@@ -219,12 +314,15 @@ func (mms *MMService) ConnectIfNeeded(ctx context.Context) error {
 	var tlsConfig *tls.Config
 	if tlsSecret != "" {
 		var err error
-		if tlsConfig, err = mms.tlsConfig(ctx, tlsSecret); err != nil {
+		if tlsConfig, err = mms.tlsConfig(ctx, tlsSecretNamespace, tlsSecret, clientCertSecret); err != nil {
+			mms.mutex.Lock()
+			defer mms.mutex.Unlock()
+			mms.reconnect = true
 			return err
 		}
 	}
 
-	mmc, err := newMmClient(ctx, endpoint, tlsConfig, dnsName,
+	mmc, err := newMmClient(ctx, endpoint, tlsConfig, clientConfig, mms.breaker, clientMetrics(mms.namespace), dnsName,
 		inferenceEndpoint, restEndpoint)
 	if err != nil {
 		mms.mutex.Lock()
@@ -250,12 +348,28 @@ func (mms *MMService) Disconnect() {
 	}
 }
 
+const watchModelEventsMethod = "/mmesh.ModelMesh/watchModelEvents"
+
 func newMmClient(ctx context.Context, mmeshEndpoint string, tlsConfig *tls.Config,
-	serviceName, externalEndpoint, restEndpoint string) (*mmClient, error) {
+	clientConfig config.ModelMeshClientConfig, breaker *circuitBreaker, metrics clientMetrics,
+	serviceName, inferenceEndpoint, restEndpoint string) (*mmClient, error) {
 	//grpcCtx, cancel := context.WithTimeout(context.Background(), GrpcDialTimeout) //TODO TBD
 
-	dialOpts := make([]grpc.DialOption, 1, 3)
-	dialOpts[0] = grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`)
+	dialOpts := make([]grpc.DialOption, 4, 7)
+	dialOpts[0] = grpc.WithDefaultServiceConfig(mmServiceConfig(clientConfig.MaxAttempts))
+	// calls rejected by the circuit breaker are included in the metrics
+	dialOpts[1] = grpc.WithChainUnaryInterceptor(metrics.unaryInterceptor, breaker.unaryInterceptor)
+	dialOpts[2] = grpc.WithStreamInterceptor(metrics.streamInterceptor)
+	// the watch stream isn't traced, it lasts as long as the connection
+	dialOpts[3] = grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithFilter(func(info *stats.RPCTagInfo) bool {
+		return info.FullMethodName != watchModelEventsMethod
+	})))
+	if clientConfig.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    clientConfig.KeepaliveTime,
+			Timeout: clientConfig.KeepaliveTimeout,
+		}))
+	}
 	if tlsConfig == nil {
 		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	} else {
@@ -267,5 +381,29 @@ func newMmClient(ctx context.Context, mmeshEndpoint string, tlsConfig *tls.Confi
 		//logger.Error(err, "failed to connect to model mesh service")
 		return nil, err
 	}
-	return &mmClient{grpcConn, mmeshapi.NewModelMeshClient(grpcConn), externalEndpoint, restEndpoint}, nil
+	return &mmClient{grpcConn, mmeshapi.NewModelMeshClient(grpcConn), inferenceEndpoint, restEndpoint}, nil
+}
+
+// mmServiceConfig returns the gRPC service config of the model-mesh client, with a retry
+// policy for the idempotent RPCs which fail because model-mesh is unavailable
+func mmServiceConfig(maxAttempts int) string {
+	serviceConfig := map[string]interface{}{"loadBalancingPolicy": "round_robin"}
+	if maxAttempts > 1 {
+		service := mmeshapi.ModelMesh_ServiceDesc.ServiceName
+		serviceConfig["methodConfig"] = []interface{}{map[string]interface{}{
+			"name": []interface{}{
+				map[string]string{"service": service, "method": "getVModelStatus"},
+				map[string]string{"service": service, "method": "ensureLoaded"},
+			},
+			"retryPolicy": map[string]interface{}{
+				"maxAttempts":          maxAttempts,
+				"initialBackoff":       "0.1s",
+				"maxBackoff":           "1s",
+				"backoffMultiplier":    2,
+				"retryableStatusCodes": []string{"UNAVAILABLE"},
+			},
+		}}
+	}
+	b, _ := json.Marshal(serviceConfig)
+	return string(b)
 }
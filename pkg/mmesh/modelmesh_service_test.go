@@ -0,0 +1,104 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"net"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	mmeshapi "github.com/kserve/modelmesh-serving/generated/mmesh"
+	"github.com/kserve/modelmesh-serving/pkg/config"
+	"github.com/kserve/modelmesh-serving/pkg/tracing"
+)
+
+func Test_MMClientTracePropagation(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewTracerProvider(exporter, 1)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	}()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceparent := make(chan []string, 1)
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		traceparent <- md.Get("traceparent")
+		return stream.SendMsg(&mmeshapi.VModelStatusInfo{})
+	}))
+	go func() { _ = server.Serve(lis) }()
+	defer server.Stop()
+
+	clientConfig := config.ModelMeshClientConfig{MaxAttempts: 1}
+	mmc, err := newMmClient(context.Background(), lis.Addr().String(), nil, clientConfig,
+		newCircuitBreaker(), clientMetrics("tracing-test"), "modelmesh-serving", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mmc.grpcConn.Close()
+
+	ctx, reconcile := otel.Tracer("test").Start(context.Background(), "predictor reconcile")
+	_, err = mmc.mmeshClient.GetVModelStatus(ctx, &mmeshapi.GetVModelStatusRequest{VModelId: "example"})
+	reconcile.End()
+	assert.NoError(t, err)
+	assert.NoError(t, tp.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	rpc := spans[0]
+	assert.Equal(t, "mmesh.ModelMesh/getVModelStatus", rpc.Name)
+	assert.Equal(t, reconcile.SpanContext().SpanID(), rpc.Parent.SpanID())
+
+	// model-mesh receives the context of the RPC span
+	header := <-traceparent
+	assert.Len(t, header, 1)
+	assert.Contains(t, header[0], rpc.SpanContext.TraceID().String()+"-"+rpc.SpanContext.SpanID().String())
+}
+
+func Test_MMServiceInferenceEndpoints(t *testing.T) {
+	mms := NewMMService("team-a", nil)
+	mms.SetExternalEndpoints("grpc://modelmesh-serving-grpc.team-a.example.com:80", "")
+	grpcEndpoint, restEndpoint := mms.InferenceEndpoints()
+	assert.Empty(t, grpcEndpoint, "nothing is advertised until connected")
+	assert.Empty(t, restEndpoint)
+
+	mms.mmClient.Store(unsafe.Pointer(&mmClient{
+		endpoint:     "grpc://modelmesh-serving.team-a:8033",
+		restEndpoint: "http://modelmesh-serving.team-a:8008",
+	}))
+	// the REST route wasn't created, its internal endpoint is advertised instead
+	grpcEndpoint, restEndpoint = mms.InferenceEndpoints()
+	assert.Equal(t, "grpc://modelmesh-serving-grpc.team-a.example.com:80", grpcEndpoint)
+	assert.Equal(t, "http://modelmesh-serving.team-a:8008", restEndpoint)
+
+	mms.SetExternalEndpoints("", "")
+	grpcEndpoint, _ = mms.InferenceEndpoints()
+	assert.Equal(t, "grpc://modelmesh-serving.team-a:8033", grpcEndpoint)
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NamespaceKVStores connects to the KV stores used by namespaces, either the controller's or a
+// namespace's own (see NamespaceKVStoreSecret), with one client per secret until it's closed.
+// Unlike ModelMeshEventStream it isn't kept up to date with changes of the secrets, it's meant
+// for tasks which only run briefly.
+type NamespaceKVStores struct {
+	k8sClient           client.Client
+	controllerNamespace string
+	secretName          string
+	connect             func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error)
+
+	stores map[types.NamespacedName]namespaceKVStore
+}
+
+type namespaceKVStore struct {
+	kvStore  KVStore
+	kvConfig KVStoreConfig
+}
+
+func NewNamespaceKVStores(logger logr.Logger, k8sClient client.Client, controllerNamespace,
+	secretName string) *NamespaceKVStores {
+	return newNamespaceKVStores(k8sClient, controllerNamespace, secretName,
+		func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error) {
+			return config.Connect(secretData, logger)
+		})
+}
+
+func newNamespaceKVStores(k8sClient client.Client, controllerNamespace, secretName string,
+	connect func(config KVStoreConfig, secretData map[string][]byte) (KVStore, error)) *NamespaceKVStores {
+	return &NamespaceKVStores{
+		k8sClient:           k8sClient,
+		controllerNamespace: controllerNamespace,
+		secretName:          secretName,
+		connect:             connect,
+		stores:              map[types.NamespacedName]namespaceKVStore{},
+	}
+}
+
+// ControllerSecret is the KV store secret of the controller, used by the namespaces
+// which don't have their own
+func (s *NamespaceKVStores) ControllerSecret() types.NamespacedName {
+	return types.NamespacedName{Name: s.secretName, Namespace: s.controllerNamespace}
+}
+
+// Get returns the KV store of the secret along with its config, connecting to it the first time
+func (s *NamespaceKVStores) Get(ctx context.Context, secret types.NamespacedName) (KVStore, KVStoreConfig, error) {
+	if ns, ok := s.stores[secret]; ok {
+		return ns.kvStore, ns.kvConfig, nil
+	}
+	kvSecret := &corev1.Secret{}
+	if err := s.k8sClient.Get(ctx, secret, kvSecret); err != nil {
+		return nil, nil, fmt.Errorf("unable to access KV store secret '%s': %w", secret, err)
+	}
+	kvConfig, err := KVStoreConfigFromSecret(secret.Name, kvSecret.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	kvStore, err := s.connect(kvConfig, kvSecret.Data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s of secret '%s': %w", kvConfig.Type(), secret, err)
+	}
+	s.stores[secret] = namespaceKVStore{kvStore: kvStore, kvConfig: kvConfig}
+	return kvStore, kvConfig, nil
+}
+
+// ServicePrefix returns the KV store used by the namespace and the prefix of the registries
+// of its model-mesh service in it
+func (s *NamespaceKVStores) ServicePrefix(ctx context.Context, namespace, serviceName string) (KVStore, string, error) {
+	secret, err := NamespaceKVStoreSecret(ctx, s.k8sClient, namespace, s.controllerNamespace, s.secretName)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to determine the KV store secret of namespace %s: %w", namespace, err)
+	}
+	kvStore, kvConfig, err := s.Get(ctx, secret)
+	if err != nil {
+		return nil, "", err
+	}
+	// the root prefix of a namespace's own KV store isn't specific to the namespace
+	return kvStore, servicePrefix(kvConfig, secret.Namespace, namespace, serviceName), nil
+}
+
+// Close closes the clients of all the KV stores
+func (s *NamespaceKVStores) Close() {
+	for secret, ns := range s.stores {
+		ns.kvStore.Close()
+		delete(s.stores, secret)
+	}
+}
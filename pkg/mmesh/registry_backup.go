@@ -0,0 +1,190 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+)
+
+// RegistryBackupVersion is the version of the RegistryBackup format
+const RegistryBackupVersion = 1
+
+// RegistryBackup is a snapshot of model-mesh's model and vmodel registries in the
+// KV store, for some namespaces
+type RegistryBackup struct {
+	Version    int                   `json:"version"`
+	Created    time.Time             `json:"created"`
+	Namespaces []NamespaceRegistries `json:"namespaces"`
+}
+
+// NamespaceRegistries holds the registry records of the model-mesh service of a namespace,
+// keyed by model or vmodel id. The records are json documents internal to model-mesh.
+type NamespaceRegistries struct {
+	Namespace   string                     `json:"namespace"`
+	ServiceName string                     `json:"serviceName"`
+	Models      map[string]json.RawMessage `json:"models"`
+	VModels     map[string]json.RawMessage `json:"vmodels"`
+}
+
+// RegistryRestoreResult is the outcome of restoring a registry record
+type RegistryRestoreResult struct {
+	Namespace string
+	// ModelRegistryPrefix or VModelRegistryPrefix
+	Registry string
+	Id       string
+	// whether the record was (or would be, in dry-run mode) restored
+	Restored bool
+	// why the record wasn't restored
+	Reason string
+}
+
+// BackupRegistries snapshots the registries of the model-mesh service with the given name in each
+// namespace, from the KV store used by the namespace
+func BackupRegistries(ctx context.Context, kvStores *NamespaceKVStores, serviceName string,
+	namespaces []string) (*RegistryBackup, error) {
+	backup := &RegistryBackup{Version: RegistryBackupVersion, Created: time.Now().UTC()}
+	for _, namespace := range namespaces {
+		kvStore, prefix, err := kvStores.ServicePrefix(ctx, namespace, serviceName)
+		if err != nil {
+			return nil, err
+		}
+		nr := NamespaceRegistries{Namespace: namespace, ServiceName: serviceName}
+		if nr.Models, err = getRegistry(ctx, kvStore, prefix+"/"+ModelRegistryPrefix); err != nil {
+			return nil, fmt.Errorf("failed to back up the model registry of namespace %s: %w", namespace, err)
+		}
+		if nr.VModels, err = getRegistry(ctx, kvStore, prefix+"/"+VModelRegistryPrefix); err != nil {
+			return nil, fmt.Errorf("failed to back up the vmodel registry of namespace %s: %w", namespace, err)
+		}
+		backup.Namespaces = append(backup.Namespaces, nr)
+	}
+	return backup, nil
+}
+
+func getRegistry(ctx context.Context, kvStore KVStore, prefix string) (map[string]json.RawMessage, error) {
+	records, err := kvStore.GetRecords(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	registry := make(map[string]json.RawMessage, len(records))
+	for id, value := range records {
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("record %s/%s isn't valid json", prefix, id)
+		}
+		registry[id] = value
+	}
+	return registry, nil
+}
+
+// RestoreRegistries restores the records of the backup which don't exist in the KV store used by
+// their namespace. Records owned by Predictors are only restored if the Predictor still exists in
+// its source registry.
+func RestoreRegistries(ctx context.Context, kvStores *NamespaceKVStores, backup *RegistryBackup,
+	registries map[string]predictor_source.PredictorRegistry, dryRun bool) ([]RegistryRestoreResult, error) {
+	if backup.Version != RegistryBackupVersion {
+		return nil, fmt.Errorf("unsupported backup version %d, expected %d", backup.Version, RegistryBackupVersion)
+	}
+	var results []RegistryRestoreResult
+	for _, nr := range backup.Namespaces {
+		kvStore, prefix, err := kvStores.ServicePrefix(ctx, nr.Namespace, nr.ServiceName)
+		if err != nil {
+			return results, err
+		}
+		// the reason that each Predictor's records can't be restored, if any
+		owners := map[types.NamespacedName]string{}
+		ownerMissing := func(predictorName, sourceId string) string {
+			nn := types.NamespacedName{Name: predictorName, Namespace: nr.Namespace}
+			key := types.NamespacedName{Name: nn.Name, Namespace: sourceId + "_" + nn.Namespace}
+			if reason, ok := owners[key]; ok {
+				return reason
+			}
+			owners[key] = predictorMissing(ctx, registries, nn, sourceId)
+			return owners[key]
+		}
+		for _, registry := range []string{VModelRegistryPrefix, ModelRegistryPrefix} {
+			records := nr.VModels
+			if registry == ModelRegistryPrefix {
+				records = nr.Models
+			}
+			for _, id := range sortedKeys(records) {
+				result := RegistryRestoreResult{Namespace: nr.Namespace, Registry: registry, Id: id}
+				predictorName, sourceId, owned := modelOwner(id)
+				if registry == VModelRegistryPrefix {
+					sourceId, _ = ownerIDFromVModelRecord(records[id])
+					predictorName, owned = id, sourceId != ""
+				}
+				if owned {
+					result.Reason = ownerMissing(predictorName, sourceId)
+				}
+				if result.Reason == "" {
+					var err error
+					if result.Restored, err = restoreRecord(ctx, kvStore, prefix+"/"+registry, id, records[id], dryRun); err != nil {
+						return results, fmt.Errorf("failed to restore %s record %s of namespace %s: %w",
+							registry, id, nr.Namespace, err)
+					} else if !result.Restored {
+						result.Reason = "already exists"
+					}
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// predictorMissing returns why the Predictor can't be found, or an empty string if it exists
+func predictorMissing(ctx context.Context, registries map[string]predictor_source.PredictorRegistry,
+	nn types.NamespacedName, sourceId string) string {
+	registry, ok := registries[sourceId]
+	if !ok {
+		return fmt.Sprintf("unknown Predictor source %q", sourceId)
+	}
+	p, err := registry.Get(ctx, nn)
+	if errors.IsNotFound(err) || (err == nil && p == nil) {
+		return fmt.Sprintf("%s %s no longer exists", registry.GetSourceName(), nn.Name)
+	} else if err != nil {
+		return fmt.Sprintf("could not get %s %s: %v", registry.GetSourceName(), nn.Name, err)
+	}
+	return ""
+}
+
+func restoreRecord(ctx context.Context, kvStore KVStore, prefix, id string, value []byte, dryRun bool) (bool, error) {
+	if !dryRun {
+		return kvStore.CreateRecord(ctx, prefix, id, value)
+	}
+	existing, err := kvStore.GetRecords(ctx, prefix)
+	if err != nil {
+		return false, err
+	}
+	_, exists := existing[id]
+	return !exists, nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	api "github.com/kserve/modelmesh-serving/apis/serving/v1alpha1"
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+	"github.com/kserve/modelmesh-serving/pkg/constants"
+	"github.com/kserve/modelmesh-serving/pkg/predictor_source"
+)
+
+func Test_RegistryBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	f, dedicated := newFakeEtcd(), newFakeEtcd()
+	f.put("root/mm/modelmesh-serving/vmodels/kept", `{"o":"ksp","a":"kept__ksp-0123456789"}`)
+	f.put("root/mm/modelmesh-serving/vmodels/gone", `{"o":"ksp","a":"gone__ksp-0123456789"}`)
+	f.put("root/mm/modelmesh-serving/vmodels/plugin", `{"o":"plugin","a":"plugin__plugin-0123456789"}`)
+	f.put("root/mm/modelmesh-serving/vmodels/unowned", `{"a":"unowned-model"}`)
+	f.put("root/mm/modelmesh-serving/registry/kept__ksp-0123456789", `{"t":"rt"}`)
+	f.put("root/mm/modelmesh-serving/registry/gone__ksp-0123456789", `{"t":"rt"}`)
+	f.put("root/mm/modelmesh-serving/registry/unowned-model", `{"t":"rt"}`)
+	f.put("root/mm_ns/user/mm/modelmesh-serving/vmodels/user-vm", `{"a":"user-model"}`)
+	dedicated.put("tenant/mm/modelmesh-serving/vmodels/tenant-vm", `{"a":"tenant-model"}`)
+	kvStore := etcdKVStore{client: f.client()}
+	kvStores := newTestNamespaceKVStores(t, f, dedicated)
+	defer kvStores.Close()
+
+	backup, err := BackupRegistries(ctx, kvStores, "modelmesh-serving", []string{testNamespace, "user", "empty", "tenant"})
+	require.NoError(t, err)
+	require.Len(t, backup.Namespaces, 4)
+	assert.Len(t, backup.Namespaces[0].VModels, 4)
+	assert.Len(t, backup.Namespaces[0].Models, 3)
+	assert.Len(t, backup.Namespaces[1].VModels, 1)
+	assert.Empty(t, backup.Namespaces[2].VModels)
+	// the namespace with its own KV store is backed up from it
+	assert.Equal(t, []string{"tenant-vm"}, sortedKeys(backup.Namespaces[3].VModels))
+
+	// round trip through the backup file format
+	b, err := json.Marshal(backup)
+	require.NoError(t, err)
+	restored := &RegistryBackup{}
+	require.NoError(t, json.Unmarshal(b, restored))
+	assert.Equal(t, backup.Namespaces, restored.Namespaces)
+
+	// lose all the data, and one of the Predictors
+	_, err = kvStore.DeletePrefix(ctx, "root")
+	require.NoError(t, err)
+	_, err = etcdKVStore{client: dedicated.client()}.DeletePrefix(ctx, "tenant")
+	require.NoError(t, err)
+	s := runtime.NewScheme()
+	require.NoError(t, api.AddToScheme(s))
+	k8sClient := fake.NewClientBuilder().WithScheme(s).WithObjects(&api.Predictor{
+		ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: testNamespace},
+	}).Build()
+	registries := map[string]predictor_source.PredictorRegistry{
+		"ksp": predictor_source.PredictorCRRegistry{Client: k8sClient},
+	}
+
+	results, err := RestoreRegistries(ctx, kvStores, restored, registries, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), f.count("root/"))
+	dryRunResults := results
+
+	results, err = RestoreRegistries(ctx, kvStores, restored, registries, false)
+	require.NoError(t, err)
+	assert.Equal(t, dryRunResults, results)
+	assert.Equal(t, []RegistryRestoreResult{
+		{Namespace: testNamespace, Registry: VModelRegistryPrefix, Id: "gone", Reason: "Predictor gone no longer exists"},
+		{Namespace: testNamespace, Registry: VModelRegistryPrefix, Id: "kept", Restored: true},
+		{Namespace: testNamespace, Registry: VModelRegistryPrefix, Id: "plugin", Reason: `unknown Predictor source "plugin"`},
+		{Namespace: testNamespace, Registry: VModelRegistryPrefix, Id: "unowned", Restored: true},
+		{Namespace: testNamespace, Registry: ModelRegistryPrefix, Id: "gone__ksp-0123456789", Reason: "Predictor gone no longer exists"},
+		{Namespace: testNamespace, Registry: ModelRegistryPrefix, Id: "kept__ksp-0123456789", Restored: true},
+		{Namespace: testNamespace, Registry: ModelRegistryPrefix, Id: "unowned-model", Restored: true},
+		{Namespace: "user", Registry: VModelRegistryPrefix, Id: "user-vm", Restored: true},
+		{Namespace: "tenant", Registry: VModelRegistryPrefix, Id: "tenant-vm", Restored: true},
+	}, results)
+	assert.Equal(t, int64(4), f.count("root/mm/"))
+	assert.Equal(t, int64(1), f.count("root/mm_ns/user/"))
+	assert.Equal(t, int64(0), f.count("tenant/"))
+	assert.Equal(t, int64(1), dedicated.count("tenant/mm/modelmesh-serving/vmodels/"))
+	records, err := kvStore.GetRecords(ctx, "root/mm/modelmesh-serving/vmodels")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"o":"ksp","a":"kept__ksp-0123456789"}`, string(records["kept"]))
+
+	// existing records aren't overwritten
+	results, err = RestoreRegistries(ctx, kvStores, restored, registries, false)
+	require.NoError(t, err)
+	assert.Equal(t, RegistryRestoreResult{Namespace: "tenant", Registry: VModelRegistryPrefix, Id: "tenant-vm",
+		Reason: "already exists"}, results[len(results)-1])
+
+	restored.Version = 2
+	_, err = RestoreRegistries(ctx, kvStores, restored, registries, false)
+	assert.Error(t, err)
+}
+
+// newTestNamespaceKVStores returns the KV stores of the controller, in the fake etcd f, and of
+// the "tenant" namespace, which has its own KV store in the fake etcd dedicated
+func newTestNamespaceKVStores(t *testing.T, f, dedicated *fakeEtcd) *NamespaceKVStores {
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "model-serving-etcd", Namespace: testNamespace},
+			Data: map[string][]byte{
+				modelmesh.EtcdSecretKey: []byte(`{"endpoints": "http://etcd:2379", "root_prefix": "root"}`),
+			},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "tenant",
+			Annotations: map[string]string{constants.KVStoreSecretAnnotationKey: "tenant-etcd"},
+		}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-etcd", Namespace: "tenant"},
+			Data: map[string][]byte{
+				modelmesh.EtcdSecretKey: []byte(`{"endpoints": "http://tenant-etcd:2379", "root_prefix": "tenant"}`),
+			},
+		},
+	).Build()
+	return newNamespaceKVStores(k8sClient, testNamespace, "model-serving-etcd",
+		func(config KVStoreConfig, _ map[string][]byte) (KVStore, error) {
+			if config.(EtcdConfig).Endpoints == "http://tenant-etcd:2379" {
+				return etcdKVStore{client: dedicated.client()}, nil
+			}
+			return etcdKVStore{client: f.client()}, nil
+		})
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WatchRevisionsConfigMapPrefix is the name prefix of the ConfigMaps in the controller
+// namespace which hold the last seen etcd revision of each watched prefix, one per prefix
+const WatchRevisionsConfigMapPrefix = "modelmesh-watch-revisions-"
+
+// RevisionStore persists the last revision seen by an EtcdRangeWatcher along with the
+// keys under its prefix at that revision, so that its watch can be resumed after a
+// controller restart and the keys deleted in the meantime are noticed even if the
+// revision has since been compacted
+type RevisionStore interface {
+	// Load returns the last saved revision of the given prefix and the values of its
+	// keys, relative to the prefix, or 0 if there isn't one
+	Load(ctx context.Context, prefix string) (int64, map[string][]byte, error)
+	Save(ctx context.Context, prefix string, revision int64, kvs map[string][]byte) error
+}
+
+const (
+	revisionPrefixKey = "prefix"
+	revisionKey       = "revision"
+	revisionKeysKey   = "keys"
+	// the keys of a prefix aren't saved beyond this compressed size, since ConfigMaps hold
+	// at most 1 MiB; the watch of the prefix isn't resumed in that case
+	maxRevisionKeysBytes = 1000 * 1024
+)
+
+// ConfigMapRevisionStore is a RevisionStore which holds the revision of each prefix in
+// its own ConfigMap, so that the saves of busy prefixes don't rewrite those of the others
+type ConfigMapRevisionStore struct {
+	client     client.Client
+	namespace  string
+	namePrefix string
+}
+
+func NewConfigMapRevisionStore(client client.Client, namespace, namePrefix string) *ConfigMapRevisionStore {
+	return &ConfigMapRevisionStore{client: client, namespace: namespace, namePrefix: namePrefix}
+}
+
+func (s *ConfigMapRevisionStore) Load(ctx context.Context, prefix string) (int64, map[string][]byte, error) {
+	cm := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, s.objectKey(prefix), cm); err != nil {
+		if errors.IsNotFound(err) {
+			return 0, nil, nil
+		}
+		return 0, nil, err
+	}
+	v, ok := cm.Data[revisionKey]
+	keys, keysOk := cm.BinaryData[revisionKeysKey]
+	if !ok || !keysOk || cm.Data[revisionPrefixKey] != prefix {
+		// the watch can't be resumed without the keys
+		return 0, nil, nil
+	}
+	revision, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, nil, err
+	}
+	kvs, err := decodeRevisionKeys(keys)
+	if err != nil {
+		return 0, nil, fmt.Errorf("could not decode the keys of revision %d: %w", revision, err)
+	}
+	return revision, kvs, nil
+}
+
+func (s *ConfigMapRevisionStore) Save(ctx context.Context, prefix string, revision int64, kvs map[string][]byte) error {
+	keys, err := encodeRevisionKeys(kvs)
+	if err != nil {
+		return err
+	}
+	objectKey := s.objectKey(prefix)
+	data := map[string]string{revisionPrefixKey: prefix, revisionKey: strconv.FormatInt(revision, 10)}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.client.Get(ctx, objectKey, cm)
+		notfound := errors.IsNotFound(err)
+		if err != nil && !notfound {
+			return err
+		}
+		if len(keys) > maxRevisionKeysBytes {
+			if notfound {
+				return nil
+			}
+			return client.IgnoreNotFound(s.client.Delete(ctx, cm))
+		}
+		if notfound {
+			cm.ObjectMeta = metav1.ObjectMeta{
+				Name:      objectKey.Name,
+				Namespace: objectKey.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "modelmesh-controller",
+				},
+			}
+			cm.Data, cm.BinaryData = data, map[string][]byte{revisionKeysKey: keys}
+			return s.client.Create(ctx, cm)
+		}
+		if reflect.DeepEqual(cm.Data, data) && bytes.Equal(cm.BinaryData[revisionKeysKey], keys) {
+			return nil
+		}
+		cm.Data, cm.BinaryData = data, map[string][]byte{revisionKeysKey: keys}
+		return s.client.Update(ctx, cm)
+	})
+}
+
+// objectKey returns the ConfigMap of the etcd prefix, whose name has a hash of the prefix
+// since it may not be a valid name
+func (s *ConfigMapRevisionStore) objectKey(prefix string) client.ObjectKey {
+	sum := sha256.Sum256([]byte(prefix))
+	return client.ObjectKey{Name: s.namePrefix + hex.EncodeToString(sum[:8]), Namespace: s.namespace}
+}
+
+// encodeRevisionKeys compresses the JSON of the keys and their values
+func encodeRevisionKeys(kvs map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(kvs); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeRevisionKeys(data []byte) (map[string][]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	kvs := map[string][]byte{}
+	if err = json.NewDecoder(zr).Decode(&kvs); err != nil {
+		return nil, err
+	}
+	return kvs, nil
+}
+
+// prefixedRevisionStore keeps the revisions of a KV store other than the controller's
+// apart from those of the same prefixes in the controller's
+type prefixedRevisionStore struct {
+	RevisionStore
+	keyPrefix string
+}
+
+func (s prefixedRevisionStore) Load(ctx context.Context, prefix string) (int64, map[string][]byte, error) {
+	return s.RevisionStore.Load(ctx, s.keyPrefix+prefix)
+}
+
+func (s prefixedRevisionStore) Save(ctx context.Context, prefix string, revision int64, kvs map[string][]byte) error {
+	return s.RevisionStore.Save(ctx, s.keyPrefix+prefix, revision, kvs)
+}
+
+// ownerRevisionStore only persists the owners of the vmodel records, which are all that
+// the DELETEs of the keys deleted while the controller was down need
+type ownerRevisionStore struct {
+	RevisionStore
+}
+
+func (s ownerRevisionStore) Save(ctx context.Context, prefix string, revision int64, kvs map[string][]byte) error {
+	owners := make(map[string][]byte, len(kvs))
+	for k, v := range kvs {
+		owners[k] = nil
+		if v == nil {
+			continue
+		}
+		// records which can't be parsed don't produce events anyway
+		if owner, err := ownerIDFromVModelRecord(v); err == nil {
+			owners[k], _ = json.Marshal(ownerRecord{O: owner})
+		}
+	}
+	return s.RevisionStore.Save(ctx, prefix, revision, owners)
+}
+
+type ownerRecord struct {
+	O string `json:"o"`
+}
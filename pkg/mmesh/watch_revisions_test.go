@@ -0,0 +1,419 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	etcd3pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	etcd3kv "go.etcd.io/etcd/api/v3/mvccpb"
+	etcd3rpc "go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcd3 "go.etcd.io/etcd/client/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeEtcd is an in-memory stand-in for the etcd KV and Watch APIs, which keeps
+// the full history of changes so that ranges and watches can be done at past revisions
+type fakeEtcd struct {
+	etcd3.KV
+	etcd3.Watcher
+
+	mu         sync.Mutex
+	rev        int64
+	compactRev int64
+	history    []*etcd3.Event
+	watches    []*fakeEtcdWatch
+	closed     int
+	// returned by Get if set
+	getErr error
+	// the ops of the Get calls
+	gets []etcd3.Op
+}
+
+type fakeEtcdWatch struct {
+	ctx      context.Context
+	key, end string
+	ch       chan etcd3.WatchResponse
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{rev: 1}
+}
+
+func (f *fakeEtcd) client() *etcd3.Client {
+	c := etcd3.NewCtxClient(context.Background())
+	c.KV, c.Watcher = f, f
+	return c
+}
+
+func (f *fakeEtcd) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed++
+	return nil
+}
+
+func (f *fakeEtcd) put(key, value string) {
+	f.record(etcd3kv.PUT, key, value)
+}
+
+func (f *fakeEtcd) delete(key string) {
+	f.record(etcd3kv.DELETE, key, "")
+}
+
+func (f *fakeEtcd) compact() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compactRev = f.rev
+}
+
+func (f *fakeEtcd) record(eventType etcd3kv.Event_EventType, key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recordLocked(eventType, key, value)
+}
+
+func (f *fakeEtcd) recordLocked(eventType etcd3kv.Event_EventType, key, value string) {
+	f.rev++
+	e := &etcd3.Event{Type: eventType, Kv: &etcd3kv.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: f.rev}}
+	f.history = append(f.history, e)
+	for _, w := range f.watches {
+		if inRange(key, w.key, w.end) {
+			w.ch <- etcd3.WatchResponse{Header: etcd3pb.ResponseHeader{Revision: f.rev}, Events: []*etcd3.Event{e}}
+		}
+	}
+}
+
+func inRange(key, start, end string) bool {
+	return key >= start && (end == "" || key < end)
+}
+
+func (f *fakeEtcd) Get(_ context.Context, key string, opts ...etcd3.OpOption) (*etcd3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	op := etcd3.OpGet(key, opts...)
+	f.gets = append(f.gets, op)
+	rev := op.Rev()
+	if rev == 0 {
+		rev = f.rev
+	} else if rev < f.compactRev {
+		return nil, etcd3rpc.ErrCompacted
+	}
+	state := f.state(key, string(op.RangeBytes()), rev)
+	resp := &etcd3.GetResponse{Header: &etcd3pb.ResponseHeader{Revision: f.rev}, Count: int64(len(state))}
+	if !op.IsCountOnly() {
+		for _, kv := range state {
+			resp.Kvs = append(resp.Kvs, kv)
+		}
+		sort.Slice(resp.Kvs, func(i, j int) bool { return string(resp.Kvs[i].Key) < string(resp.Kvs[j].Key) })
+	}
+	return resp, nil
+}
+
+// state returns the keys in the given range at the given revision
+func (f *fakeEtcd) state(key, end string, rev int64) map[string]*etcd3kv.KeyValue {
+	state := map[string]*etcd3kv.KeyValue{}
+	for _, e := range f.history {
+		if e.Kv.ModRevision > rev {
+			break
+		}
+		if k := string(e.Kv.Key); !inRange(k, key, end) {
+			continue
+		} else if e.Type == etcd3kv.PUT {
+			state[k] = e.Kv
+		} else {
+			delete(state, k)
+		}
+	}
+	return state
+}
+
+func (f *fakeEtcd) Delete(_ context.Context, key string, opts ...etcd3.OpOption) (*etcd3.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := etcd3.OpDelete(key, opts...)
+	state := f.state(key, string(op.RangeBytes()), f.rev)
+	for k := range state {
+		f.recordLocked(etcd3kv.DELETE, k, "")
+	}
+	return &etcd3.DeleteResponse{Header: &etcd3pb.ResponseHeader{Revision: f.rev}, Deleted: int64(len(state))}, nil
+}
+
+func (f *fakeEtcd) Txn(context.Context) etcd3.Txn {
+	return &fakeEtcdTxn{f: f}
+}
+
+// fakeEtcdTxn only supports comparing the create revision of keys and puts
+type fakeEtcdTxn struct {
+	f         *fakeEtcd
+	cmps      []etcd3.Cmp
+	then, els []etcd3.Op
+}
+
+func (t *fakeEtcdTxn) If(cs ...etcd3.Cmp) etcd3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *fakeEtcdTxn) Then(ops ...etcd3.Op) etcd3.Txn {
+	t.then = append(t.then, ops...)
+	return t
+}
+
+func (t *fakeEtcdTxn) Else(ops ...etcd3.Op) etcd3.Txn {
+	t.els = append(t.els, ops...)
+	return t
+}
+
+func (t *fakeEtcdTxn) Commit() (*etcd3.TxnResponse, error) {
+	f := t.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	succeeded := true
+	for _, c := range t.cmps {
+		var createRev int64
+		if kv, ok := f.state(string(c.Key), "", f.rev)[string(c.Key)]; ok {
+			createRev = kv.ModRevision // good enough since only compared to 0
+		}
+		if c.Target != etcd3pb.Compare_CREATE || c.Result != etcd3pb.Compare_EQUAL {
+			panic("unsupported comparison")
+		}
+		succeeded = succeeded && createRev == c.TargetUnion.(*etcd3pb.Compare_CreateRevision).CreateRevision
+	}
+	ops := t.then
+	if !succeeded {
+		ops = t.els
+	}
+	for _, op := range ops {
+		if !op.IsPut() {
+			panic("unsupported operation")
+		}
+		f.recordLocked(etcd3kv.PUT, string(op.KeyBytes()), string(op.ValueBytes()))
+	}
+	return &etcd3.TxnResponse{Header: &etcd3pb.ResponseHeader{Revision: f.rev}, Succeeded: succeeded}, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...etcd3.OpOption) etcd3.WatchChan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	op := etcd3.OpGet(key, opts...)
+	w := &fakeEtcdWatch{ctx: ctx, key: key, end: string(op.RangeBytes()), ch: make(chan etcd3.WatchResponse, 64)}
+	if op.Rev() <= f.compactRev {
+		w.ch <- etcd3.WatchResponse{CompactRevision: f.compactRev}
+		close(w.ch)
+		return w.ch
+	}
+	for _, e := range f.history {
+		if e.Kv.ModRevision >= op.Rev() && inRange(string(e.Kv.Key), w.key, w.end) {
+			w.ch <- etcd3.WatchResponse{Header: etcd3pb.ResponseHeader{Revision: e.Kv.ModRevision}, Events: []*etcd3.Event{e}}
+		}
+	}
+	f.watches = append(f.watches, w)
+	go func() {
+		// like etcd, the channel is closed when the watch is cancelled
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i := range f.watches {
+			if f.watches[i] == w {
+				f.watches = append(f.watches[:i], f.watches[i+1:]...)
+				break
+			}
+		}
+		close(w.ch)
+	}()
+	return w.ch
+}
+
+// memRevisionStore is a RevisionStore which doesn't persist anything
+type memRevisionStore struct {
+	sync.Mutex
+	revisions map[string]int64
+	kvs       map[string]map[string][]byte
+}
+
+func newMemRevisionStore() *memRevisionStore {
+	return &memRevisionStore{revisions: map[string]int64{}, kvs: map[string]map[string][]byte{}}
+}
+
+func (s *memRevisionStore) Load(_ context.Context, prefix string) (int64, map[string][]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.revisions[prefix], s.kvs[prefix], nil
+}
+
+func (s *memRevisionStore) Save(_ context.Context, prefix string, revision int64, kvs map[string][]byte) error {
+	s.Lock()
+	defer s.Unlock()
+	s.revisions[prefix], s.kvs[prefix] = revision, kvs
+	return nil
+}
+
+func watchFakeEtcd(t *testing.T, f *fakeEtcd, revisions RevisionStore) (<-chan kvEvent, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := make(chan kvEvent, 16)
+	w := NewEtcdRangeWatcher(logger, f.client(), "mm/svc/vmodels/")
+	w.Revisions = revisions
+	w.Start(ctx, false, func(eventType KeyEventType, key string, value []byte) {
+		events <- kvEvent{eventType, key, string(value)}
+	})
+	return events, cancel
+}
+
+func Test_EtcdRangeWatcher_Resume(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("mm/svc/vmodels/vm1", "v1")
+	f.put("mm/svc/vmodels/vm2", "v2")
+	revisions := newMemRevisionStore()
+
+	// the first start sends every key
+	events, _ := watchFakeEtcd(t, f, revisions)
+	expectEvents(t, events, kvEvent{UPDATE, "vm1", "v1"}, kvEvent{UPDATE, "vm2", "v2"}, kvEvent{INITIALIZED, "", ""})
+	f.put("mm/svc/vmodels/vm3", "v3")
+	expectEvents(t, events, kvEvent{UPDATE, "vm3", "v3"})
+
+	// simulate the last save prior to a restart
+	require.NoError(t, revisions.Save(context.Background(), "mm/svc/vmodels/", f.rev,
+		map[string][]byte{"vm1": []byte("v1"), "vm2": []byte("v2"), "vm3": []byte("v3")}))
+	f.put("mm/svc/vmodels/vm1", "v1b")
+	f.delete("mm/svc/vmodels/vm2")
+
+	// a resumed watch sends only the changes since the saved revision
+	events, _ = watchFakeEtcd(t, f, revisions)
+	expectEvents(t, events, kvEvent{INITIALIZED, "", ""}, kvEvent{UPDATE, "vm1", "v1b"}, kvEvent{DELETE, "vm2", "v2"})
+	assert.Empty(t, events)
+}
+
+func Test_EtcdRangeWatcher_ResumeCompacted(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("mm/svc/vmodels/vm1", "v1")
+	f.put("mm/svc/vmodels/vm2", "v2")
+	revisions := newMemRevisionStore()
+	require.NoError(t, revisions.Save(context.Background(), "mm/svc/vmodels/", f.rev,
+		map[string][]byte{"vm1": []byte("v1"), "vm2": []byte("v2")}))
+	f.delete("mm/svc/vmodels/vm1")
+	f.put("mm/svc/vmodels/vm2", "v2b")
+	f.put("mm/svc/vmodels/vm3", "v3")
+	f.compact()
+
+	// the saved revision is no longer available, only modified keys are sent
+	// and the saved keys which no longer exist are deleted
+	events, _ := watchFakeEtcd(t, f, revisions)
+	expectEvents(t, events, kvEvent{UPDATE, "vm2", "v2b"}, kvEvent{UPDATE, "vm3", "v3"}, kvEvent{INITIALIZED, "", ""},
+		kvEvent{DELETE, "vm1", "v1"})
+	assert.Empty(t, events)
+
+	f.delete("mm/svc/vmodels/vm2")
+	expectEvents(t, events, kvEvent{DELETE, "vm2", "v2b"})
+}
+
+func Test_ConfigMapRevisionStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewConfigMapRevisionStore(fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+		"modelmesh-serving", WatchRevisionsConfigMapPrefix)
+
+	rev, kvs, err := s.Load(ctx, "root/mm/svc/vmodels/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), rev)
+	assert.Empty(t, kvs)
+
+	vmodels := map[string][]byte{"vm1": []byte("v1"), "vm2": {}}
+	require.NoError(t, s.Save(ctx, "root/mm/svc/vmodels/", 42, vmodels))
+	require.NoError(t, s.Save(ctx, "root/mm_ns/user-ns/mm/svc/registry/", 7, map[string][]byte{}))
+	require.NoError(t, s.Save(ctx, "root.mm.svc.vmodels", 3, map[string][]byte{"vm3": []byte("v3")}))
+
+	rev, kvs, err = s.Load(ctx, "root/mm/svc/vmodels/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), rev)
+	assert.Equal(t, vmodels, kvs)
+	rev, kvs, err = s.Load(ctx, "root/mm_ns/user-ns/mm/svc/registry/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), rev)
+	assert.Empty(t, kvs)
+	rev, _, err = s.Load(ctx, "root.mm.svc.vmodels")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rev)
+
+	// each prefix has its own ConfigMap
+	cms := &corev1.ConfigMapList{}
+	require.NoError(t, s.client.List(ctx, cms))
+	assert.Len(t, cms.Items, 3)
+	for _, cm := range cms.Items {
+		assert.True(t, strings.HasPrefix(cm.Name, WatchRevisionsConfigMapPrefix), cm.Name)
+		assert.LessOrEqual(t, len(cm.Name), 63)
+	}
+
+	// unchanged revisions aren't written again
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, s.client.Get(ctx, s.objectKey("root/mm/svc/vmodels/"), cm))
+	require.NoError(t, s.Save(ctx, "root/mm/svc/vmodels/", 42, vmodels))
+	saved := &corev1.ConfigMap{}
+	require.NoError(t, s.client.Get(ctx, s.objectKey("root/mm/svc/vmodels/"), saved))
+	assert.Equal(t, cm.ResourceVersion, saved.ResourceVersion)
+
+	// the watch isn't resumed without the saved keys
+	delete(cm.BinaryData, revisionKeysKey)
+	require.NoError(t, s.client.Update(ctx, cm))
+	rev, kvs, err = s.Load(ctx, "root/mm/svc/vmodels/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), rev)
+	assert.Empty(t, kvs)
+
+	// nor when there are too many keys to save
+	large := map[string][]byte{}
+	for i := 0; i < 40000; i++ {
+		sum := sha256.Sum256([]byte(strconv.Itoa(i)))
+		large[hex.EncodeToString(sum[:])] = nil
+	}
+	require.NoError(t, s.Save(ctx, "root.mm.svc.vmodels", 4, large))
+	rev, _, err = s.Load(ctx, "root.mm.svc.vmodels")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), rev)
+}
+
+func Test_OwnerRevisionStore(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemRevisionStore()
+	s := ownerRevisionStore{mem}
+
+	require.NoError(t, s.Save(ctx, "root/mm/svc/vmodels/", 5, map[string][]byte{
+		"vm1": []byte(`{"o":"isvc-1","a":"vm1__isvc-0123456789","t":"vm1__isvc-0123456789","u":1700000000}`),
+		"vm2": []byte("not json"),
+		"vm3": nil,
+	}))
+	rev, kvs, err := mem.Load(ctx, "root/mm/svc/vmodels/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), rev)
+	// only the owners are kept, which still identify the Predictors of the DELETEs
+	assert.Equal(t, map[string][]byte{"vm1": []byte(`{"o":"isvc-1"}`), "vm2": nil, "vm3": nil}, kvs)
+	owner, err := ownerIDFromVModelRecord(kvs["vm1"])
+	require.NoError(t, err)
+	assert.Equal(t, "isvc-1", owner)
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/go-zookeeper/zk"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+)
+
+const (
+	zkSessionTimeout = 30 * time.Second
+	zkRetryInterval  = 3 * time.Second
+	zkCheckInterval  = 100 * time.Millisecond
+)
+
+// ZookeeperConfig is the json config of a ZooKeeper KV store
+type ZookeeperConfig struct {
+	// comma-separated list of host:port
+	ConnectString string `json:"connect_string"`
+	// chroot path under which model-mesh stores its data
+	RootPrefix string `json:"root_prefix,omitempty"`
+}
+
+func (zc ZookeeperConfig) Type() string {
+	return modelmesh.KVStoreZookeeper
+}
+
+func (zc ZookeeperConfig) SecretKey() string {
+	return modelmesh.ZookeeperSecretKey
+}
+
+func (zc ZookeeperConfig) Prefix() string {
+	if zc.RootPrefix == "" {
+		return ""
+	}
+	return path.Join("/", zc.RootPrefix)
+}
+
+func (zc ZookeeperConfig) ForNamespace(namespace string) KVStoreConfig {
+	zc.RootPrefix = namespaceRootPrefix(zc.Prefix(), namespace)
+	return zc
+}
+
+// ModelMeshConnection returns the connection string used by model-mesh, which includes the chroot path
+func (zc ZookeeperConfig) ModelMeshConnection() string {
+	return zc.ConnectString + zc.Prefix()
+}
+
+func (zc ZookeeperConfig) Connect(_ map[string][]byte, logger logr.Logger) (KVStore, error) {
+	// session events are only logged, the client reconnects by itself
+	conn, _, err := zk.Connect(strings.Split(zc.ConnectString, ","), zkSessionTimeout,
+		zk.WithLogger(zkLogger{logger.WithName("zk")}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zookeeper client: %w", err)
+	}
+	return zkKVStore{conn}, nil
+}
+
+type zkLogger struct {
+	logger logr.Logger
+}
+
+func (l zkLogger) Printf(format string, args ...interface{}) {
+	l.logger.V(1).Info(fmt.Sprintf(format, args...))
+}
+
+// zkConn is the subset of *zk.Conn used to watch model-mesh's registries
+type zkConn interface {
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Delete(path string, version int32) error
+	State() zk.State
+	Close()
+}
+
+type zkKVStore struct {
+	conn zkConn
+}
+
+// WatchPrefix watches the children of the znode at the given path, the prefix
+// of model-mesh's etcd keys corresponds to a parent znode in ZooKeeper
+func (zs zkKVStore) WatchPrefix(ctx context.Context, logger logr.Logger, prefix string,
+	keysOnly bool, listener KvListener) {
+	w := &zkRangeWatcher{
+		conn:     zs.conn,
+		dir:      path.Join("/", prefix),
+		keysOnly: keysOnly,
+		listener: listener,
+	}
+	w.logger = logger.WithName("ZookeeperRangeWatcher").WithValues("WatchPrefix", w.dir)
+	w.logger.Info("ZookeeperRangeWatcher starting")
+	w.run(ctx)
+}
+
+// Check waits for the ZooKeeper session to be established
+func (zs zkKVStore) Check(ctx context.Context) error {
+	for {
+		switch zs.conn.State() {
+		case zk.StateHasSession:
+			return nil
+		case zk.StateAuthFailed:
+			return &KVStoreError{Type: modelmesh.KVStoreZookeeper, Reason: KVStoreAuthFailed, Err: zk.ErrAuthFailed}
+		}
+		if !sleep(ctx, zkCheckInterval) {
+			return &KVStoreError{Type: modelmesh.KVStoreZookeeper, Reason: KVStoreUnreachable, Err: ctx.Err()}
+		}
+	}
+}
+
+func (zs zkKVStore) ListChildren(_ context.Context, prefix string) ([]string, error) {
+	children, _, err := zs.conn.Children(path.Join("/", prefix))
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	}
+	return children, err
+}
+
+// DeletePrefix deletes the znode at the given path along with all its descendants
+func (zs zkKVStore) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	p := path.Join("/", prefix)
+	children, _, err := zs.conn.Children(p)
+	if errors.Is(err, zk.ErrNoNode) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	var deleted int64
+	for _, child := range children {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		n, err := zs.DeletePrefix(ctx, path.Join(p, child))
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+	if err = zs.conn.Delete(p, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return deleted, err
+	}
+	return deleted + 1, nil
+}
+
+func (zs zkKVStore) GetRecords(_ context.Context, prefix string) (map[string][]byte, error) {
+	dir := path.Join("/", prefix)
+	children, _, err := zs.conn.Children(dir)
+	if errors.Is(err, zk.ErrNoNode) {
+		return map[string][]byte{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	records := make(map[string][]byte, len(children))
+	for _, child := range children {
+		value, _, err := zs.conn.Get(path.Join(dir, child))
+		if errors.Is(err, zk.ErrNoNode) {
+			continue // deleted in the meantime
+		} else if err != nil {
+			return nil, err
+		}
+		records[child] = value
+	}
+	return records, nil
+}
+
+func (zs zkKVStore) CreateRecord(_ context.Context, prefix, name string, value []byte) (bool, error) {
+	dir := path.Join("/", prefix)
+	// create any missing parent znodes
+	for i := 1; i <= len(dir); i++ {
+		if i == len(dir) || dir[i] == '/' {
+			if _, err := zs.conn.Create(dir[:i], nil, 0, zk.WorldACL(zk.PermAll)); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+				return false, err
+			}
+		}
+	}
+	_, err := zs.conn.Create(path.Join(dir, name), value, 0, zk.WorldACL(zk.PermAll))
+	if errors.Is(err, zk.ErrNodeExists) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (zs zkKVStore) Close() error {
+	zs.conn.Close()
+	return nil
+}
+
+type zkRangeWatcher struct {
+	conn     zkConn
+	dir      string
+	keysOnly bool
+	listener KvListener
+	logger   logr.Logger
+
+	cache map[string]*zkEntry
+	// names of the children whose data watch fired
+	dataEvents chan string
+}
+
+type zkEntry struct {
+	value []byte
+	mzxid int64
+	// set when the data watch fired and the value must be re-read
+	stale bool
+}
+
+func (w *zkRangeWatcher) run(ctx context.Context) {
+	w.cache = make(map[string]*zkEntry)
+	w.dataEvents = make(chan string, 16)
+	initSent := false
+	var childEvents <-chan zk.Event
+	for ctx.Err() == nil {
+		if childEvents == nil {
+			children, _, ch, err := w.conn.ChildrenW(w.dir)
+			if errors.Is(err, zk.ErrNoNode) {
+				// the parent znode is created when model-mesh first registers a model
+				var exists bool
+				if exists, _, ch, err = w.conn.ExistsW(w.dir); err == nil && !exists {
+					children = nil
+				} else if err == nil {
+					continue // created in the meantime
+				}
+			}
+			if err != nil {
+				w.logger.Error(err, "Error refreshing znode children, retrying after 3sec")
+				if !sleep(ctx, zkRetryInterval) {
+					return
+				}
+				continue
+			}
+			childEvents = ch
+			w.refreshChildren(ctx, children)
+		}
+		var retry <-chan time.Time
+		for name, e := range w.cache {
+			if e.stale {
+				if w.refreshData(ctx, name, e); e.stale {
+					retry = time.After(zkRetryInterval)
+				}
+			}
+		}
+		if !initSent {
+			w.listener(INITIALIZED, "", nil)
+			initSent = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-retry:
+		case <-childEvents:
+			childEvents = nil
+		case name := <-w.dataEvents:
+			if e, ok := w.cache[name]; ok {
+				e.stale = true
+			}
+		}
+	}
+}
+
+func (w *zkRangeWatcher) refreshChildren(ctx context.Context, children []string) {
+	found := make(map[string]struct{}, len(children))
+	for _, name := range children {
+		found[name] = struct{}{}
+		if _, ok := w.cache[name]; ok {
+			continue
+		}
+		e := &zkEntry{}
+		w.cache[name] = e
+		if w.refreshData(ctx, name, e); e.mzxid == 0 && !e.stale {
+			delete(w.cache, name) // already deleted
+		}
+	}
+	for name, e := range w.cache {
+		if _, ok := found[name]; !ok {
+			delete(w.cache, name)
+			w.listener(DELETE, name, e.value)
+		}
+	}
+}
+
+func (w *zkRangeWatcher) refreshData(ctx context.Context, name string, e *zkEntry) {
+	data, stat, ch, err := w.conn.GetW(path.Join(w.dir, name))
+	if err != nil {
+		if errors.Is(err, zk.ErrNoNode) {
+			// deletion is handled when the children watch fires
+			e.stale = false
+		} else {
+			w.logger.Error(err, "Error reading znode", "key", name)
+			e.stale = true
+		}
+		return
+	}
+	e.stale = false
+	go func() {
+		select {
+		case <-ch:
+			select {
+			case w.dataEvents <- name:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+		}
+	}()
+	if stat.Mzxid != e.mzxid {
+		if w.keysOnly {
+			// like etcd's keys-only watches, the updates of the data are sent without it
+			data = nil
+		}
+		e.value, e.mzxid = data, stat.Mzxid
+		w.listener(UPDATE, name, data)
+	}
+}
+
+// returns false if the context was cancelled
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,333 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mmesh
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-zookeeper/zk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kserve/modelmesh-serving/controllers/modelmesh"
+)
+
+// fakeZk is an in-memory stand-in for a ZooKeeper server, with one-shot watches
+type fakeZk struct {
+	mu           sync.Mutex
+	zxid         int64
+	nodes        map[string]*fakeZnode
+	childWatches map[string][]chan zk.Event
+	dataWatches  map[string][]chan zk.Event
+}
+
+type fakeZnode struct {
+	data  []byte
+	mzxid int64
+}
+
+func newFakeZk() *fakeZk {
+	return &fakeZk{
+		nodes:        map[string]*fakeZnode{"/": {}},
+		childWatches: map[string][]chan zk.Event{},
+		dataWatches:  map[string][]chan zk.Event{},
+	}
+}
+
+func (f *fakeZk) fire(watches map[string][]chan zk.Event, p string, eventType zk.EventType) {
+	for _, ch := range watches[p] {
+		ch <- zk.Event{Type: eventType, Path: p}
+		close(ch)
+	}
+	delete(watches, p)
+}
+
+func (f *fakeZk) watch(watches map[string][]chan zk.Event, p string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	watches[p] = append(watches[p], ch)
+	return ch
+}
+
+// set creates or updates the znode at the given path, creating any missing parents
+func (f *fakeZk) set(p string, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(p, data)
+}
+
+func (f *fakeZk) setLocked(p string, data string) {
+	if parent := path.Dir(p); f.nodes[parent] == nil {
+		f.setLocked(parent, "")
+	}
+	f.zxid++
+	if n, ok := f.nodes[p]; ok {
+		n.data, n.mzxid = []byte(data), f.zxid
+		f.fire(f.dataWatches, p, zk.EventNodeDataChanged)
+		return
+	}
+	f.nodes[p] = &fakeZnode{data: []byte(data), mzxid: f.zxid}
+	f.fire(f.dataWatches, p, zk.EventNodeCreated)
+	f.fire(f.childWatches, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+func (f *fakeZk) delete(p string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.nodes, p)
+	f.fire(f.dataWatches, p, zk.EventNodeDeleted)
+	f.fire(f.childWatches, p, zk.EventNodeDeleted)
+	f.fire(f.childWatches, path.Dir(p), zk.EventNodeChildrenChanged)
+}
+
+func (f *fakeZk) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.nodes[p] == nil {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	var children []string
+	for np := range f.nodes {
+		if np != p && path.Dir(np) == p {
+			children = append(children, strings.TrimPrefix(np, p+"/"))
+		}
+	}
+	sort.Strings(children)
+	return children, &zk.Stat{}, f.watch(f.childWatches, p), nil
+}
+
+func (f *fakeZk) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.nodes[p]
+	if n == nil {
+		return nil, nil, nil, zk.ErrNoNode
+	}
+	return n.data, &zk.Stat{Mzxid: n.mzxid}, f.watch(f.dataWatches, p), nil
+}
+
+func (f *fakeZk) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := f.nodes[p]
+	ch := f.watch(f.dataWatches, p)
+	if n == nil {
+		return false, nil, ch, nil
+	}
+	return true, &zk.Stat{Mzxid: n.mzxid}, ch, nil
+}
+
+func (f *fakeZk) Children(p string) ([]string, *zk.Stat, error) {
+	children, stat, _, err := f.ChildrenW(p)
+	return children, stat, err
+}
+
+func (f *fakeZk) Get(p string) ([]byte, *zk.Stat, error) {
+	data, stat, _, err := f.GetW(p)
+	return data, stat, err
+}
+
+func (f *fakeZk) Create(p string, data []byte, _ int32, _ []zk.ACL) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.nodes[p] != nil {
+		return "", zk.ErrNodeExists
+	} else if f.nodes[path.Dir(p)] == nil {
+		return "", zk.ErrNoNode
+	}
+	f.setLocked(p, string(data))
+	return p, nil
+}
+
+func (f *fakeZk) Delete(p string, _ int32) error {
+	f.mu.Lock()
+	if f.nodes[p] == nil {
+		f.mu.Unlock()
+		return zk.ErrNoNode
+	}
+	for np := range f.nodes {
+		if path.Dir(np) == p && np != p {
+			f.mu.Unlock()
+			return zk.ErrNotEmpty
+		}
+	}
+	f.mu.Unlock()
+	f.delete(p)
+	return nil
+}
+
+func (f *fakeZk) State() zk.State {
+	return zk.StateHasSession
+}
+
+func (f *fakeZk) Close() {}
+
+type kvEvent struct {
+	eventType KeyEventType
+	key       string
+	value     string
+}
+
+func watchFakeZk(t *testing.T, f *fakeZk, prefix string, keysOnly bool) <-chan kvEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events := make(chan kvEvent, 16)
+	go zkKVStore{f}.WatchPrefix(ctx, ctrl.Log, prefix, keysOnly, func(eventType KeyEventType, key string, value []byte) {
+		events <- kvEvent{eventType, key, string(value)}
+	})
+	return events
+}
+
+func expectEvents(t *testing.T, events <-chan kvEvent, expected ...kvEvent) {
+	t.Helper()
+	for _, e := range expected {
+		select {
+		case actual := <-events:
+			assert.Equal(t, e, actual)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for event %v", e)
+		}
+	}
+}
+
+func Test_ZookeeperWatchPrefix(t *testing.T) {
+	f := newFakeZk()
+	f.set("/root/mm/svc/vmodels/vm1", "v1")
+	events := watchFakeZk(t, f, "root/mm/svc/vmodels", false)
+
+	expectEvents(t, events, kvEvent{UPDATE, "vm1", "v1"}, kvEvent{INITIALIZED, "", ""})
+
+	f.set("/root/mm/svc/vmodels/vm2", "v2")
+	expectEvents(t, events, kvEvent{UPDATE, "vm2", "v2"})
+
+	f.set("/root/mm/svc/vmodels/vm1", "v1b")
+	expectEvents(t, events, kvEvent{UPDATE, "vm1", "v1b"})
+
+	f.delete("/root/mm/svc/vmodels/vm2")
+	expectEvents(t, events, kvEvent{DELETE, "vm2", "v2"})
+}
+
+func Test_ZookeeperWatchPrefix_MissingParent(t *testing.T) {
+	f := newFakeZk()
+	events := watchFakeZk(t, f, "/mm/svc/registry", true)
+
+	expectEvents(t, events, kvEvent{INITIALIZED, "", ""})
+
+	f.set("/mm/svc/registry/m1", "ignored")
+	expectEvents(t, events, kvEvent{UPDATE, "m1", ""})
+
+	f.delete("/mm/svc/registry/m1")
+	expectEvents(t, events, kvEvent{DELETE, "m1", ""})
+}
+
+func Test_ZookeeperWatchPrefix_KeysOnlyUpdates(t *testing.T) {
+	f := newFakeZk()
+	f.set("/mm/svc/registry/m1", "loading")
+	events := watchFakeZk(t, f, "/mm/svc/registry", true)
+
+	expectEvents(t, events, kvEvent{UPDATE, "m1", ""}, kvEvent{INITIALIZED, "", ""})
+
+	// updates of the model records are sent, without their data
+	f.set("/mm/svc/registry/m1", "loaded")
+	expectEvents(t, events, kvEvent{UPDATE, "m1", ""})
+	f.set("/mm/svc/registry/m1", "failed")
+	expectEvents(t, events, kvEvent{UPDATE, "m1", ""})
+}
+
+func Test_KVStoreConfigFromSecret(t *testing.T) {
+	kvc, err := KVStoreConfigFromSecret("s", map[string][]byte{
+		modelmesh.ZookeeperSecretKey: []byte(`{"connect_string": "zk-0:2181,zk-1:2181", "root_prefix": "mmesh"}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, modelmesh.KVStoreZookeeper, kvc.Type())
+	assert.Equal(t, "/mmesh", kvc.Prefix())
+
+	nsc := kvc.ForNamespace("user-ns")
+	assert.Equal(t, "/mmesh/mm_ns/user-ns", nsc.Prefix())
+	assert.Equal(t, "zk-0:2181,zk-1:2181/mmesh/mm_ns/user-ns", nsc.(ZookeeperConfig).ModelMeshConnection())
+	assert.Equal(t, "zk-0:2181,zk-1:2181", ZookeeperConfig{ConnectString: "zk-0:2181,zk-1:2181"}.ModelMeshConnection())
+
+	kvc, err = KVStoreConfigFromSecret("s", map[string][]byte{
+		modelmesh.EtcdSecretKey: []byte(`{"endpoints": "http://etcd:2379", "root_prefix": "mmesh"}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, modelmesh.KVStoreEtcd, kvc.Type())
+	assert.Equal(t, "mmesh/mm_ns/user-ns", kvc.ForNamespace("user-ns").Prefix())
+
+	_, err = KVStoreConfigFromSecret("s", map[string][]byte{
+		modelmesh.ZookeeperSecretKey: []byte(`{"root_prefix": "mmesh"}`),
+	})
+	assert.ErrorContains(t, err, "connect_string")
+
+	_, err = KVStoreConfigFromSecret("s", map[string][]byte{})
+	assert.ErrorContains(t, err, "was not found")
+}
+
+func Test_ZookeeperDeletePrefix(t *testing.T) {
+	f := newFakeZk()
+	f.set("/root/mm_ns/ns1/mm/svc/vmodels/vm1", "v")
+	f.set("/root/mm_ns/ns1/mm/svc/registry/m1", "v")
+	f.set("/root/mm_ns/ns2/mm/svc/vmodels/vm1", "v")
+	zs := zkKVStore{conn: f}
+	ctx := context.Background()
+
+	children, err := zs.ListChildren(ctx, "root/mm_ns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns1", "ns2"}, children)
+
+	// every znode beneath and including ns1
+	deleted, err := zs.DeletePrefix(ctx, "root/mm_ns/ns1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), deleted)
+	children, err = zs.ListChildren(ctx, "root/mm_ns")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns2"}, children)
+
+	children, err = zs.ListChildren(ctx, "other")
+	require.NoError(t, err)
+	assert.Empty(t, children)
+}
+
+func Test_ZookeeperRecords(t *testing.T) {
+	f := newFakeZk()
+	f.set("/root/mm/svc/vmodels/vm1", "v1")
+	zs := zkKVStore{conn: f}
+	ctx := context.Background()
+
+	// the parent znodes are created as needed
+	created, err := zs.CreateRecord(ctx, "root/mm_ns/ns/mm/svc/vmodels", "vm2", []byte("v2"))
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = zs.CreateRecord(ctx, "root/mm/svc/vmodels", "vm1", []byte("v3"))
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	records, err := zs.GetRecords(ctx, "root/mm/svc/vmodels")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"vm1": []byte("v1")}, records)
+	records, err = zs.GetRecords(ctx, "root/mm_ns/ns/mm/svc/vmodels")
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"vm2": []byte("v2")}, records)
+
+	records, err = zs.GetRecords(ctx, "other")
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	accessLogFormatText = "text"
+	accessLogFormatJSON = "json"
+)
+
+// accessLogEntry is a line of the access log, written once the request is served
+type accessLogEntry struct {
+	Time       string            `json:"time"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Model      string            `json:"model,omitempty"`
+	Status     int               `json:"status"`
+	DurationMs int64             `json:"duration_ms"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+type accessLogEntryKey struct{}
+
+// setAccessLogModel records the model of the request in its access log entry, if it's logged
+func setAccessLogModel(ctx context.Context, model string) {
+	if entry, ok := ctx.Value(accessLogEntryKey{}).(*accessLogEntry); ok {
+		entry.Model = model
+	}
+}
+
+// statusRecorder captures the status of the response, and keeps the streamed
+// completions flushed
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// accessLog writes a line to out for the sampled fraction of the requests, including the
+// values of the given request headers
+func accessLog(out io.Writer, format string, sampleRatio float64, headers []string, next http.Handler) http.Handler {
+	var mutex sync.Mutex
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sampleRatio <= 0 || rand.Float64() >= sampleRatio {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		entry := &accessLogEntry{Method: r.Method, Path: r.URL.Path}
+		for _, header := range headers {
+			if value := r.Header.Get(header); value != "" {
+				if entry.Headers == nil {
+					entry.Headers = make(map[string]string, len(headers))
+				}
+				entry.Headers[header] = value
+			}
+		}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogEntryKey{}, entry)))
+		entry.Time = start.UTC().Format(time.RFC3339Nano)
+		entry.Status = rec.status
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		entry.DurationMs = time.Since(start).Milliseconds()
+
+		line := entry.text()
+		if format == accessLogFormatJSON {
+			b, _ := json.Marshal(entry)
+			line = string(b)
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, _ = fmt.Fprintln(out, line)
+	})
+}
+
+func (e *accessLogEntry) text() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s %s status=%d duration=%dms", e.Time, e.Method, e.Path, e.Status, e.DurationMs)
+	if e.Model != "" {
+		fmt.Fprintf(&sb, " model=%s", e.Model)
+	}
+	names := make([]string, 0, len(e.Headers))
+	for header := range e.Headers {
+		names = append(names, header)
+	}
+	sort.Strings(names)
+	for _, header := range names {
+		fmt.Fprintf(&sb, " %s=%q", header, e.Headers[header])
+	}
+	return sb.String()
+}
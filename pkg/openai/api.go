@@ -0,0 +1,230 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openai implements a proxy which serves the OpenAI completions and chat
+// completions API for text generation models served by model-mesh. Requests are
+// translated to KServe v2 ModelInfer requests routed to the vmodel named by their
+// model field.
+package openai
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kserve/modelmesh-serving/generated/inference"
+)
+
+const (
+	// names of the tensors of text generation models
+	TextInputName  = "text_input"
+	TextOutputName = "text_output"
+
+	bytesDatatype = "BYTES"
+)
+
+// stringOrList is a JSON field which can be a string or an array of strings
+type stringOrList []string
+
+func (s *stringOrList) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*s = stringOrList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return errors.New("must be a string or an array of strings")
+	}
+	*s = list
+	return nil
+}
+
+// samplingParams are the request fields common to completions and chat completions
+type samplingParams struct {
+	Model       string       `json:"model"`
+	MaxTokens   *int64       `json:"max_tokens,omitempty"`
+	Temperature *float64     `json:"temperature,omitempty"`
+	TopP        *float64     `json:"top_p,omitempty"`
+	Stop        stringOrList `json:"stop,omitempty"`
+	N           *int         `json:"n,omitempty"`
+	Stream      bool         `json:"stream,omitempty"`
+	User        string       `json:"user,omitempty"`
+}
+
+type CompletionRequest struct {
+	samplingParams
+	Prompt stringOrList `json:"prompt"`
+}
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ChatCompletionRequest struct {
+	samplingParams
+	Messages []ChatMessage `json:"messages"`
+}
+
+type CompletionChoice struct {
+	Index        int     `json:"index"`
+	Text         string  `json:"text"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+type CompletionResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []CompletionChoice `json:"choices"`
+}
+
+type ChatCompletionChoice struct {
+	Index int `json:"index"`
+	// set in responses
+	Message *ChatMessage `json:"message,omitempty"`
+	// set in stream chunks
+	Delta        *ChatMessage `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+type ChatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+}
+
+type ErrorResponse struct {
+	Error ErrorInfo `json:"error"`
+}
+
+type ErrorInfo struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Code    *string `json:"code"`
+}
+
+func (p *samplingParams) validate() error {
+	if p.Model == "" {
+		return errors.New("'model' is required")
+	}
+	if p.N != nil && *p.N != 1 {
+		return errors.New("only one choice can be generated, 'n' must be 1")
+	}
+	if p.MaxTokens != nil && *p.MaxTokens <= 0 {
+		return errors.New("'max_tokens' must be positive")
+	}
+	return nil
+}
+
+func (r *CompletionRequest) validate() error {
+	if err := r.samplingParams.validate(); err != nil {
+		return err
+	}
+	if len(r.Prompt) != 1 {
+		return errors.New("'prompt' must be a single string")
+	}
+	return nil
+}
+
+func (r *ChatCompletionRequest) validate() error {
+	if err := r.samplingParams.validate(); err != nil {
+		return err
+	}
+	if len(r.Messages) == 0 {
+		return errors.New("'messages' must not be empty")
+	}
+	return nil
+}
+
+// chatPrompt renders the messages of a chat as the prompt of a text generation model,
+// one "<role>: <content>" line per message followed by the assistant's turn
+func chatPrompt(messages []ChatMessage) string {
+	var sb strings.Builder
+	for _, m := range messages {
+		sb.WriteString(m.Role)
+		sb.WriteString(": ")
+		sb.WriteString(m.Content)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("assistant:")
+	return sb.String()
+}
+
+// inferRequest returns the ModelInfer request generating the completion of the prompt.
+// This version of the v2 protocol has no floating point parameters, those are passed as strings.
+func inferRequest(prompt string, p *samplingParams) *inference.ModelInferRequest {
+	params := map[string]*inference.InferParameter{}
+	if p.MaxTokens != nil {
+		params["max_tokens"] = &inference.InferParameter{
+			ParameterChoice: &inference.InferParameter_Int64Param{Int64Param: *p.MaxTokens}}
+	}
+	if p.Temperature != nil {
+		params["temperature"] = &inference.InferParameter{ParameterChoice: &inference.InferParameter_StringParam{
+			StringParam: strconv.FormatFloat(*p.Temperature, 'f', -1, 64)}}
+	}
+	if p.TopP != nil {
+		params["top_p"] = &inference.InferParameter{ParameterChoice: &inference.InferParameter_StringParam{
+			StringParam: strconv.FormatFloat(*p.TopP, 'f', -1, 64)}}
+	}
+	if len(p.Stop) > 0 {
+		stop, _ := json.Marshal([]string(p.Stop))
+		params["stop"] = &inference.InferParameter{
+			ParameterChoice: &inference.InferParameter_StringParam{StringParam: string(stop)}}
+	}
+	return &inference.ModelInferRequest{
+		ModelName: p.Model,
+		Inputs: []*inference.ModelInferRequest_InferInputTensor{{
+			Name:     TextInputName,
+			Datatype: bytesDatatype,
+			Shape:    []int64{1},
+			Contents: &inference.InferTensorContents{BytesContents: [][]byte{[]byte(prompt)}},
+		}},
+		Parameters: params,
+	}
+}
+
+// generatedText returns the text_output of a ModelInfer response, or its only BYTES output
+func generatedText(resp *inference.ModelInferResponse) (string, error) {
+	index := -1
+	for i, output := range resp.Outputs {
+		if output.Name == TextOutputName || (len(resp.Outputs) == 1 && output.Datatype == bytesDatatype) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return "", fmt.Errorf("model %s returned no %s output", resp.ModelName, TextOutputName)
+	}
+	if contents := resp.Outputs[index].Contents; contents != nil && len(contents.BytesContents) > 0 {
+		return string(contents.BytesContents[0]), nil
+	}
+	if index < len(resp.RawOutputContents) {
+		// raw BYTES elements are prefixed by their 4-byte little-endian length
+		raw := resp.RawOutputContents[index]
+		if len(raw) >= 4 {
+			if n := binary.LittleEndian.Uint32(raw); int(n) <= len(raw)-4 {
+				return string(raw[4 : 4+n]), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("model %s returned an empty %s output", resp.ModelName, TextOutputName)
+}
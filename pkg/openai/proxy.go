@@ -0,0 +1,367 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/kserve/modelmesh-serving/generated/inference"
+)
+
+// The proxy is configured with the environment variables of the REST proxy, which the
+// controller also sets on its container, plus its own listen port and listener options.
+// The REST listener has the same options, the names of both are prefixed with their sidecar's.
+const (
+	OpenAIProxyEnvPrefix  = "OPENAI_PROXY_"
+	RESTListenerEnvPrefix = "REST_PROXY_LISTENER_"
+
+	ListenPortEnvVar     = "LISTEN_PORT"
+	CORSOriginsEnvVar    = "CORS_ALLOWED_ORIGINS"
+	MaxBodySizeEnvVar    = "MAX_REQUEST_BODY_BYTES"
+	AuthTypeEnvVar       = "AUTH_TYPE"
+	AuthTokensEnvVar     = "AUTH_TOKENS_PATH"
+	ListenCertEnvVar     = "LISTEN_TLS_CERT_PATH"
+	ListenKeyEnvVar      = "LISTEN_TLS_KEY_PATH"
+	ListenClientCAEnvVar = "LISTEN_TLS_CLIENT_CA_PATH"
+	AccessLogEnvVar      = "ACCESS_LOG_FORMAT"
+	AccessLogRatioEnvVar = "ACCESS_LOG_SAMPLE_RATIO"
+	AccessLogHdrsEnvVar  = "ACCESS_LOG_REQUEST_HEADERS"
+	// port of the REST proxy behind the REST listener
+	BackendPortEnvVar = "BACKEND_PORT"
+
+	grpcPortEnvVar       = "REST_PROXY_GRPC_PORT"
+	grpcMaxMsgSizeEnvVar = "REST_PROXY_GRPC_MAX_MSG_SIZE_BYTES"
+	useTLSEnvVar         = "REST_PROXY_USE_TLS"
+	skipVerifyEnvVar     = "REST_PROXY_SKIP_VERIFY"
+	tlsCertEnvVar        = "MM_TLS_KEY_CERT_PATH"
+	tlsKeyEnvVar         = "MM_TLS_PRIVATE_KEY_PATH"
+	tlsTrustCertEnvVar   = "MM_TLS_TRUST_CERT_PATH"
+)
+
+const (
+	authTypeBearer = "bearer"
+	authTypeMTLS   = "mtls"
+
+	shutdownTimeout           = 10 * time.Second
+	readHeaderTimeout         = 10 * time.Second
+	defaultGrpcMaxMessageSize = 16 * 1024 * 1024
+)
+
+type Options struct {
+	ListenPort     int
+	GrpcPort       int
+	GrpcMaxMsgSize int
+	// port of the REST proxy behind the REST listener
+	BackendPort int
+	// connect to model-mesh over TLS with the TLS certificate of model-mesh
+	UseTLS     bool
+	SkipVerify bool
+	TLSCert    string
+	TLSKey     string
+	TLSTrust   []string
+	// the TLS certificate of the listener, that of model-mesh if not set
+	ListenCert     string
+	ListenKey      string
+	ListenClientCA string
+
+	CORSAllowedOrigins  []string
+	MaxRequestBodyBytes int64
+	AuthType            string
+	AuthTokensPath      string
+
+	// text or json to write an access log to stdout, none if empty
+	AccessLogFormat         string
+	AccessLogSampleRatio    float64
+	AccessLogRequestHeaders []string
+}
+
+// OptionsFromEnv reads the options from the environment variables of the container of the
+// sidecar, whose own variables have the given prefix
+func OptionsFromEnv(prefix string) (Options, error) {
+	opts := Options{
+		UseTLS:         os.Getenv(useTLSEnvVar) == "true",
+		SkipVerify:     os.Getenv(skipVerifyEnvVar) == "true",
+		TLSCert:        os.Getenv(tlsCertEnvVar),
+		TLSKey:         os.Getenv(tlsKeyEnvVar),
+		ListenCert:     os.Getenv(prefix + ListenCertEnvVar),
+		ListenKey:      os.Getenv(prefix + ListenKeyEnvVar),
+		ListenClientCA: os.Getenv(prefix + ListenClientCAEnvVar),
+		AuthType:       os.Getenv(prefix + AuthTypeEnvVar),
+		AuthTokensPath: os.Getenv(prefix + AuthTokensEnvVar),
+		GrpcMaxMsgSize: defaultGrpcMaxMessageSize,
+
+		AccessLogFormat:      os.Getenv(prefix + AccessLogEnvVar),
+		AccessLogSampleRatio: 1,
+	}
+	if trust := os.Getenv(tlsTrustCertEnvVar); trust != "" {
+		opts.TLSTrust = strings.Split(trust, ",")
+	}
+	if origins := os.Getenv(prefix + CORSOriginsEnvVar); origins != "" {
+		opts.CORSAllowedOrigins = strings.Split(origins, ",")
+	}
+	for envVar, value := range map[string]*int{
+		prefix + ListenPortEnvVar:  &opts.ListenPort,
+		prefix + BackendPortEnvVar: &opts.BackendPort,
+		grpcPortEnvVar:             &opts.GrpcPort,
+		grpcMaxMsgSizeEnvVar:       &opts.GrpcMaxMsgSize,
+	} {
+		if s := os.Getenv(envVar); s != "" {
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return opts, fmt.Errorf("invalid value of %s: %w", envVar, err)
+			}
+			*value = i
+		}
+	}
+	if s := os.Getenv(prefix + MaxBodySizeEnvVar); s != "" {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid value of %s: %w", prefix+MaxBodySizeEnvVar, err)
+		}
+		opts.MaxRequestBodyBytes = i
+	}
+	if s := os.Getenv(prefix + AccessLogRatioEnvVar); s != "" {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return opts, fmt.Errorf("invalid value of %s: %w", prefix+AccessLogRatioEnvVar, err)
+		}
+		opts.AccessLogSampleRatio = f
+	}
+	if headers := os.Getenv(prefix + AccessLogHdrsEnvVar); headers != "" {
+		opts.AccessLogRequestHeaders = strings.Split(headers, ",")
+	}
+	if opts.ListenPort == 0 {
+		return opts, fmt.Errorf("%s must be set", prefix+ListenPortEnvVar)
+	}
+	return opts, nil
+}
+
+// Run serves the OpenAI API until the context is done
+func Run(ctx context.Context, opts Options, log logr.Logger) error {
+	if opts.GrpcPort == 0 {
+		return fmt.Errorf("%s must be set", grpcPortEnvVar)
+	}
+	conn, err := dialModelMesh(opts)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	log.Info("Serving the OpenAI API")
+	return opts.serve(ctx, NewServer(inference.NewGRPCInferenceServiceClient(conn), log).Handler(), log)
+}
+
+// serve serves the handler on the listen port with the listener options until the context is done
+func (opts Options) serve(ctx context.Context, handler http.Handler, log logr.Logger) error {
+	handler, err := opts.wrap(handler)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", opts.ListenPort),
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	certFile, keyFile := opts.ListenCert, opts.ListenKey
+	if certFile == "" && opts.UseTLS {
+		certFile, keyFile = opts.TLSCert, opts.TLSKey
+	}
+	if opts.AuthType == authTypeMTLS {
+		if certFile == "" || opts.ListenClientCA == "" {
+			return errors.New("mtls authentication requires the TLS certificate and client CAs of the listener")
+		}
+		pool, err := certPool(opts.ListenClientCA)
+		if err != nil {
+			return err
+		}
+		server.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	log.Info("Listening", "port", opts.ListenPort, "TLS", certFile != "", "auth", opts.AuthType)
+	if certFile != "" {
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func dialModelMesh(opts Options) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if opts.UseTLS {
+		tlsConfig := &tls.Config{InsecureSkipVerify: opts.SkipVerify}
+		// model-mesh may require client certificates
+		cert, err := tls.LoadX509KeyPair(opts.TLSCert, opts.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not load the TLS certificate of model-mesh: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		if tlsConfig.RootCAs, err = certPool(append([]string{opts.TLSCert}, opts.TLSTrust...)...); err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	return grpc.Dial(fmt.Sprintf("localhost:%d", opts.GrpcPort), grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(opts.GrpcMaxMsgSize)))
+}
+
+func certPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// wrap applies the CORS, authentication, request size and access log options to the handler
+func (opts Options) wrap(handler http.Handler) (http.Handler, error) {
+	if opts.MaxRequestBodyBytes > 0 {
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, opts.MaxRequestBodyBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+	switch opts.AuthType {
+	case "", authTypeMTLS:
+	case authTypeBearer:
+		handler = bearerAuth(&tokenFile{path: opts.AuthTokensPath}, handler)
+	default:
+		return nil, fmt.Errorf("unsupported authentication type %q", opts.AuthType)
+	}
+	if len(opts.CORSAllowedOrigins) > 0 {
+		handler = cors(opts.CORSAllowedOrigins, handler)
+	}
+	switch opts.AccessLogFormat {
+	case "":
+	case accessLogFormatText, accessLogFormatJSON:
+		// rejected requests are logged too
+		handler = accessLog(os.Stdout, opts.AccessLogFormat, opts.AccessLogSampleRatio,
+			opts.AccessLogRequestHeaders, handler)
+	default:
+		return nil, fmt.Errorf("unsupported access log format %q", opts.AccessLogFormat)
+	}
+	return handler, nil
+}
+
+// cors allows the requests from the given origins, before they are authenticated
+// since preflight requests have no credentials
+func cors(allowedOrigins []string, next http.Handler) http.Handler {
+	allowed := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin != "" && (allowed["*"] || allowed[origin]) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerAuth(tokens *tokenFile, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || !tokens.contains(token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenFile holds the accepted bearer tokens, one per line, re-read when the
+// mounted Secret changes
+type tokenFile struct {
+	path string
+
+	mutex   sync.Mutex
+	modTime time.Time
+	tokens  []string
+}
+
+func (tf *tokenFile) contains(token string) bool {
+	tf.mutex.Lock()
+	defer tf.mutex.Unlock()
+	if info, err := os.Stat(tf.path); err == nil && !info.ModTime().Equal(tf.modTime) {
+		if tokens, err := readTokens(tf.path); err == nil {
+			tf.tokens, tf.modTime = tokens, info.ModTime()
+		}
+	}
+	found := false
+	for _, t := range tf.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			found = true
+		}
+	}
+	return found && token != ""
+}
+
+func readTokens(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if token := strings.TrimSpace(scanner.Text()); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, scanner.Err()
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+// RunRESTListener serves the REST port in front of the REST proxy, which listens on the
+// backend port, until the context is done. It enforces the listener options which the
+// REST proxy image doesn't support.
+func RunRESTListener(ctx context.Context, opts Options, log logr.Logger) error {
+	if opts.BackendPort == 0 {
+		return fmt.Errorf("%s must be set", RESTListenerEnvPrefix+BackendPortEnvVar)
+	}
+	log.Info("Serving the REST proxy", "backendPort", opts.BackendPort)
+	return opts.serve(ctx, opts.restProxy(log), log)
+}
+
+// restProxy forwards the requests to the REST proxy, which serves over TLS with the
+// certificate of model-mesh when it's enabled
+func (opts Options) restProxy(log logr.Logger) http.Handler {
+	backend := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", opts.BackendPort)}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.UseTLS {
+		backend.Scheme = "https"
+		// the names of the certificate needn't include localhost, so it's pinned instead
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify:    true,
+			VerifyPeerCertificate: pinnedCertificate(opts.TLSCert),
+		}
+	}
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(backend)
+			r.SetXForwarded()
+		},
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			log.Error(err, "Failed to forward the request to the REST proxy", "path", r.URL.Path)
+			writeError(w, http.StatusBadGateway, "the REST proxy is unavailable")
+		},
+	}
+}
+
+// pinnedCertificate verifies that the peer presents the certificate of the file, which is
+// re-read since it's renewed in place
+func pinnedCertificate(certFile string) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		pemBytes, err := os.ReadFile(certFile)
+		if err != nil {
+			return err
+		}
+		block, _ := pem.Decode(pemBytes)
+		if block == nil || len(rawCerts) == 0 || !bytes.Equal(block.Bytes, rawCerts[0]) {
+			return errors.New("the REST proxy didn't present the TLS certificate of model-mesh")
+		}
+		return nil
+	}
+}
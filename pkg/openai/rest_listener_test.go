@@ -0,0 +1,94 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoRESTProxy responds with the path and body of the requests
+func echoRESTProxy(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_, _ = w.Write([]byte(r.URL.Path + " " + string(body)))
+}
+
+func backendPort(t *testing.T, server *httptest.Server) int {
+	u, _ := url.Parse(server.URL)
+	port, err := strconv.Atoi(u.Port())
+	assert.NoError(t, err)
+	return port
+}
+
+func TestRESTListener(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(echoRESTProxy))
+	defer backend.Close()
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+	assert.NoError(t, os.WriteFile(tokensPath, []byte("token-1\n"), 0600))
+	opts := Options{
+		BackendPort:         backendPort(t, backend),
+		MaxRequestBodyBytes: 64,
+		AuthType:            authTypeBearer,
+		AuthTokensPath:      tokensPath,
+	}
+	handler, err := opts.wrap(opts.restProxy(logr.Discard()))
+	assert.NoError(t, err)
+
+	path := "/v2/models/m/infer"
+	assert.Equal(t, http.StatusUnauthorized, post(handler, path, `{}`).Code)
+	w := post(handler, path, `{"inputs": []}`, "Authorization", "Bearer token-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, path+` {"inputs": []}`, w.Body.String())
+
+	// the body limit applies to requests without a content length too
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(strings.Repeat("a", 65)))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", "Bearer token-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	// an unavailable REST proxy
+	backend.Close()
+	assert.Equal(t, http.StatusBadGateway, post(handler, path, `{}`, "Authorization", "Bearer token-1").Code)
+}
+
+func TestRESTListenerTLS(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(echoRESTProxy))
+	defer backend.Close()
+	certPath := filepath.Join(t.TempDir(), "tls.crt")
+	assert.NoError(t, os.WriteFile(certPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}), 0600))
+	opts := Options{BackendPort: backendPort(t, backend), UseTLS: true, TLSCert: certPath}
+
+	w := post(opts.restProxy(logr.Discard()), "/v2/models/m/infer", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// a REST proxy which doesn't present the certificate of model-mesh is rejected
+	assert.NoError(t, os.WriteFile(certPath, []byte("-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n"), 0600))
+	w = post(opts.restProxy(logr.Discard()), "/v2/models/m/infer", `{}`)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+}
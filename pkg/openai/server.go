@@ -0,0 +1,238 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-serving/generated/inference"
+)
+
+// vModelIdHeader routes inference requests to a vmodel in model-mesh
+const vModelIdHeader = "mm-vmodel-id"
+
+const finishReasonStop = "stop"
+
+// Server translates OpenAI API requests to ModelInfer requests to model-mesh
+type Server struct {
+	client inference.GRPCInferenceServiceClient
+	log    logr.Logger
+	now    func() time.Time
+}
+
+func NewServer(client inference.GRPCInferenceServiceClient, log logr.Logger) *Server {
+	return &Server{client: client, log: log, now: time.Now}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/completions", s.completions)
+	mux.HandleFunc("/v1/chat/completions", s.chatCompletions)
+	return mux
+}
+
+func (s *Server) completions(w http.ResponseWriter, r *http.Request) {
+	req := &CompletionRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	text, ok := s.generate(w, r.Context(), req.Prompt[0], &req.samplingParams)
+	if !ok {
+		return
+	}
+	resp := &CompletionResponse{
+		ID:      newID("cmpl-"),
+		Object:  "text_completion",
+		Created: s.now().Unix(),
+		Model:   req.Model,
+	}
+	if !req.Stream {
+		resp.Choices = []CompletionChoice{{Text: text, FinishReason: stringPtr(finishReasonStop)}}
+		writeJSON(w, resp)
+		return
+	}
+	// model-mesh's inference API is unary, the completion is streamed as a single chunk
+	stream := newEventStream(w)
+	resp.Choices = []CompletionChoice{{Text: text}}
+	stream.send(resp)
+	resp.Choices = []CompletionChoice{{FinishReason: stringPtr(finishReasonStop)}}
+	stream.send(resp)
+	stream.done()
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	req := &ChatCompletionRequest{}
+	if !decodeRequest(w, r, req) {
+		return
+	}
+	if err := req.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	text, ok := s.generate(w, r.Context(), chatPrompt(req.Messages), &req.samplingParams)
+	if !ok {
+		return
+	}
+	resp := &ChatCompletionResponse{
+		ID:      newID("chatcmpl-"),
+		Object:  "chat.completion",
+		Created: s.now().Unix(),
+		Model:   req.Model,
+	}
+	message := &ChatMessage{Role: "assistant", Content: text}
+	if !req.Stream {
+		resp.Choices = []ChatCompletionChoice{{Message: message, FinishReason: stringPtr(finishReasonStop)}}
+		writeJSON(w, resp)
+		return
+	}
+	stream := newEventStream(w)
+	resp.Object = "chat.completion.chunk"
+	resp.Choices = []ChatCompletionChoice{{Delta: message}}
+	stream.send(resp)
+	resp.Choices = []ChatCompletionChoice{{Delta: &ChatMessage{}, FinishReason: stringPtr(finishReasonStop)}}
+	stream.send(resp)
+	stream.done()
+}
+
+// generate returns the text generated by the model for the prompt, or writes the error response
+func (s *Server) generate(w http.ResponseWriter, ctx context.Context, prompt string, p *samplingParams) (string, bool) {
+	setAccessLogModel(ctx, p.Model)
+	ctx = metadata.AppendToOutgoingContext(ctx, vModelIdHeader, p.Model)
+	resp, err := s.client.ModelInfer(ctx, inferRequest(prompt, p))
+	if err != nil {
+		st := status.Convert(err)
+		code := httpStatus(st.Code())
+		if code >= http.StatusInternalServerError {
+			s.log.Error(err, "Inference request failed", "model", p.Model)
+		}
+		writeError(w, code, st.Message())
+		return "", false
+	}
+	text, err := generatedText(resp)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return "", false
+	}
+	return text, true
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "only POST requests are supported")
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		}
+		return false
+	}
+	return true
+}
+
+// httpStatus returns the HTTP status of the response to a failed ModelInfer request
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	errType := "invalid_request_error"
+	if code >= http.StatusInternalServerError {
+		errType = "server_error"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(&ErrorResponse{Error: ErrorInfo{Message: message, Type: errType}})
+}
+
+// eventStream writes server-sent events
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newEventStream(w http.ResponseWriter) *eventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	return &eventStream{w: w, flusher: flusher}
+}
+
+func (es *eventStream) send(v interface{}) {
+	b, _ := json.Marshal(v)
+	es.write("data: " + string(b) + "\n\n")
+}
+
+func (es *eventStream) done() {
+	es.write("data: [DONE]\n\n")
+}
+
+func (es *eventStream) write(event string) {
+	_, _ = es.w.Write([]byte(event))
+	if es.flusher != nil {
+		es.flusher.Flush()
+	}
+}
+
+func newID(prefix string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,254 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/kserve/modelmesh-serving/generated/inference"
+)
+
+// fakeInferenceClient generates the reversed prompt
+type fakeInferenceClient struct {
+	inference.GRPCInferenceServiceClient
+	raw      bool
+	err      error
+	requests []*inference.ModelInferRequest
+	vModelId []string
+}
+
+func (c *fakeInferenceClient) ModelInfer(ctx context.Context, req *inference.ModelInferRequest,
+	_ ...grpc.CallOption) (*inference.ModelInferResponse, error) {
+	c.requests = append(c.requests, req)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	c.vModelId = append(c.vModelId, md.Get(vModelIdHeader)...)
+	if c.err != nil {
+		return nil, c.err
+	}
+	prompt := []rune(string(req.Inputs[0].Contents.BytesContents[0]))
+	for i, j := 0, len(prompt)-1; i < j; i, j = i+1, j-1 {
+		prompt[i], prompt[j] = prompt[j], prompt[i]
+	}
+	output := &inference.ModelInferResponse_InferOutputTensor{Name: TextOutputName, Datatype: bytesDatatype, Shape: []int64{1}}
+	resp := &inference.ModelInferResponse{ModelName: req.ModelName, Outputs: []*inference.ModelInferResponse_InferOutputTensor{output}}
+	if c.raw {
+		raw := binary.LittleEndian.AppendUint32(nil, uint32(len(string(prompt))))
+		resp.RawOutputContents = [][]byte{append(raw, string(prompt)...)}
+	} else {
+		output.Contents = &inference.InferTensorContents{BytesContents: [][]byte{[]byte(string(prompt))}}
+	}
+	return resp, nil
+}
+
+func post(handler http.Handler, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func newTestServer(client *fakeInferenceClient) *Server {
+	s := NewServer(client, logr.Discard())
+	s.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return s
+}
+
+func TestCompletions(t *testing.T) {
+	client := &fakeInferenceClient{}
+	handler := newTestServer(client).Handler()
+
+	w := post(handler, "/v1/completions",
+		`{"model": "gpt-small", "prompt": "olleh", "max_tokens": 16, "temperature": 0.7, "stop": "\n"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	resp := &CompletionResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(t, "text_completion", resp.Object)
+	assert.Equal(t, "gpt-small", resp.Model)
+	assert.Equal(t, int64(1700000000), resp.Created)
+	assert.True(t, strings.HasPrefix(resp.ID, "cmpl-"))
+	assert.Len(t, resp.Choices, 1)
+	assert.Equal(t, "hello", resp.Choices[0].Text)
+	assert.Equal(t, "stop", *resp.Choices[0].FinishReason)
+
+	// the request is routed to the vmodel named by the model field
+	assert.Equal(t, []string{"gpt-small"}, client.vModelId)
+	req := client.requests[0]
+	assert.Equal(t, "gpt-small", req.ModelName)
+	assert.Equal(t, TextInputName, req.Inputs[0].Name)
+	assert.Equal(t, int64(16), req.Parameters["max_tokens"].GetInt64Param())
+	assert.Equal(t, "0.7", req.Parameters["temperature"].GetStringParam())
+	assert.Equal(t, `["\n"]`, req.Parameters["stop"].GetStringParam())
+
+	// raw output contents
+	client.raw = true
+	w = post(handler, "/v1/completions", `{"model": "gpt-small", "prompt": ["dlrow"]}`)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+	assert.Equal(t, "world", resp.Choices[0].Text)
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	client := &fakeInferenceClient{}
+	handler := newTestServer(client).Handler()
+
+	w := post(handler, "/v1/chat/completions",
+		`{"model": "chat-small", "stream": true, "messages": [{"role": "system", "content": "be brief"}, {"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, "system: be brief\nuser: hi\nassistant:",
+		string(client.requests[0].Inputs[0].Contents.BytesContents[0]))
+
+	events := strings.Split(strings.TrimSuffix(w.Body.String(), "\n\n"), "\n\n")
+	assert.Len(t, events, 3)
+	assert.Equal(t, "data: [DONE]", events[2])
+	chunk := &ChatCompletionResponse{}
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[0], "data: ")), chunk))
+	assert.Equal(t, "chat.completion.chunk", chunk.Object)
+	assert.Equal(t, "assistant", chunk.Choices[0].Delta.Role)
+	assert.Equal(t, ":tnatsissa\nih :resu\nfeirb eb :metsys", chunk.Choices[0].Delta.Content)
+	assert.Nil(t, chunk.Choices[0].FinishReason)
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(events[1], "data: ")), chunk))
+	assert.Equal(t, "stop", *chunk.Choices[0].FinishReason)
+}
+
+func TestCompletionErrors(t *testing.T) {
+	client := &fakeInferenceClient{}
+	handler := newTestServer(client).Handler()
+
+	for body, code := range map[string]int{
+		`{"prompt": "hi"}`:                            http.StatusBadRequest,
+		`{"model": "m", "prompt": ["a", "b"]}`:        http.StatusBadRequest,
+		`{"model": "m", "prompt": "hi", "n": 2}`:      http.StatusBadRequest,
+		`{"model": "m", "prompt": "hi", "max_tokens"`: http.StatusBadRequest,
+	} {
+		w := post(handler, "/v1/completions", body)
+		assert.Equal(t, code, w.Code, body)
+		errResp := &ErrorResponse{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), errResp))
+		assert.Equal(t, "invalid_request_error", errResp.Error.Type)
+	}
+	assert.Empty(t, client.requests)
+
+	client.err = status.Error(codes.NotFound, "vmodel not found")
+	w := post(handler, "/v1/chat/completions", `{"model": "missing", "messages": [{"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "vmodel not found")
+
+	client.err = status.Error(codes.Unavailable, "no runtime pods")
+	w = post(handler, "/v1/completions", `{"model": "m", "prompt": "hi"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "server_error")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/completions", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestListenerOptions(t *testing.T) {
+	tokensPath := filepath.Join(t.TempDir(), "tokens")
+	assert.NoError(t, os.WriteFile(tokensPath, []byte("token-1\n\ntoken-2\n"), 0600))
+	opts := Options{
+		CORSAllowedOrigins:  []string{"https://app.example.com"},
+		MaxRequestBodyBytes: 64,
+		AuthType:            authTypeBearer,
+		AuthTokensPath:      tokensPath,
+	}
+	handler, err := opts.wrap(newTestServer(&fakeInferenceClient{}).Handler())
+	assert.NoError(t, err)
+
+	body := `{"model": "m", "prompt": "hi"}`
+	assert.Equal(t, http.StatusUnauthorized, post(handler, "/v1/completions", body).Code)
+	assert.Equal(t, http.StatusUnauthorized, post(handler, "/v1/completions", body, "Authorization", "Bearer token-3").Code)
+	w := post(handler, "/v1/completions", body, "Authorization", "Bearer token-2", "Origin", "https://app.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// other origins aren't allowed
+	w = post(handler, "/v1/completions", body, "Authorization", "Bearer token-1", "Origin", "https://other.example.com")
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// preflight requests aren't authenticated
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Authorization")
+
+	w = post(handler, "/v1/completions", `{"model": "m", "prompt": "`+strings.Repeat("a", 64)+`"}`,
+		"Authorization", "Bearer token-1")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	handler := accessLog(&out, accessLogFormatJSON, 1, []string{"X-Request-Id", "X-Tenant"},
+		newTestServer(&fakeInferenceClient{}).Handler())
+
+	w := post(handler, "/v1/completions", `{"model": "gpt-small", "prompt": "olleh"}`, "X-Request-Id", "req-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	entry := &accessLogEntry{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), entry))
+	assert.Equal(t, http.MethodPost, entry.Method)
+	assert.Equal(t, "/v1/completions", entry.Path)
+	assert.Equal(t, "gpt-small", entry.Model)
+	assert.Equal(t, http.StatusOK, entry.Status)
+	assert.Equal(t, map[string]string{"X-Request-Id": "req-1"}, entry.Headers)
+
+	// rejected requests are logged with their status
+	out.Reset()
+	w = post(handler, "/v1/completions", `{"prompt": "olleh"}`)
+	assert.NoError(t, json.Unmarshal(out.Bytes(), entry))
+	assert.Equal(t, w.Code, entry.Status)
+	assert.NotEqual(t, http.StatusOK, entry.Status)
+
+	// text lines
+	out.Reset()
+	handler = accessLog(&out, accessLogFormatText, 1, []string{"X-Request-Id"},
+		newTestServer(&fakeInferenceClient{}).Handler())
+	post(handler, "/v1/completions", `{"model": "gpt-small", "prompt": "olleh"}`, "X-Request-Id", "req-2")
+	assert.Regexp(t, `^\S+ POST /v1/completions status=200 duration=\d+ms model=gpt-small X-Request-Id="req-2"\n$`, out.String())
+
+	// none of the requests are logged with a zero ratio
+	out.Reset()
+	handler = accessLog(&out, accessLogFormatText, 0, nil, newTestServer(&fakeInferenceClient{}).Handler())
+	assert.Equal(t, http.StatusOK, post(handler, "/v1/completions", `{"model": "m", "prompt": "hi"}`).Code)
+	assert.Empty(t, out.String())
+
+	// unknown formats are rejected
+	_, err := Options{AccessLogFormat: "xml"}.wrap(newTestServer(&fakeInferenceClient{}).Handler())
+	assert.ErrorContains(t, err, "unsupported access log format")
+}
@@ -116,7 +116,8 @@ func (s *cachedPredictorSource) Find(_ context.Context, namespace string, predic
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	for _, p := range s.cache {
-		if !isDeleted(p) && p.Namespace == namespace && predicate(p) {
+		// all namespaces if empty, like the registries of Kubernetes resources
+		if !isDeleted(p) && (namespace == "" || p.Namespace == namespace) && predicate(p) {
 			return true, nil
 		}
 	}
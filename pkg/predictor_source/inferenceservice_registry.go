@@ -39,7 +39,7 @@ const (
 	azureBlobHostSuffix = "blob.core.windows.net"
 )
 
-var _ PredictorRegistry = (*InferenceServiceRegistry)(nil)
+var _ PredictorLister = (*InferenceServiceRegistry)(nil)
 
 type InferenceServiceRegistry struct {
 	Client client.Client
@@ -266,7 +266,13 @@ func (isvcr InferenceServiceRegistry) Get(ctx context.Context, nname types.Names
 	if err := isvcr.Client.Get(ctx, nname, inferenceService); err != nil {
 		return nil, err
 	}
+	return buildPredictorFromInferenceService(inferenceService)
+}
 
+// buildPredictorFromInferenceService converts the InferenceService along with its status and
+// storage, returning nil if it isn't deployed by model-mesh
+func buildPredictorFromInferenceService(inferenceService *v1beta1.InferenceService) (*v1alpha1.Predictor, error) {
+	nname := types.NamespacedName{Name: inferenceService.Name, Namespace: inferenceService.Namespace}
 	p, err := BuildBasePredictorFromInferenceService(inferenceService)
 
 	if err != nil {
@@ -323,7 +329,6 @@ func (isvcr InferenceServiceRegistry) Get(ctx context.Context, nname types.Names
 	p.Spec.Storage.Parameters = &parameters
 	p.Spec.Storage.StorageKey = secretKey
 	return p, nil
-
 }
 
 func (isvcr InferenceServiceRegistry) Find(ctx context.Context, namespace string,
@@ -335,31 +340,33 @@ func (isvcr InferenceServiceRegistry) Find(ctx context.Context, namespace string
 	}
 
 	for i := range list.Items {
-		inferenceService := &list.Items[i]
-		nname := types.NamespacedName{Name: inferenceService.Name, Namespace: inferenceService.Namespace}
-		p, err := BuildBasePredictorFromInferenceService(inferenceService)
+		p, err := buildPredictorFromInferenceService(&list.Items[i])
 		if err != nil {
 			return true, nil
 		}
-		if p != nil {
-			secretKey, parameters, modelPath, schemaPath, err := processInferenceServiceStorage(inferenceService, nname)
-			if err != nil {
-				return true, nil
-			}
-			p.Spec.Storage = &v1alpha1.Storage{}
-			p.Spec.Storage.Path = &modelPath
-			p.Spec.Storage.SchemaPath = schemaPath
-			p.Spec.Storage.Parameters = &parameters
-			p.Spec.Storage.StorageKey = secretKey
-
-			if predicate(p) {
-				return true, nil
-			}
+		if p != nil && predicate(p) {
+			return true, nil
 		}
 	}
 	return false, nil
 }
 
+// List returns the InferenceServices deployed by model-mesh with their status, skipping
+// those which can't be converted
+func (isvcr InferenceServiceRegistry) List(ctx context.Context, namespace string) ([]*v1alpha1.Predictor, error) {
+	list := &v1beta1.InferenceServiceList{}
+	if err := isvcr.Client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	predictors := make([]*v1alpha1.Predictor, 0, len(list.Items))
+	for i := range list.Items {
+		if p, err := buildPredictorFromInferenceService(&list.Items[i]); err == nil && p != nil {
+			predictors = append(predictors, p)
+		}
+	}
+	return predictors, nil
+}
+
 func (isvcr InferenceServiceRegistry) UpdateStatus(ctx context.Context, predictor *v1alpha1.Predictor) (bool, error) {
 	inferenceService := &v1beta1.InferenceService{}
 
@@ -24,7 +24,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-var _ PredictorRegistry = (*PredictorCRRegistry)(nil)
+var _ PredictorLister = (*PredictorCRRegistry)(nil)
 
 type PredictorCRRegistry struct {
 	Client client.Client
@@ -52,6 +52,18 @@ func (pr PredictorCRRegistry) Find(ctx context.Context, namespace string,
 	return false, nil
 }
 
+func (pr PredictorCRRegistry) List(ctx context.Context, namespace string) ([]*api.Predictor, error) {
+	list := &api.PredictorList{}
+	if err := pr.Client.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	predictors := make([]*api.Predictor, len(list.Items))
+	for i := range list.Items {
+		predictors[i] = &list.Items[i]
+	}
+	return predictors, nil
+}
+
 // UpdateStatus returns true if update was successful
 func (pr PredictorCRRegistry) UpdateStatus(ctx context.Context, predictor *api.Predictor) (bool, error) {
 	if err := pr.Client.Status().Update(ctx, predictor); err != nil {
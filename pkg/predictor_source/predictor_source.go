@@ -56,6 +56,14 @@ type PredictorRegistry interface {
 	GetSourceName() string
 }
 
+// PredictorLister is implemented by the registries which can list all their Predictors.
+// Unlike Find, List includes the status of the Predictors and skips those which are invalid.
+type PredictorLister interface {
+	PredictorRegistry
+
+	List(ctx context.Context, namespace string) ([]*api.Predictor, error)
+}
+
 func ResolveSource(nn types.NamespacedName, defaultSource string) (types.NamespacedName, string) {
 	namespace := nn.Namespace
 	// Check if namespace has a source prefix
@@ -0,0 +1,111 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing exports OpenTelemetry traces of the controller's reconciles. The spans
+// of the model-mesh calls made during a reconcile are children of its span, and their
+// trace context is propagated to model-mesh.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+const (
+	serviceName     = "modelmesh-controller"
+	instrumentation = "github.com/kserve/modelmesh-serving"
+)
+
+// Setup installs a global TracerProvider exporting to the configured OTLP collector and
+// returns the function flushing and stopping it on shutdown. Nothing is exported if no
+// collector endpoint is configured.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not create OTLP trace exporter for %s: %w", cfg.Endpoint, err)
+	}
+	tp := NewTracerProvider(exporter, cfg.SamplingRatio)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// NewTracerProvider returns a TracerProvider sampling the given fraction of the traces
+// which don't have a sampled parent, and exporting them in batches
+func NewTracerProvider(exporter sdktrace.SpanExporter, samplingRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName(serviceName))),
+	)
+}
+
+// Reconcile calls the given reconcile function within a span named after the controller
+func Reconcile(ctx context.Context, controller string, req ctrl.Request,
+	reconcile func(context.Context, ctrl.Request) (ctrl.Result, error)) (ctrl.Result, error) {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, controller+" reconcile",
+		trace.WithAttributes(
+			attribute.String("controller", controller),
+			semconv.K8SNamespaceName(req.Namespace),
+			attribute.String("name", req.Name),
+		))
+	defer span.End()
+
+	result, err := reconcile(ctx, req)
+	if err != nil {
+		recordError(span, err)
+	} else if result.Requeue || result.RequeueAfter > 0 {
+		span.SetAttributes(attribute.String("requeue_after", result.RequeueAfter.String()))
+	}
+	return result, err
+}
+
+// Span runs fn in a span with the given name and attributes, any error it returns is recorded in the span
+func Span(ctx context.Context, name string, fn func(context.Context) error, attributes ...attribute.KeyValue) error {
+	ctx, span := otel.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attributes...))
+	defer span.End()
+
+	err := fn(ctx)
+	if err != nil {
+		recordError(span, err)
+	}
+	return err
+}
+
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
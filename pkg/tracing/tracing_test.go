@@ -0,0 +1,115 @@
+// Copyright 2023 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kserve/modelmesh-serving/pkg/config"
+)
+
+func TestReconcile(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := NewTracerProvider(exporter, 1)
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "example-predictor"}}
+	var parent trace.SpanContext
+	_, _ = Reconcile(context.Background(), "predictor", req, func(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+		parent = trace.SpanContextFromContext(ctx)
+		_, child := otel.Tracer("test").Start(ctx, "setVModel")
+		child.End()
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	})
+	_, err := Reconcile(context.Background(), "predictor", req, func(context.Context, ctrl.Request) (ctrl.Result, error) {
+		return ctrl.Result{}, errors.New("model-mesh unavailable")
+	})
+	assert.EqualError(t, err, "model-mesh unavailable")
+	assert.NoError(t, tp.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	child, reconcile, failed := spans[0], spans[1], spans[2]
+
+	assert.Equal(t, "setVModel", child.Name)
+	assert.Equal(t, parent.SpanID(), child.Parent.SpanID())
+
+	assert.Equal(t, "predictor reconcile", reconcile.Name)
+	assert.Equal(t, parent.SpanID(), reconcile.SpanContext.SpanID())
+	assert.Contains(t, reconcile.Attributes, attribute.String("k8s.namespace.name", "ns"))
+	assert.Contains(t, reconcile.Attributes, attribute.String("name", "example-predictor"))
+	assert.Contains(t, reconcile.Attributes, attribute.String("requeue_after", "1m0s"))
+	assert.Equal(t, codes.Unset, reconcile.Status.Code)
+
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, "model-mesh unavailable", failed.Status.Description)
+	assert.Len(t, failed.Events, 1)
+}
+
+func TestSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := NewTracerProvider(exporter, 1)
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "predictor reconcile")
+	assert.NoError(t, Span(ctx, "predictor status update", func(ctx context.Context) error {
+		assert.True(t, trace.SpanFromContext(ctx).IsRecording())
+		return nil
+	}, attribute.String("name", "example-predictor")))
+	parent.End()
+	err := Span(context.Background(), "servingruntime status update", func(context.Context) error {
+		return errors.New("conflict")
+	})
+	assert.EqualError(t, err, "conflict")
+	assert.NoError(t, tp.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	update, failed := spans[0], spans[2]
+
+	assert.Equal(t, "predictor status update", update.Name)
+	assert.Equal(t, parent.SpanContext().SpanID(), update.Parent.SpanID())
+	assert.Contains(t, update.Attributes, attribute.String("name", "example-predictor"))
+	assert.Equal(t, codes.Unset, update.Status.Code)
+
+	assert.Equal(t, "servingruntime status update", failed.Name)
+	assert.False(t, failed.Parent.IsValid())
+	assert.Equal(t, codes.Error, failed.Status.Code)
+	assert.Equal(t, "conflict", failed.Status.Description)
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{SamplingRatio: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+	// the global provider is left as is
+	_, span := otel.Tracer("test").Start(context.Background(), "span")
+	assert.False(t, span.IsRecording())
+}